@@ -2,13 +2,30 @@ package main
 
 import (
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"os"
 	"os/signal"
+	"runtime"
+	"runtime/debug"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/spf13/pflag"
+	"github.com/bscott/ts-chat/internal/bots"
+	"github.com/bscott/ts-chat/internal/chat"
+	"github.com/bscott/ts-chat/internal/i18n"
 	"github.com/bscott/ts-chat/internal/server"
+	"github.com/bscott/ts-chat/internal/ui"
+)
+
+// Build details, injected at build time with
+// -ldflags "-X main.Version=... -X main.Commit=... -X main.BuildDate=..."
+var (
+	Version   = "dev"
+	Commit    = "unknown"
+	BuildDate = "unknown"
 )
 
 // Default configuration values
@@ -17,93 +34,328 @@ const (
 	defaultRoomName = "Chat Room"
 	defaultMaxUsers = 10
 	defaultHostname = "chatroom"
+	defaultHistorySize = 50
+	defaultShutdownGrace = 5 * time.Second
+	defaultKeepAlive = 30 * time.Second
+	defaultAcceptBurst = 10
+	defaultGreetBurst = 5
+	defaultWriteTimeout = chat.DefaultWriteTimeout
+	defaultOutboundQueue = chat.DefaultOutboundQueueSize
+	defaultLogLevel = "info"
+	defaultLogFormat = "text"
+	defaultTheme = "default"
+	defaultLang = i18n.DefaultLanguage
+	defaultMaxMessageLength = chat.DefaultMaxMessageLength
+	defaultRateLimit = chat.DefaultMessageRateLimit
+	defaultRateLimitWindow = chat.DefaultRateLimitWindow
+	defaultFloodViolations = chat.DefaultFloodViolations
+	defaultFloodWindow = chat.DefaultFloodWindow
+	defaultFloodMute = chat.DefaultFloodMute
+	defaultFilterAction = server.FilterMask
+	defaultMessageLogFormat = server.MessageLogJSON
+	defaultNicknameMinLength = chat.DefaultMinNicknameLength
+	defaultNicknameMaxLength = chat.DefaultMaxNicknameLength
+	defaultNicknameSymbols = chat.DefaultNicknameSymbols
 )
 
-type config struct {
-	Port           int
-	RoomName       string
-	MaxUsers       int
-	EnableTailscale bool
-	HostName       string
-}
-
 func main() {
-	// Parse command-line flags
-	cfg := parseFlags()
+	// Parse the config file and command-line flags
+	cfg, err := parseFlags(os.Args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		os.Exit(1)
+	}
 
-	// Setup logger
-	log.SetPrefix("[ts-chat] ")
+	// Setup logger. The level is kept in a LevelVar so SIGHUP can change it.
+	var logLevel slog.LevelVar
+	logger, err := newLogger(&logLevel, cfg.LogLevel, cfg.LogFormat)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to configure logging: %v\n", err)
+		os.Exit(1)
+	}
+	slog.SetDefault(logger)
+	
+	cfg.Build = buildInfo()
+	logger.Info("Build info", "version", cfg.Build.Version, "commit", cfg.Build.Commit, "build_date", cfg.Build.BuildDate, "go", cfg.Build.GoVersion)
+	
+	listeners, err := cfg.ListenSpecs()
+	if err != nil {
+		logger.Error("Failed to create server", "error", err)
+		os.Exit(1)
+	}
 	
-	if cfg.EnableTailscale {
-		log.Printf("Starting Tailscale Terminal Chat with hostname: %s, port: %d", cfg.HostName, cfg.Port)
+	tailnet := false
+	for _, spec := range listeners {
+		tailnet = tailnet || spec.Network == server.NetworkTailscale
+	}
+	if tailnet {
+		logger.Info("Starting Tailscale Terminal Chat", "hostname", cfg.HostName)
 		
 		// Check for auth key
 		if os.Getenv("TS_AUTHKEY") == "" {
-			log.Println("Warning: TS_AUTHKEY environment variable not set. Tailscale mode may not work properly.")
-			log.Println("Set TS_AUTHKEY=tskey-... to authenticate with Tailscale")
+			logger.Warn("TS_AUTHKEY environment variable not set. Tailscale mode may not work properly. Set TS_AUTHKEY=tskey-... to authenticate with Tailscale")
 		}
 	} else {
-		log.Printf("Starting Terminal Chat on port: %d", cfg.Port)
+		logger.Info("Starting Terminal Chat")
 	}
 
 	// Create and start the chat server
-	chatServer, err := server.NewServer(server.Config{
-		Port:           cfg.Port,
-		RoomName:       cfg.RoomName,
-		MaxUsers:       cfg.MaxUsers,
-		EnableTailscale: cfg.EnableTailscale,
-		HostName:       cfg.HostName,
-	})
+	chatServer, err := server.NewServer(cfg, logger)
 	if err != nil {
-		log.Fatalf("Failed to create server: %v", err)
+		logger.Error("Failed to create server", "error", err)
+		os.Exit(1)
 	}
 
 	// Start the server
 	go func() {
 		if err := chatServer.Start(); err != nil {
-			log.Fatalf("Server error: %v", err)
+			logger.Error("Server error", "error", err)
+			os.Exit(1)
 		}
 	}()
 
-	if cfg.EnableTailscale {
-		log.Printf("Chat server started. Users can connect via: telnet %s.ts.net %d", cfg.HostName, cfg.Port)
-	} else {
-		log.Printf("Chat server started. Users can connect via: telnet localhost %d", cfg.Port)
+	for _, spec := range listeners {
+		host := "localhost"
+		if spec.Network == server.NetworkTailscale {
+			host = cfg.HostName + ".ts.net"
+		}
+		if cfg.EnableTLS {
+			logger.Info(fmt.Sprintf("Chat server started. Users can connect via: openssl s_client -quiet -connect %s:%d", host, spec.Port))
+		} else {
+			logger.Info(fmt.Sprintf("Chat server started. Users can connect via: telnet %s %d", host, spec.Port))
+		}
 	}
 	
-	log.Printf("Room name: %s", cfg.RoomName)
-	log.Printf("Maximum users: %d", cfg.MaxUsers)
-	log.Print("Press Ctrl+C to stop the server")
+	logger.Info("Press Ctrl+C to stop the server", "room", cfg.RoomName, "max_users", cfg.MaxUsers)
 
-	// Wait for interrupt signal
+	// Wait for interrupt signal or a shutdown over the control socket,
+	// reloading the configuration on SIGHUP
 	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
-	<-sigCh
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+wait:
+	for {
+		select {
+		case sig := <-sigCh:
+			if sig != syscall.SIGHUP {
+				break wait
+			}
+			reload(chatServer, &logLevel, logger)
+		case <-chatServer.ShutdownRequested():
+			break wait
+		}
+	}
 
-	log.Print("Shutting down server...")
+	logger.Info("Shutting down server")
 	if err := chatServer.Stop(); err != nil {
-		log.Printf("Error shutting down server: %v", err)
+		logger.Error("Error shutting down server", "error", err)
 	}
 	os.Exit(0)
 }
 
-func parseFlags() config {
-	var cfg config
+// buildInfo collects the build details. A commit that wasn't injected falls
+// back to the VCS revision Go embeds when building from a checkout.
+func buildInfo() chat.BuildInfo {
+	info := chat.BuildInfo{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+	}
+	
+	if bi, ok := debug.ReadBuildInfo(); ok && info.Commit == "unknown" {
+		for _, setting := range bi.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				info.Commit = setting.Value
+			case "vcs.time":
+				if info.BuildDate == "unknown" {
+					info.BuildDate = setting.Value
+				}
+			}
+		}
+	}
+	
+	return info
+}
 
-	// Define command-line flags
-	pflag.IntVarP(&cfg.Port, "port", "p", defaultPort, "TCP port to listen on")
-	pflag.StringVarP(&cfg.RoomName, "room-name", "r", defaultRoomName, "Chat room name")
-	pflag.IntVarP(&cfg.MaxUsers, "max-users", "m", defaultMaxUsers, "Maximum allowed users")
-	pflag.BoolVarP(&cfg.EnableTailscale, "tailscale", "t", false, "Enable Tailscale mode")
-	pflag.StringVarP(&cfg.HostName, "hostname", "H", defaultHostname, "Tailscale hostname (only used if --tailscale is enabled)")
+// reload re-reads the config file and flags and applies what can change
+// while the server runs. A config that fails to load leaves everything as is.
+func reload(chatServer *server.Server, logLevel *slog.LevelVar, logger *slog.Logger) {
+	cfg, err := parseFlags(os.Args[1:])
+	if err != nil {
+		logger.Error("Error reloading configuration", "error", err)
+		return
+	}
+	cfg.Build = buildInfo()
+	
+	// Only change the log level once the whole config is known to be valid
+	if err := cfg.Validate(); err != nil {
+		logger.Error("Error reloading configuration", "error", fmt.Errorf("invalid configuration: %w", err))
+		return
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(cfg.LogLevel)); err != nil {
+		logger.Error("Error reloading configuration", "error", fmt.Errorf("invalid log level %q", cfg.LogLevel))
+		return
+	}
+	logLevel.Set(level)
+	
+	if err := chatServer.Reload(cfg); err != nil {
+		logger.Error("Error reloading configuration", "error", err)
+		return
+	}
+	logger.Info("Reloaded configuration")
+}
+
+// newLogger creates the process logger, storing level in levelVar. Text
+// output is the default; format "json" emits one JSON object per line.
+func newLogger(levelVar *slog.LevelVar, level, format string) (*slog.Logger, error) {
+	if err := levelVar.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid log level %q", level)
+	}
+
+	opts := &slog.HandlerOptions{Level: levelVar}
+	switch strings.ToLower(format) {
+	case "", "text":
+		return slog.New(slog.NewTextHandler(os.Stderr, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(os.Stderr, opts)), nil
+	default:
+		return nil, fmt.Errorf("invalid log format %q (expected text or json)", format)
+	}
+}
+
+func parseFlags(args []string) (server.Config, error) {
+	cfg := server.Config{
+		Port:        defaultPort,
+		RoomName:    defaultRoomName,
+		MaxUsers:    defaultMaxUsers,
+		HostName:    defaultHostname,
+		HistorySize: defaultHistorySize,
+		ShutdownGrace: defaultShutdownGrace,
+		KeepAlive:   defaultKeepAlive,
+		AcceptBurst: defaultAcceptBurst,
+		GreetBurst: defaultGreetBurst,
+		WriteTimeout: defaultWriteTimeout,
+		OutboundQueue: defaultOutboundQueue,
+		LogLevel:    defaultLogLevel,
+		LogFormat:   defaultLogFormat,
+		Theme:       defaultTheme,
+		Lang:        defaultLang,
+		MaxMessageLength: defaultMaxMessageLength,
+		RateLimit:   defaultRateLimit,
+		RateLimitWindow: defaultRateLimitWindow,
+		FloodViolations: defaultFloodViolations,
+		FloodWindow: defaultFloodWindow,
+		FloodMute:   defaultFloodMute,
+		FilterAction: defaultFilterAction,
+		MessageLogFormat: defaultMessageLogFormat,
+		NicknameMinLength: defaultNicknameMinLength,
+		NicknameMaxLength: defaultNicknameMaxLength,
+		NicknameSymbols: defaultNicknameSymbols,
+	}
+
+	// Load the config file first so that flags override its values
+	if path := configPath(args); path != "" {
+		if err := server.LoadConfig(path, &cfg); err != nil {
+			return cfg, err
+		}
+	}
+
+	// Define command-line flags, defaulting to the values loaded so far. A
+	// fresh flag set lets the flags be parsed again when reloading.
+	fs := pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	fs.StringP("config", "c", "", "Path to a YAML config file (flags override its values)")
+	fs.IntVarP(&cfg.Port, "port", "p", cfg.Port, "TCP port to listen on")
+	fs.StringVarP(&cfg.RoomName, "room-name", "r", cfg.RoomName, "Chat room name")
+	fs.IntVarP(&cfg.MaxUsers, "max-users", "m", cfg.MaxUsers, "Maximum allowed users")
+	fs.BoolVar(&cfg.ShowOccupancy, "show-occupancy", cfg.ShowOccupancy, "Include the user count, e.g. (3/10 users), in join and leave notices")
+	fs.StringVar(&cfg.JoinTemplate, "join-template", cfg.JoinTemplate, "Go template for join notices, using {{.Nickname}} and {{.RoomName}}")
+	fs.StringVar(&cfg.LeaveTemplate, "leave-template", cfg.LeaveTemplate, "Go template for leave notices, using {{.Nickname}} and {{.RoomName}}")
+	fs.IntVar(&cfg.MaxQueue, "max-queue", cfg.MaxQueue, "Users who may wait for a place when the room is full (0 turns them away)")
+	fs.IntVar(&cfg.MaxPerIP, "max-per-ip", cfg.MaxPerIP, "Connections allowed from one address, or one Tailscale user in Tailscale mode (0 is unlimited)")
+	fs.IntVar(&cfg.MaxConnections, "max-connections", cfg.MaxConnections, "Connections allowed across the whole server, whatever room they are in (0 is unlimited)")
+	fs.Float64Var(&cfg.AcceptRate, "accept-rate", cfg.AcceptRate, "New connections accepted per second on average (0 is unlimited)")
+	fs.IntVar(&cfg.AcceptBurst, "accept-burst", cfg.AcceptBurst, "New connections accepted at once before --accept-rate applies")
+	fs.Float64Var(&cfg.GreetRate, "greet-rate", cfg.GreetRate, "Connections from one source greeted per second on average; faster ones are closed without a reply (0 is unlimited)")
+	fs.IntVar(&cfg.GreetBurst, "greet-burst", cfg.GreetBurst, "Connections from one source greeted at once before --greet-rate applies")
+	fs.BoolVarP(&cfg.EnableTailscale, "tailscale", "t", cfg.EnableTailscale, "Enable Tailscale mode")
+	fs.StringSliceVar(&cfg.Listen, "listen", cfg.Listen, "Listeners as network:port, e.g. tcp:2323,tailscale:2323, replacing --port and --tailscale")
+	fs.StringVar(&cfg.BindAddr, "bind-addr", cfg.BindAddr, "IP address TCP listeners bind to, e.g. 127.0.0.1 or ::1 (default every interface)")
+	fs.StringVarP(&cfg.HostName, "hostname", "H", cfg.HostName, "Tailscale hostname (only used if --tailscale is enabled)")
+	fs.BoolVar(&cfg.EnableTLS, "tls", cfg.EnableTLS, "Encrypt connections with TLS (requires --tls-cert and --tls-key)")
+	fs.StringVar(&cfg.CertFile, "tls-cert", cfg.CertFile, "TLS certificate file in PEM format, reloaded on SIGHUP")
+	fs.StringVar(&cfg.KeyFile, "tls-key", cfg.KeyFile, "TLS private key file in PEM format, reloaded on SIGHUP")
+	fs.IntVar(&cfg.HistorySize, "history-size", cfg.HistorySize, "Number of recent messages replayed on join (0 disables)")
+	fs.StringVar(&cfg.OperatorPassword, "operator-password", cfg.OperatorPassword, "Password for the /op command (if empty and --operators is unset, the first user to join becomes operator)")
+	fs.StringSliceVar(&cfg.Operators, "operators", cfg.Operators, "Tailscale logins made operators when they connect, e.g. alice@example.com (Tailscale mode only)")
+	fs.StringVar(&cfg.BanFile, "ban-file", cfg.BanFile, "File to persist bans to (if empty, bans are lost on restart)")
+	fs.StringVar(&cfg.ExportDir, "export-dir", cfg.ExportDir, "Directory the /export command saves room transcripts to (if empty, /export is disabled)")
+	fs.StringVar(&cfg.FilterFile, "filter-file", cfg.FilterFile, "File of banned words and /regex/ patterns, reloaded with /filter reload (if empty, no filtering)")
+	fs.StringVar(&cfg.FilterAction, "filter-action", cfg.FilterAction, "What to do with messages containing banned words: mask or reject")
+	fs.StringVar(&cfg.MessageLog, "message-log", cfg.MessageLog, "File to log room messages to, see --message-log-format (if empty, messages aren't logged)")
+	fs.StringVar(&cfg.MessageLogFormat, "message-log-format", cfg.MessageLogFormat, "How --message-log is written: json (one object per line) or sqlite (a database)")
+	fs.BoolVar(&cfg.MessageLogSystem, "message-log-system", cfg.MessageLogSystem, "Also write system messages such as join and leave notices to --message-log")
+	fs.BoolVar(&cfg.SequenceNumbers, "sequence-numbers", cfg.SequenceNumbers, "Prefix each message with its number in the room, to debug dropped or reordered messages")
+	fs.IntVar(&cfg.MetricsPort, "metrics-port", cfg.MetricsPort, "Port to serve Prometheus metrics on at /metrics (0 disables)")
+	fs.IntVar(&cfg.HealthPort, "health-port", cfg.HealthPort, "Port to serve /healthz and /readyz health checks on (0 disables)")
+	fs.StringVar(&cfg.ControlSocket, "control-socket", cfg.ControlSocket, "Unix socket path, or localhost host:port, for administering the server without joining the chat")
+	fs.IntVar(&cfg.WebSocketPort, "websocket-port", cfg.WebSocketPort, "Port to serve the JSON WebSocket gateway on at /ws (0 disables)")
+	fs.StringSliceVar(&cfg.WebSocketOrigins, "websocket-origins", cfg.WebSocketOrigins, "Extra origins allowed to open WebSocket connections, e.g. chat.example.com")
+	fs.DurationVar(&cfg.IdleTimeout, "idle-timeout", cfg.IdleTimeout, "Disconnect users who send nothing for this long, e.g. 30m (0 disables)")
+	fs.DurationVar(&cfg.KeepAlive, "keepalive", cfg.KeepAlive, "Interval between keepalive probes used to detect dead connections (0 disables)")
+	fs.DurationVar(&cfg.WriteTimeout, "write-timeout", cfg.WriteTimeout, "Disconnect users whose connection accepts no data for this long, e.g. a stalled reader (0 disables)")
+	fs.IntVar(&cfg.OutboundQueue, "outbound-queue", cfg.OutboundQueue, "Messages that may wait to be written to each user before new ones are dropped")
+	fs.BoolVar(&cfg.DisconnectSlow, "disconnect-slow", cfg.DisconnectSlow, "Disconnect users whose outbound queue fills up instead of dropping their messages")
+	fs.IntVar(&cfg.MaxMessageLength, "max-message-length", cfg.MaxMessageLength, "Maximum message length in characters")
+	fs.IntVar(&cfg.RateLimit, "rate-limit", cfg.RateLimit, "Maximum messages a user may send per rate limit window")
+	fs.DurationVar(&cfg.RateLimitWindow, "rate-limit-window", cfg.RateLimitWindow, "Time window for the message rate limit")
+	fs.IntVar(&cfg.OperatorRateLimit, "operator-rate-limit", cfg.OperatorRateLimit, "Maximum messages an operator may send per rate limit window (0 exempts operators)")
+	fs.IntVar(&cfg.FloodViolations, "flood-violations", cfg.FloodViolations, "Rate limit violations within --flood-window that mute a user for --flood-mute (0 disables)")
+	fs.DurationVar(&cfg.FloodWindow, "flood-window", cfg.FloodWindow, "Time window for counting rate limit violations")
+	fs.DurationVar(&cfg.FloodMute, "flood-mute", cfg.FloodMute, "How long a user who keeps exceeding the rate limit is muted")
+	fs.IntVar(&cfg.NicknameMinLength, "nickname-min-length", cfg.NicknameMinLength, "Minimum nickname length in characters")
+	fs.IntVar(&cfg.NicknameMaxLength, "nickname-max-length", cfg.NicknameMaxLength, "Maximum nickname length in characters")
+	fs.StringVar(&cfg.NicknameSymbols, "nickname-symbols", cfg.NicknameSymbols, "Characters allowed in nicknames besides letters and digits")
+	fs.StringSliceVar(&cfg.ReservedNicknames, "reserved-nicknames", cfg.ReservedNicknames, "Nicknames nobody may use, e.g. admin,root (System is always reserved)")
+	fs.BoolVar(&cfg.AutoRenameOnCollision, "nickname-auto-rename", cfg.AutoRenameOnCollision, "Give users whose nickname is taken a numbered variant such as bob2 instead of asking again")
+	fs.DurationVar(&cfg.ResumeWindow, "resume-window", cfg.ResumeWindow, "How long users who lose their connection can get their nickname and room back with a resume token, e.g. 5m (0 disables)")
+	fs.StringSliceVar(&cfg.Bots, "bots", cfg.Bots, "Bots to run in every room: "+strings.Join(bots.Names(), ", "))
+	fs.BoolVar(&cfg.TopicLogOperators, "topic-log-operators", cfg.TopicLogOperators, "Only let operators see who changed a room's topic with /topiclog")
+	fs.StringToStringVar(&cfg.Macros, "macro", cfg.Macros, "Text macro as name=text, e.g. lenny='( ͡° ͜ʖ ͡°)'; repeat for more (shrug, tableflip and unflip are built in)")
+	fs.StringVar(&cfg.Theme, "theme", cfg.Theme, "Color theme: "+strings.Join(ui.ThemeNames(), ", "))
+	fs.BoolVar(&cfg.NoNickColors, "no-nick-colors", cfg.NoNickColors, "Show every nickname in the theme's user color instead of a color derived from the name")
+	fs.StringVar(&cfg.Motd, "motd", cfg.Motd, "Message of the day shown to users when they connect")
+	fs.StringVar(&cfg.MotdFile, "motd-file", cfg.MotdFile, "File to read the message of the day from instead of --motd, reloaded on SIGHUP")
+	fs.StringVar(&cfg.BannerFile, "banner-file", cfg.BannerFile, "File with ASCII art to show instead of the default banner")
+	fs.BoolVar(&cfg.NoBanner, "no-banner", cfg.NoBanner, "Don't show a banner when users join")
+	fs.StringVar(&cfg.Lang, "lang", cfg.Lang, "Language of prompts, notices and help: "+strings.Join(i18n.Languages(), ", "))
+	fs.StringVar(&cfg.Timezone, "timezone", cfg.Timezone, "Default time zone for message timestamps, e.g. UTC or Europe/Berlin (users can change theirs with /tz; if empty, server local time)")
+	fs.BoolVar(&cfg.NoColor, "no-color", cfg.NoColor, "Send plain text without ANSI styling by default (users can enable it with /color on)")
+	fs.StringVar(&cfg.LogLevel, "log-level", cfg.LogLevel, "Log level: debug, info, warn or error")
+	fs.StringVar(&cfg.LogFormat, "log-format", cfg.LogFormat, "Log output format: text or json")
+	fs.DurationVar(&cfg.ShutdownGrace, "shutdown-grace", cfg.ShutdownGrace, "How long to warn connected users before shutting down (0 disconnects immediately)")
 
 	// Display help message
-	pflag.Usage = func() {
+	fs.Usage = func() {
 		fmt.Fprintf(os.Stderr, "Usage: %s [options]\n\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "Options:\n")
-		pflag.PrintDefaults()
+		fs.PrintDefaults()
 	}
 
-	pflag.Parse()
-	return cfg
-}
\ No newline at end of file
+	if err := fs.Parse(args); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+// configPath extracts the --config flag ahead of the full flag parse
+func configPath(args []string) string {
+	fs := pflag.NewFlagSet("config", pflag.ContinueOnError)
+	fs.ParseErrorsWhitelist.UnknownFlags = true
+	fs.SetOutput(io.Discard)
+	fs.Usage = func() {}
+	path := fs.StringP("config", "c", "", "")
+	fs.Parse(args)
+	return *path
+}
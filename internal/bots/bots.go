@@ -0,0 +1,34 @@
+// Package bots contains MessageHandlers that can be enabled on the server
+package bots
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/bscott/ts-chat/internal/chat"
+)
+
+// registry maps bot names to their constructors
+var registry = map[string]func() chat.MessageHandler{
+	"ping": func() chat.MessageHandler { return PingBot{} },
+}
+
+// Names returns the names of the available bots, sorted
+func Names() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New creates the bot with the given name
+func New(name string) (chat.MessageHandler, error) {
+	newBot, ok := registry[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown bot %q (available: %s)", name, strings.Join(Names(), ", "))
+	}
+	return newBot(), nil
+}
@@ -0,0 +1,25 @@
+package bots
+
+import (
+	"context"
+	"strings"
+
+	"github.com/bscott/ts-chat/internal/chat"
+)
+
+// PingBot answers "!ping" so users can check the server is responsive
+type PingBot struct{}
+
+// Name returns the nickname the bot replies as
+func (PingBot) Name() string {
+	return "PingBot"
+}
+
+// HandleMessage replies "pong" to messages starting with "!ping"
+func (PingBot) HandleMessage(ctx context.Context, msg chat.Message) (string, error) {
+	fields := strings.Fields(msg.Content)
+	if msg.IsAction || len(fields) == 0 || fields[0] != "!ping" {
+		return "", nil
+	}
+	return msg.From + ": pong", nil
+}
@@ -0,0 +1,32 @@
+package chat
+
+import (
+	"errors"
+	"time"
+)
+
+// targetedActions are the IRC-style actions performed on another user in
+// the room, keyed by command. Each is the catalog ID of a format taking the
+// target's nickname.
+var targetedActions = map[string]string{
+	"/slap": "action.slap",
+	"/hug":  "action.hug",
+}
+
+// performAction broadcasts one of the targetedActions as if the user had
+// typed it with /me. The target must be in the user's room.
+func (c *Client) performAction(command, nickname string) error {
+	room := c.Room()
+	target, ok := room.GetClient(nickname)
+	if !ok {
+		return errors.New(c.t("room.no_such_user", room.Name, nickname))
+	}
+
+	c.broadcastOwn(Message{
+		From:      c.Nickname(),
+		Content:   c.t(targetedActions[command], target.Nickname()),
+		Timestamp: time.Now(),
+		IsAction:  true,
+	})
+	return nil
+}
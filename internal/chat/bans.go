@@ -0,0 +1,15 @@
+package chat
+
+// BanEntry describes a banned connection source
+type BanEntry struct {
+	Source string // Remote host or Tailscale identity
+	Reason string
+}
+
+// BanList stores banned connection sources. It is implemented by the server
+// so bans can be enforced before a client is created.
+type BanList interface {
+	Ban(source, reason string) error
+	Unban(source string) (bool, error)
+	List() []BanEntry
+}
@@ -3,87 +3,340 @@ package chat
 import (
 	"bufio"
 	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net"
+	"os"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
+	"unicode/utf8"
 
+	"github.com/bscott/ts-chat/internal/i18n"
+	"github.com/bscott/ts-chat/internal/metrics"
 	"github.com/bscott/ts-chat/internal/ui"
 )
 
-// Constants for rate limiting and validation
+// Default limits, used when ClientConfig leaves them unset
 const (
-	MaxMessageLength = 1000     // Maximum message length in characters
-	MessageRateLimit = 5        // Maximum messages per second
-	RateLimitWindow  = 5 * time.Second // Time window for rate limiting
+	DefaultMaxMessageLength = 1000     // Maximum message length in characters
+	DefaultMessageRateLimit = 5        // Maximum messages per rate limit window
+	DefaultRateLimitWindow  = 5 * time.Second // Time window for rate limiting
+	DefaultOutboundQueueSize = 256    // Maximum messages waiting to be written to a client
+	DefaultFloodViolations  = 3       // Rate limit violations within the flood window that mute a client
+	DefaultFloodWindow      = time.Minute // Time window for counting rate limit violations
+	DefaultFloodMute        = time.Minute // How long a flooding client is muted
 )
 
+const (
+	DefaultTerminalWidth = 80   // Assumed terminal width when the client doesn't report one
+	DefaultWriteTimeout = 10 * time.Second // Default bound on a single write to a client
+)
+
+// defaultHistoryCount is how many messages /history shows when not told
+const defaultHistoryCount = 20
+
+// ClientConfig holds per-connection settings for clients
+type ClientConfig struct {
+	IdleTimeout      time.Duration // Disconnect clients that send nothing for this long (0 disables, needs a transport with deadlines)
+	KeepAlive        time.Duration // Interval between keepalive probes that detect dead connections (0 disables)
+	WriteTimeout     time.Duration // Disconnect the client if a write takes longer than this (0 disables)
+	OutboundQueueSize int          // Messages that may wait to be written to the client
+	DisconnectSlow   bool          // Disconnect the client when its queue is full instead of dropping messages
+	OperatorPassword string        // Password for /op
+	Operator         bool          // Grant operator status on connect, e.g. for allowlisted Tailscale users
+	AutoOperator     bool          // Make the client an operator if it is the first user on the server
+	Source           string        // Connection source recorded by /ban: the Tailscale login name if known, otherwise the remote host
+	Identity         string        // Tailscale user and machine shown to operators in /who (empty if unknown)
+	Bans             BanList       // Shared ban list (nil disables /ban)
+	Sessions         SessionStore  // Resume tokens for users whose connection drops (nil disables resuming)
+	MaxQueue         int           // Clients that may wait for a place when the lobby is full (0 turns them away)
+	Filter           WordFilter    // Censors banned words in room messages (nil disables filtering)
+	Stats            StatsProvider // Server statistics for /stats (nil disables it)
+	Build            BuildInfo     // Build details reported by /version
+	MOTD             string        // Message of the day shown after the banner (empty shows none)
+	Banner           string        // ASCII art shown above the welcome message (empty uses DefaultBanner)
+	NoBanner         bool          // Skip the banner and start with the welcome message
+	ExportDir        string        // Directory /export writes transcripts to (empty disables it)
+	Nicknames        NicknamePolicy // Rules for choosing nicknames
+	AutoRenameOnCollision bool      // Give users whose nickname is taken a numbered variant instead of asking again
+	Logger           *slog.Logger  // Logger for this connection (defaults to slog.Default)
+	Theme            *ui.Theme     // Color theme (nil uses the default theme)
+	Catalog          *i18n.Catalog // Language of the text the user sees (nil is English)
+	Location         *time.Location // Default time zone for timestamps (nil uses the server's local time)
+	NoColor          bool          // Start with plain, unstyled output
+	MaxMessageLength int           // Maximum message length in characters
+	MessageRateLimit int           // Maximum messages per rate limit window
+	RateLimitWindow  time.Duration // Time window for rate limiting
+	OperatorRateLimit int          // Maximum messages per window for operators (0 exempts them from the limit)
+	FloodViolations  int           // Rate limit violations within FloodWindow that mute the client for FloodMute (0 disables)
+	FloodWindow      time.Duration // Time window for counting rate limit violations
+	FloodMute        time.Duration // How long a flooding client is muted
+	JSON             bool          // Send JSON-encoded messages instead of styled text, e.g. for WebSocket clients
+	TopicLogOperatorsOnly bool     // Only operators may use /topiclog
+	Macros           map[string]string // Text macros such as /tableflip by name, without the slash, besides the built-in ones
+	LineEnding       string        // Ends each line written to the client (empty uses TelnetLineEnding, or StreamLineEnding for JSON clients)
+}
+
 // Client represents a chat client
 type Client struct {
-	Nickname          string
-	conn              net.Conn
-	reader            *bufio.Reader
+	nickname          string       // Protected by nickMu
+	nickMu            sync.RWMutex
+	JoinedAt          time.Time   // When the client connected
+	lastActive        time.Time   // When the client last sent a message, protected by activityMu
+	activityMu        sync.Mutex
+	operator          atomic.Bool // Whether the client has operator rights
+	invisible         atomic.Bool // Hidden from /who and join/leave notices; set by /invisible
+	dnd               atomic.Bool // Refuses private messages; set by /dnd
+	nickColor         atomic.Pointer[string] // Overrides the nickname's color for others; set by /mycolor or an operator's /color
+	away              bool        // Whether the client is away, protected by awayMu
+	awayMessage       string      // Optional reason given with /away, protected by awayMu
+	awayMu            sync.Mutex
+	mutedUntil        time.Time   // When an operator's /mute ends (zero if not muted), protected by muteMu
+	muteMu            sync.Mutex
+	conn              Transport
+	config            ClientConfig
+	logger            *slog.Logger
+	renderer          atomic.Pointer[ui.Renderer] // Formats output; swapped by /color
+	width             atomic.Int32 // Terminal width reported via Telnet NAWS (0 if unknown)
+	location          atomic.Pointer[time.Location] // Time zone message timestamps are shown in; set by /tz
+	timeFormat        atomic.Pointer[timeFormat]    // Layout message timestamps are shown with; set by /timeformat
+	reader            *bufio.Reader // Line reader; for Telnet clients it reads through a lineEditor
+	lastHeard         atomic.Int64  // When data last arrived from the connection, in Unix nanoseconds; see heardReader
+	telnet            *telnetReader // Telnet protocol handling (nil for JSON clients)
+	lines             chan readResult // Lines read by the reader goroutine, see startReading
+	readOnce          sync.Once
+	stopRead          chan struct{} // Closed to stop the reader goroutine handing over lines
+	stopReadOnce      sync.Once
 	writer            *bufio.Writer
-	room              *Room
+	eol               string        // Line ending written after each line, see ClientConfig.LineEnding
+	manager           *RoomManager
+	room              *Room        // Current room, protected by roomMu
+	roomMu            sync.RWMutex // Mutex for the current room pointer
 	mu                sync.Mutex // Mutex to protect concurrent writes
-	fullRoomRejection bool       // Flag indicating client was rejected due to room being full
+	outbound          chan Message  // Messages waiting to be written, in order
+	latencyAvg        atomic.Int64  // Moving average of send latency in nanoseconds, see recordLatency
+	latencyMax        atomic.Int64  // Worst send latency in nanoseconds
+	slow              bool          // Average send latency is too high; only used by writeLoop
+	quit              chan struct{} // Closed to stop the writer goroutine
+	left              chan struct{} // Closed once Handle has returned and the client has left its room
+	quitOnce          sync.Once
+	evictOnce         sync.Once // Guards disconnecting the client after a failed write
+	ignored           map[string]string // Nicknames whose messages are hidden from this client, keyed by nicknameKey
+	ignoreMu          sync.Mutex // Mutex for the ignore list
+	emojiOff          atomic.Bool // Leave shortcodes such as :smile: as typed; set by /emoji
+	markdownOff       atomic.Bool // Show *bold* and _italic_ markers as typed; set by /markdown
 	messageTimestamps []time.Time // Timestamps of recent messages for rate limiting
+	violations        []time.Time // Times the rate limit was exceeded, for flood detection
+	lineReadAt        time.Time   // When the line being handled was read; only used by Handle
+	lastReport        time.Time   // When the client last used /report; only used by Handle
+	resumed           *Session    // Session the user resumed at the nickname prompt, if any
+	resumeToken       string      // Token the session is kept under if the connection drops
+	ended             atomic.Bool // Disconnected on purpose, e.g. by /quit or a kick, so the session isn't kept
 	rateLimitMu       sync.Mutex // Mutex for rate limiting data
 }
 
-// NewClient creates a new chat client
-func NewClient(conn net.Conn, room *Room) (*Client, error) {
-	client := &Client{
-		conn:              conn,
-		reader:            bufio.NewReader(conn),
-		writer:            bufio.NewWriter(conn),
-		room:              room,
-		fullRoomRejection: false,
-		messageTimestamps: make([]time.Time, 0, MessageRateLimit*2),
+// NewClient creates a new chat client and places it in the lobby. If ctx is
+// cancelled before the client has joined, for example because the server is
+// shutting down, the connection is closed and NewClient returns ctx's error.
+func NewClient(ctx context.Context, conn Transport, manager *RoomManager, cfg ClientConfig) (*Client, error) {
+	client := newClient(conn, manager, cfg)
+	
+	if !cfg.JSON {
+		telnet := newTelnetReader(heardReader{client}, client.writeRaw, client.setWindowSize)
+		client.telnet = telnet
+		editor := newLineEditor(telnet, client.writeRaw, telnet.echoing)
+		editor.complete = client.completions
+		editor.list = client.formatCompletions
+		client.reader = bufio.NewReader(editor)
+		
+		// Ask for the window size so boxes fit the client's terminal, and
+		// for keystrokes as they are typed so input can be edited
+		if err := telnet.requestWindowSize(); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("telnet negotiation failed: %w", err)
+		}
+		if err := telnet.requestCharacterMode(); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("telnet negotiation failed: %w", err)
+		}
 	}
 	
+	// Closing the connection unblocks the nickname prompt and the lobby
+	// queue, which would otherwise wait for input indefinitely
+	stop := context.AfterFunc(ctx, func() {
+		conn.Close()
+	})
+	defer stop()
+	
 	// Ask for nickname
 	if err := client.requestNickname(); err != nil {
 		// Ensure connection is closed on error
 		conn.Close()
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("nickname request cancelled: %w", ctx.Err())
+		}
 		return nil, fmt.Errorf("nickname request failed: %w", err)
 	}
+	client.logger = client.logger.With("nickname", client.Nickname())
 	
-	// Join the room
-	room.Join(client)
+	now := time.Now()
+	client.JoinedAt = now
+	client.lastActive = now
+	
+	// Start delivering queued messages before anything can be broadcast to us
+	go client.writeLoop()
 	
-	// Check if client was rejected due to room being full
-	if client.fullRoomRejection {
-		// Close the connection since the room is full
+	// Join the lobby, waiting for a place if it is full
+	err := manager.JoinLobby(client)
+	if errors.Is(err, ErrRoomFull) && cfg.MaxQueue > 0 {
+		err = client.waitForLobby()
+	}
+	if err != nil {
+		// Close the connection since the client can't join
+		notice := client.t("join.full")
+		switch {
+		case errors.Is(err, ErrNicknameTaken):
+			notice = client.nicknameErrorMessage(client.Nickname(), err)
+		case errors.Is(err, ErrQueueFull):
+			notice = client.t("join.queue_full")
+		case errors.Is(err, errLeftQueue):
+			notice = client.t("join.left_queue")
+		}
+		client.stopReading()
+		client.stopWriter()
+		client.write(client.render().FormatSystemMessage(notice) + client.eol)
 		conn.Close()
-		return nil, fmt.Errorf("room is full")
+		return nil, err
+	}
+	
+	// Without an operator password or allowlist, the first user on the
+	// server moderates it
+	if cfg.Operator || (cfg.AutoOperator && manager.UserCount() == 1) {
+		client.SetOperator(true)
+	}
+	
+	// Put a returning user back in the room they were in
+	if client.resumed != nil && client.resumed.Room != "" && roomKey(client.resumed.Room) != roomKey(manager.Lobby().Name) {
+		if _, err := manager.Move(client, client.resumed.Room); err != nil {
+			client.logger.Info("Could not return resumed session to its room", "room", client.resumed.Room, "error", err)
+		}
 	}
 	
 	// Send welcome message
 	if err := client.sendWelcomeMessage(); err != nil {
 		// Leave the room since we encountered an error
-		room.Leave(client)
+		manager.Leave(client)
+		client.stopReading()
+		client.stopWriter()
 		// Close the connection
 		conn.Close()
 		return nil, fmt.Errorf("welcome message failed: %w", err)
 	}
 	
+	if cfg.Sessions != nil {
+		if client.resumed != nil {
+			client.logger.Info("Session resumed")
+			client.sendSystemMessage(client.t("session.resumed"))
+		}
+		client.resumeToken = cfg.Sessions.Issue()
+		client.sendSystemMessage(client.t("session.token", ui.FormatUptime(cfg.Sessions.Window()), client.resumeToken))
+	}
+	
 	return client, nil
 }
 
+// newClient sets up a client over conn without talking to it: no Telnet
+// negotiation, nickname prompt or joining a room. NewClient does those;
+// tests use newClient directly to put clients in rooms without a network.
+func newClient(conn Transport, manager *RoomManager, cfg ClientConfig) *Client {
+	client := &Client{
+		conn:              conn,
+		config:            cfg,
+		logger:            cfg.Logger,
+		writer:            bufio.NewWriter(conn),
+		manager:           manager,
+		quit:              make(chan struct{}),
+		left:              make(chan struct{}),
+		lines:             make(chan readResult, 1),
+		stopRead:          make(chan struct{}),
+		ignored:           make(map[string]string),
+	}
+	if client.logger == nil {
+		client.logger = slog.Default()
+	}
+	if client.config.MaxMessageLength <= 0 {
+		client.config.MaxMessageLength = DefaultMaxMessageLength
+	}
+	if client.config.MessageRateLimit <= 0 {
+		client.config.MessageRateLimit = DefaultMessageRateLimit
+	}
+	if client.config.RateLimitWindow <= 0 {
+		client.config.RateLimitWindow = DefaultRateLimitWindow
+	}
+	if client.config.FloodWindow <= 0 {
+		client.config.FloodWindow = DefaultFloodWindow
+	}
+	if client.config.FloodMute <= 0 {
+		client.config.FloodMute = DefaultFloodMute
+	}
+	if client.config.OutboundQueueSize <= 0 {
+		client.config.OutboundQueueSize = DefaultOutboundQueueSize
+	}
+	client.outbound = make(chan Message, client.config.OutboundQueueSize)
+	if client.config.Nicknames.MinLength <= 0 {
+		client.config.Nicknames.MinLength = DefaultMinNicknameLength
+	}
+	if client.config.Nicknames.MaxLength <= 0 {
+		client.config.Nicknames.MaxLength = DefaultMaxNicknameLength
+	}
+	client.messageTimestamps = make([]time.Time, 0, client.config.MessageRateLimit*2)
+	client.SetColor(!cfg.NoColor && !cfg.JSON)
+	client.eol = cfg.LineEnding
+	if client.eol == "" {
+		client.eol = TelnetLineEnding
+		if cfg.JSON {
+			client.eol = StreamLineEnding
+		}
+	}
+	
+	client.reader = bufio.NewReader(heardReader{client})
+	return client
+}
+
+// heardReader reads from a client's connection, noting when anything
+// arrives. That includes Telnet negotiation the user never sees, which is
+// how unresponsive hears a reply to its probe.
+type heardReader struct {
+	c *Client
+}
+
+func (r heardReader) Read(p []byte) (int, error) {
+	n, err := r.c.conn.Read(p)
+	if n > 0 {
+		r.c.lastHeard.Store(time.Now().UnixNano())
+	}
+	return n, err
+}
+
 // requestNickname asks the user for a nickname
 func (c *Client) requestNickname() error {
 	// Send welcome message
-	if err := c.write(ui.FormatTitle("Welcome to Tailscale Terminal Chat") + "\r\n\r\n"); err != nil {
+	if err := c.write(c.render().FormatTitle(c.t("welcome.title")) + c.eol + c.eol); err != nil {
 		return fmt.Errorf("failed to write welcome message: %w", err)
 	}
 	
 	// Ask for nickname
 	for {
-		if err := c.write(ui.InputStyle.Render("Please enter your nickname: ")); err != nil {
+		if err := c.write(c.render().FormatPrompt(c.t("nickname.prompt"))); err != nil {
 			return fmt.Errorf("failed to write nickname prompt: %w", err)
 		}
 		
@@ -93,43 +346,130 @@ func (c *Client) requestNickname() error {
 			return fmt.Errorf("failed to read nickname: %w", err)
 		}
 		
-		// Trim whitespace
-		nickname = strings.TrimSpace(nickname)
+		// Strip escape sequences and trim whitespace
+		nickname = strings.TrimSpace(sanitizeInput(strings.ToValidUTF8(nickname, "\uFFFD")))
 		
-		// Validate nickname
-		if nickname == "" {
-			if err := c.write("Nickname cannot be empty. Please try again.\r\n"); err != nil {
-				return fmt.Errorf("failed to write error message: %w", err)
+		// A user whose connection dropped may answer with their resume token
+		var resumed *Session
+		if token, ok := strings.CutPrefix(nickname, "/resume "); ok && c.config.Sessions != nil {
+			session, ok := c.config.Sessions.Resume(strings.TrimSpace(token))
+			if !ok {
+				if err := c.write(c.t("session.invalid") + c.eol); err != nil {
+					return fmt.Errorf("failed to write error message: %w", err)
+				}
+				continue
 			}
-			continue
+			nickname, resumed = session.Nickname, &session
 		}
 		
-		if strings.ToLower(nickname) == "system" {
-			if err := c.write("Nickname 'System' is reserved. Please choose another nickname.\r\n"); err != nil {
-				return fmt.Errorf("failed to write error message: %w", err)
+		// Validate nickname. Nicknames of suspended sessions are held for
+		// their owners, and one still held by a dead connection of the
+		// same user is taken back.
+		err = ValidateNickname(nickname, c.config.Nicknames)
+		if err == nil && c.config.Sessions != nil && c.config.Sessions.Held(nickname) {
+			err = fmt.Errorf("%w: %s", ErrNicknameTaken, nickname)
+		}
+		if err == nil && !c.manager.IsNicknameAvailable(nickname) && !c.manager.ReclaimNickname(nickname, c.Source()) {
+			err = fmt.Errorf("%w: %s", ErrNicknameTaken, nickname)
+		}
+		if errors.Is(err, ErrNicknameTaken) && c.config.AutoRenameOnCollision && resumed == nil {
+			if suggested, ok := c.manager.SuggestNickname(nickname, c.config.Nicknames); ok {
+				notice := c.t("nickname.renamed", nickname, suggested)
+				if err := c.write(notice + c.eol); err != nil {
+					return fmt.Errorf("failed to write nickname notice: %w", err)
+				}
+				nickname, err = suggested, nil
 			}
-			continue
 		}
-		
-		if !c.room.IsNicknameAvailable(nickname) {
-			errMsg := fmt.Sprintf("Nickname '%s' is already taken. Please choose another nickname.\r\n", nickname)
-			if err := c.write(errMsg); err != nil {
+		if err != nil {
+			if err := c.write(c.nicknameErrorMessage(nickname, err) + c.eol); err != nil {
 				return fmt.Errorf("failed to write error message: %w", err)
 			}
 			continue
 		}
 		
 		// Set nickname
-		c.Nickname = nickname
+		c.setNickname(nickname)
+		c.resumed = resumed
 		break
 	}
 	
 	return nil
 }
 
-// sendWelcomeMessage sends a welcome message to the client
-func (c *Client) sendWelcomeMessage() error {
-	banner := `
+// nicknameErrorMessage explains why a nickname was rejected
+func (c *Client) nicknameErrorMessage(nickname string, err error) string {
+	switch {
+	case errors.Is(err, ErrNicknameEmpty):
+		return c.t("nickname.empty")
+	case errors.Is(err, ErrNicknameReserved):
+		return c.t("nickname.reserved", nickname)
+	case errors.Is(err, ErrNicknameTaken):
+		return c.t("nickname.taken", nickname)
+	default:
+		return c.t("nickname.invalid", err)
+	}
+}
+
+// errLeftQueue is returned by waitForLobby when the client gives up waiting
+var errLeftQueue = errors.New("left the waiting queue")
+
+// waitForLobby holds the client in the lobby's waiting queue until a place
+// frees up. Input other than /quit is ignored while waiting, but the
+// connection is still read so clients that disconnect give up their place.
+// The reader started for that goes on to feed Handle once the client is
+// admitted, so the pending read never has to be interrupted.
+func (c *Client) waitForLobby() error {
+	entry, err := c.manager.QueueForLobby(c, c.config.MaxQueue, func(position int) {
+		c.sendSystemMessage(c.t("queue.position", position))
+	})
+	if err != nil || entry == nil {
+		return err
+	}
+	
+	c.startReading()
+	for {
+		select {
+		case err := <-entry.admitted:
+			if err != nil {
+				return err
+			}
+			c.setRoom(c.manager.Lobby())
+			c.logger.Info("Admitted from queue")
+			
+			// The reader is already waiting for a line, with no idle
+			// deadline while the client was queued. Transports that allow
+			// it apply this one to that read; others from the next line.
+			c.armIdleDeadline()
+			return nil
+			
+		case <-entry.closed:
+			return ErrRoomClosed
+			
+		case result := <-c.lines:
+			if result.err == nil && strings.TrimSpace(result.message) != "/quit" {
+				c.sendSystemMessage(c.t("queue.waiting"))
+				continue
+			}
+			err := result.err
+			if err == nil {
+				err = errLeftQueue
+			}
+			if c.manager.Lobby().dequeue(entry) {
+				return err
+			}
+			// Admitted just as the client left
+			if <-entry.admitted == nil {
+				c.manager.Lobby().Leave(c)
+			}
+			return err
+		}
+	}
+}
+
+// DefaultBanner is the ASCII art shown when users join, unless the
+// server configures another or none
+const DefaultBanner = `
 ╔═══════════════════════════════════════════════════════════════════════╗
 ║           _____                    _             _   _____             ║
 ║          |_   _|__ _ __ _ __ ___ (_)_ __   __ _| | |  __ \            ║
@@ -140,18 +480,39 @@ func (c *Client) sendWelcomeMessage() error {
 ║                             CHAT ROOM                                 ║
 ╚═══════════════════════════════════════════════════════════════════════╝
 `
-	coloredBanner := ui.SystemStyle.Render(banner)
-	welcomeMsg := ui.FormatWelcomeMessage(c.room.Name, c.Nickname)
+
+// sendWelcomeMessage sends a welcome message to the client
+func (c *Client) sendWelcomeMessage() error {
+	if !c.config.NoBanner {
+		banner := c.config.Banner
+		if banner == "" {
+			banner = DefaultBanner
+		}
+		if err := c.write(c.render().FormatBanner(banner) + c.eol); err != nil {
+			return fmt.Errorf("failed to write banner: %w", err)
+		}
+	}
 	
-	if err := c.write(coloredBanner + "\r\n"); err != nil {
-		return fmt.Errorf("failed to write banner: %w", err)
+	if motd := c.render().FormatMOTD(c.config.MOTD); motd != "" {
+		if err := c.write(motd + c.eol + c.eol); err != nil {
+			return fmt.Errorf("failed to write MOTD: %w", err)
+		}
 	}
 	
-	if err := c.write(welcomeMsg + "\r\n\r\n"); err != nil {
+	welcomeMsg := c.render().FormatWelcomeMessage(c.Room().Name, c.Nickname())
+	if err := c.write(welcomeMsg + c.eol + c.eol); err != nil {
 		return fmt.Errorf("failed to write welcome message: %w", err)
 	}
 	
-	if err := c.write("Type a message and press Enter to send. Type /help for commands.\r\n\r\n"); err != nil {
+	if err := c.showTopic(); err != nil {
+		return fmt.Errorf("failed to write topic: %w", err)
+	}
+	
+	if err := c.replayHistory(); err != nil {
+		return fmt.Errorf("failed to replay history: %w", err)
+	}
+	
+	if err := c.write(c.t("welcome.ready") + c.eol + c.eol); err != nil {
 		return fmt.Errorf("failed to write help message: %w", err)
 	}
 	
@@ -160,111 +521,370 @@ func (c *Client) sendWelcomeMessage() error {
 
 // Handle handles client interactions
 func (c *Client) Handle(ctx context.Context) {
-	log.Printf("Starting handler for client %s", c.Nickname)
+	c.logger.Debug("Starting client handler")
 	
-	// Cleanup when done
+	// Stop the reader goroutine and leave the room when done
+	done := make(chan struct{})
 	defer func() {
-		log.Printf("Client handler for %s is shutting down", c.Nickname)
-		c.room.Leave(c)
+		c.logger.Debug("Client handler is shutting down")
+		close(done)
+		c.stopReading()
+		
+		// Keep the session for a while if the connection dropped
+		if c.resumeToken != "" && !c.ended.Load() && ctx.Err() == nil {
+			if room := c.Room(); room != nil {
+				c.config.Sessions.Suspend(c.resumeToken, Session{Nickname: c.Nickname(), Room: room.Name})
+				c.logger.Info("Session kept for resuming", "resume_window", c.config.Sessions.Window())
+			}
+		}
+		c.manager.Leave(c)
+		close(c.left)
+		c.stopWriter()
 	}()
 	
-	// Create a timeout reader
-	readCh := make(chan readResult)
-	readErrorCh := make(chan error)
+	// A single reader goroutine feeds lines to the loop below. It is already
+	// running if the client waited in the lobby's queue.
+	c.startReading()
+	
+	if c.config.KeepAlive > 0 {
+		go c.keepaliveLoop(done, c.config.KeepAlive)
+	}
 	
 	// Handle client messages
 	for {
 		select {
 		case <-ctx.Done():
-			log.Printf("Context cancelled for client %s", c.Nickname)
+			c.logger.Debug("Context cancelled")
 			return
 			
-		default:
-			// Use a goroutine for reading to handle timeouts and cancelations
-			go func() {
-				line, err := c.reader.ReadString('\n')
-				if err != nil {
-					readErrorCh <- err
-					return
-				}
-				readCh <- readResult{message: line}
-			}()
-			
-			// Wait for either a message, error, or context cancellation
-			select {
-			case <-ctx.Done():
-				log.Printf("Context cancelled while reading for client %s", c.Nickname)
-				return
-				
-			case err := <-readErrorCh:
-				if err == io.EOF {
-					// Client disconnected normally
-					log.Printf("Client %s disconnected (EOF)", c.Nickname)
+		case result := <-c.lines:
+			if result.err != nil {
+				if result.err == io.EOF {
+					// Client disconnected normally. A last line without a
+					// newline was still meant to be sent, so it is handled
+					// like any other before the client leaves.
+					if strings.TrimSpace(result.message) != "" {
+						c.markActive()
+						c.lineReadAt = result.at
+						c.handleLine(result.message)
+					}
+					c.logger.Info("Client disconnected")
 					return
 				}
 				
-				// Try to notify the client of the error
-				log.Printf("Error reading from client %s: %v", c.Nickname, err)
-				c.sendSystemMessage(fmt.Sprintf("Error reading message: %v", err))
-				return
-				
-			case result := <-readCh:
-				// Process the message
-				message := strings.TrimSpace(result.message)
+				var netErr net.Error
+				switch {
+				case errors.Is(result.err, os.ErrDeadlineExceeded):
+					// The read deadline set in readLoop expired
+					c.ended.Store(true)
+					c.logger.Info("Client disconnected due to inactivity", "idle_timeout", c.config.IdleTimeout)
+					c.write(c.render().FormatSystemMessage(c.t("idle.disconnected")) + c.eol)
 				
-				// Skip empty messages
-				if message == "" {
-					continue
-				}
+				case errors.Is(result.err, net.ErrClosed):
+					// Closed on our side, by a kick or the server shutting down
+					c.logger.Debug("Connection closed")
 				
-				// Validate message length
-				if err := c.validateMessageLength(message); err != nil {
-					log.Printf("Message from %s rejected: %v", c.Nickname, err)
-					c.sendSystemMessage(fmt.Sprintf("Error: %v", err))
-					continue
-				}
+				case connectionLost(result.err):
+					// Nothing can be written to a connection that is gone
+					c.logger.Info("Client connection lost", "error", result.err)
 				
-				// Check rate limiting (except for /quit command)
-				if !strings.HasPrefix(message, "/quit") {
-					if err := c.checkRateLimit(); err != nil {
-						log.Printf("Message from %s rate limited: %v", c.Nickname, err)
-						c.sendSystemMessage(fmt.Sprintf("Error: %v", err))
-						continue
-					}
-				}
+				case errors.As(result.err, &netErr) && netErr.Timeout():
+					c.logger.Info("Client connection timed out", "error", result.err)
 				
-				// Handle command or regular message
-				if strings.HasPrefix(message, "/") {
-					if err := c.handleCommand(message); err != nil {
-						log.Printf("Error handling command from %s: %v", c.Nickname, err)
-						c.sendSystemMessage(fmt.Sprintf("Error: %v", err))
-					}
-				} else {
-					// Send message to room
-					c.room.Broadcast(Message{
-						From:      c.Nickname,
-						Content:   message,
-						Timestamp: time.Now(),
-					})
+				default:
+					// Try to notify the client of the error
+					c.logger.Warn("Error reading from client", "error", result.err)
+					c.sendSystemMessage(c.t("read.error", result.err))
 				}
+				return
 			}
+			
+			c.markActive()
+			c.lineReadAt = result.at
+			c.handleLine(result.message)
 		}
 	}
 }
 
+// connectionLost reports whether err means the peer went away without
+// closing the connection cleanly
+func connectionLost(err error) bool {
+	return errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNABORTED) ||
+		errors.Is(err, syscall.EPIPE)
+}
+
 // readResult holds the result of a read operation
 type readResult struct {
 	message string
+	err     error
+	at      time.Time // When the read completed
+}
+
+// startReading starts the goroutine reading lines from the connection into
+// c.lines, unless it is already running. Once the nickname has been chosen
+// it is the connection's only reader.
+func (c *Client) startReading() {
+	c.readOnce.Do(func() {
+		go c.readLoop()
+	})
+}
+
+// stopReading stops the reader goroutine handing over lines. The channel is
+// buffered, so a reader with a result pending exits once its read returns,
+// which closing the connection forces.
+func (c *Client) stopReading() {
+	c.stopReadOnce.Do(func() {
+		close(c.stopRead)
+	})
+}
+
+// readLoop reads lines from the connection until a read fails or reading is stopped
+func (c *Client) readLoop() {
+	for {
+		// Each received line pushes the idle deadline further out
+		c.armIdleDeadline()
+		
+		line, err := c.reader.ReadString('\n')
+		
+		select {
+		case c.lines <- readResult{message: line, err: err, at: time.Now()}:
+		case <-c.stopRead:
+			return
+		}
+		
+		if err != nil {
+			return
+		}
+	}
+}
+
+// armIdleDeadline gives the client IdleTimeout to send its next line, on
+// transports with deadlines. Clients waiting in the lobby's queue aren't in
+// a room yet and may wait as long as they like.
+func (c *Client) armIdleDeadline() {
+	if c.config.IdleTimeout <= 0 || c.Room() == nil {
+		return
+	}
+	if d, ok := c.conn.(deadlineTransport); ok {
+		if err := d.SetReadDeadline(time.Now().Add(c.config.IdleTimeout)); err != nil {
+			c.logger.Warn("Error setting read deadline", "error", err)
+		}
+	}
+}
+
+// keepaliveLoop sends a Telnet NOP every interval until done is closed. A
+// peer that vanished without closing the connection makes the write fail
+// or time out, and closing the connection then ends Handle, which frees the
+// client's slot in its room.
+func (c *Client) keepaliveLoop(done <-chan struct{}, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if err := c.ping(interval); err != nil {
+				c.logger.Info("Client connection is dead", "error", err)
+				c.conn.Close()
+				return
+			}
+		}
+	}
+}
+
+// unresponsive reports whether the client's connection appears dead: its
+// writer has already given up, or the peer doesn't answer a probe within
+// timeout. A write alone proves nothing, since it succeeds on a half-open
+// connection until the kernel gives up retransmitting, so the peer has to
+// reply: WebSocket clients are pinged, and Telnet clients are sent a timing
+// mark, which they acknowledge (RFC 860). Clients that can't be probed this
+// way, such as netcat, are given the benefit of the doubt.
+func (c *Client) unresponsive(timeout time.Duration) bool {
+	select {
+	case <-c.quit:
+		return true
+	default:
+	}
+	
+	if p, ok := c.conn.(pingTransport); ok {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		return p.Ping(ctx) != nil
+	}
+	if c.telnet == nil || !c.telnet.speaksTelnet() {
+		return false
+	}
+	
+	sent := time.Now()
+	if err := c.writeProbe([]byte{telnetIAC, telnetDO, telnetOptTimingMark}, timeout); err != nil {
+		return true
+	}
+	ticker := time.NewTicker(timeout / 20)
+	defer ticker.Stop()
+	for c.lastHeard.Load() < sent.UnixNano() {
+		if time.Since(sent) >= timeout {
+			return true
+		}
+		<-ticker.C
+	}
+	return false
+}
+
+// ping writes a Telnet NOP, failing if it can't be sent within timeout
+func (c *Client) ping(timeout time.Duration) error {
+	if err := c.writeProbe([]byte{telnetIAC, telnetNOP}, timeout); err != nil {
+		return fmt.Errorf("error writing keepalive: %w", err)
+	}
+	return nil
+}
+
+// writeProbe writes Telnet commands, failing if they can't be sent within
+// timeout
+func (c *Client) writeProbe(data []byte, timeout time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	
+	if d, ok := c.conn.(deadlineTransport); ok {
+		if err := d.SetWriteDeadline(time.Now().Add(timeout)); err != nil {
+			return fmt.Errorf("error setting write deadline: %w", err)
+		}
+		defer d.SetWriteDeadline(time.Time{})
+	}
+	
+	if _, err := c.writer.Write(data); err != nil {
+		return err
+	}
+	return c.writer.Flush()
+}
+
+// handleLine processes a single line of input from the client
+func (c *Client) handleLine(line string) {
+	// Replace invalid UTF-8 and strip escape sequences so input can't garble
+	// other clients' terminals
+	message := strings.TrimSpace(sanitizeInput(strings.ToValidUTF8(line, "\uFFFD")))
+	
+	// Skip empty messages
+	if message == "" {
+		return
+	}
+	
+	// Expand shortcodes first so the length limit applies to what is sent
+	if !c.emojiOff.Load() {
+		message = expandEmoji(message)
+	}
+	
+	// Validate message length
+	if err := c.validateMessageLength(message); err != nil {
+		c.logger.Debug("Message rejected", "error", err)
+		c.sendSystemMessage(c.t("error", err))
+		return
+	}
+	
+	// Check rate limiting (except for /quit and operator announcements)
+	exempt := strings.HasPrefix(message, "/quit") || (strings.HasPrefix(message, "/announce") && c.IsOperator())
+	if !exempt {
+		if err := c.checkRateLimit(); err != nil {
+			c.logger.Info("Message rate limited", "error", err)
+			c.sendSystemMessage(c.t("error", err))
+			return
+		}
+	}
+	
+	// Handle command or regular message
+	if strings.HasPrefix(message, "/") {
+		if err := c.handleCommand(message); err != nil {
+			c.logger.Debug("Error handling command", "command", message, "error", err)
+			c.sendSystemMessage(c.t("error", err))
+		}
+		return
+	}
+	
+	c.say(message)
+}
+
+// say sends a message the user typed to their room
+func (c *Client) say(message string) {
+	message, ok := c.filterContent(message)
+	if !ok {
+		return
+	}
+	
+	// Talking in the room means the user is back
+	if c.setBack() {
+		c.sendSystemMessage(c.t("away.off"))
+	}
+	
+	// Send message to room
+	c.broadcastOwn(Message{
+		From:      c.Nickname(),
+		Content:   message,
+		Timestamp: time.Now(),
+		Color:     c.NickColor(),
+	})
+}
+
+// broadcastOwn sends one of the client's own messages to its room. The
+// client's copy is written directly instead of going through its queue, so
+// it appears immediately and in the order the user typed it.
+func (c *Client) broadcastOwn(msg Message) {
+	if until, muted := c.Muted(); muted {
+		c.sendSystemMessage(c.t("muted.remaining", ui.FormatUptime(time.Until(until))))
+		return
+	}
+	
+	// A numbered message only gets its number from the room, so the room
+	// delivers the sender's copy as well
+	room := c.Room()
+	if !room.numbersMessages() {
+		msg.sender = c
+		if err := c.writeMessage(msg); err != nil {
+			c.logger.Warn("Error echoing message", "error", err)
+		}
+	}
+	if err := room.Broadcast(msg); err != nil {
+		c.logger.Debug("Message dropped", "room", room.Name, "error", err)
+	}
+}
+
+// filterContent applies the word filter to content meant for the room. It
+// returns false, after telling the user, if the message must not be sent.
+func (c *Client) filterContent(content string) (string, bool) {
+	if c.config.Filter == nil {
+		return content, true
+	}
+	
+	filtered, ok := c.config.Filter.Filter(content)
+	if !ok {
+		c.logger.Info("Message rejected by word filter")
+		c.sendSystemMessage(c.t("message.filtered"))
+	}
+	return filtered, ok
 }
 
 // validateMessageLength checks if a message is within the allowed length
 func (c *Client) validateMessageLength(message string) error {
-	if len(message) > MaxMessageLength {
-		return fmt.Errorf("message too long (max %d characters)", MaxMessageLength)
+	limit := c.maxMessageLength()
+	if utf8.RuneCountInString(message) > limit {
+		return errors.New(c.t("message.too_long", limit))
 	}
 	return nil
 }
 
+// maxMessageLength returns the message length limit in the client's current
+// room, which may override the server's
+func (c *Client) maxMessageLength() int {
+	if room := c.Room(); room != nil {
+		if limit := room.MaxMessageLength(); limit > 0 {
+			return limit
+		}
+	}
+	return c.config.MaxMessageLength
+}
+
 // checkRateLimit checks if the client is sending messages too quickly
 func (c *Client) checkRateLimit() error {
 	now := time.Now()
@@ -275,7 +895,8 @@ func (c *Client) checkRateLimit() error {
 	c.messageTimestamps = append(c.messageTimestamps, now)
 	
 	// Remove timestamps outside the window
-	cutoff := now.Add(-RateLimitWindow)
+	window := c.config.RateLimitWindow
+	cutoff := now.Add(-window)
 	newTimestamps := make([]time.Time, 0, len(c.messageTimestamps))
 	
 	for _, ts := range c.messageTimestamps {
@@ -286,143 +907,1057 @@ func (c *Client) checkRateLimit() error {
 	
 	c.messageTimestamps = newTimestamps
 	
+	// Operators get their own, usually higher, limit or none at all
+	limit := c.config.MessageRateLimit
+	if c.IsOperator() {
+		if c.config.OperatorRateLimit <= 0 {
+			return nil
+		}
+		limit = c.config.OperatorRateLimit
+	}
+	
 	// Check if we have too many messages in the window
-	if len(c.messageTimestamps) > MessageRateLimit {
-		waitTime := c.messageTimestamps[0].Add(RateLimitWindow).Sub(now)
-		return fmt.Errorf("rate limit exceeded (max %d messages per %s). Try again in %.1f seconds", 
-			MessageRateLimit, RateLimitWindow, waitTime.Seconds())
+	if len(c.messageTimestamps) > limit {
+		metrics.RateLimitedTotal.Inc()
+		if _, muted := c.Muted(); !muted && c.floodingLocked(now) {
+			c.mute(now.Add(c.config.FloodMute))
+			c.logger.Warn("Client muted for flooding", "duration", c.config.FloodMute)
+			return errors.New(c.t("message.flood_muted", ui.FormatUptime(c.config.FloodMute)))
+		}
+		waitTime := c.messageTimestamps[0].Add(window).Sub(now)
+		return errors.New(c.t("message.rate_limit", limit, window, waitTime.Seconds()))
 	}
 	
 	return nil
 }
 
-// handleCommand handles a command from the client
-func (c *Client) handleCommand(cmd string) error {
-	parts := strings.SplitN(cmd, " ", 2)
-	command := strings.ToLower(parts[0])
+// floodingLocked records a rate limit violation and reports whether the
+// client has now had FloodViolations of them within FloodWindow, in which
+// case the count starts over. c.rateLimitMu must be held.
+func (c *Client) floodingLocked(now time.Time) bool {
+	if c.config.FloodViolations <= 0 {
+		return false
+	}
 	
-	switch command {
-	case "/who":
-		return c.showUserList()
-		
-	case "/me":
-		if len(parts) < 2 || strings.TrimSpace(parts[1]) == "" {
-			c.sendSystemMessage("Usage: /me <action>")
-			return fmt.Errorf("invalid /me command usage")
-		}
-		action := parts[1]
-		c.room.Broadcast(Message{
-			From:      c.Nickname,
-			Content:   action,
-			Timestamp: time.Now(),
-			IsAction:  true,
-		})
-		
-	case "/help":
-		return c.showHelp()
-		
-	case "/quit":
-		c.sendSystemMessage("Goodbye!")
-		// We don't return an error here since this is expected behavior
-		if err := c.conn.Close(); err != nil {
-			return fmt.Errorf("error closing connection: %w", err)
+	cutoff := now.Add(-c.config.FloodWindow)
+	recent := c.violations[:0]
+	for _, ts := range c.violations {
+		if ts.After(cutoff) {
+			recent = append(recent, ts)
 		}
-		
-	default:
-		c.sendSystemMessage(fmt.Sprintf("Unknown command: %s", command))
-		return fmt.Errorf("unknown command: %s", command)
 	}
+	c.violations = append(recent, now)
 	
-	return nil
-}
-
-// showUserList shows the list of users in the room
-func (c *Client) showUserList() error {
-	users := c.room.GetUserList()
-	msg := ui.FormatUserList(c.room.Name, users, c.room.MaxUsers)
-	return c.write(msg + "\r\n")
+	if len(c.violations) < c.config.FloodViolations {
+		return false
+	}
+	c.violations = c.violations[:0]
+	return true
 }
 
-// showHelp shows the help message
-func (c *Client) showHelp() error {
-	helpMsg := ui.FormatHelp()
-	return c.write(helpMsg + "\r\n")
+// handleCommand looks a command up in the registry, checks its arguments
+// and the client's operator status, and runs it
+func (c *Client) handleCommand(line string) error {
+	parts := strings.SplitN(line, " ", 2)
+	name := strings.ToLower(parts[0])
+	
+	cmd, ok := lookupCommand(name)
+	if !ok {
+		if text, ok := c.macro(name); ok {
+			rest := ""
+			if len(parts) > 1 {
+				rest = parts[1]
+			}
+			return c.sendMacro(text, rest)
+		}
+		c.sendSystemMessage(c.t("command.unknown", name))
+		return errors.New(c.t("command.not_found", name))
+	}
+	if cmd.Operator && !c.IsOperator() {
+		return errors.New(c.t("permission.operator", cmd.Name))
+	}
+	
+	var args []string
+	if len(parts) > 1 {
+		args = splitArgs(parts[1], cmd.MaxArgs, cmd.Rest)
+	}
+	err := errUsage
+	if len(args) >= cmd.MinArgs && len(args) <= cmd.MaxArgs {
+		err = cmd.Run(c, args)
+	}
+	
+	if errors.Is(err, errUsage) {
+		usage := cmd.Name
+		if cmd.HasUsage {
+			usage = c.t("usage."+strings.TrimPrefix(cmd.Name, "/"), cmd.UsageArgs...)
+		}
+		c.sendSystemMessage(c.t("command.usage", usage))
+		var reason usageError
+		if errors.As(err, &reason) {
+			return errors.New(c.t("command.invalid_usage_reason", cmd.Name, string(reason)))
+		}
+		if err == errUsage {
+			return errors.New(c.t("command.invalid_usage", cmd.Name))
+		}
+	}
+	return err
 }
 
-// sendSystemMessage sends a system message to the client
-func (c *Client) sendSystemMessage(message string) {
+// sendPrivateMessage delivers a message to a single user and echoes it to the sender
+func (c *Client) sendPrivateMessage(nickname, content string) error {
+	if sameNickname(nickname, c.Nickname()) {
+		c.sendSystemMessage(c.t("msg.self"))
+		return nil
+	}
+	
+	target, ok := c.manager.FindClient(nickname)
+	if !ok {
+		return errors.New(c.t("user.not_found", nickname))
+	}
+	
 	msg := Message{
-		From:      "System",
-		Content:   message,
+		From:      c.Nickname(),
+		To:        target.Nickname(),
+		Content:   content,
 		Timestamp: time.Now(),
-		IsSystem:  true,
+		IsPrivate: true,
 	}
 	
+	if target.DoNotDisturb() {
+		c.sendSystemMessage(c.t("dnd.notice", target.Nickname()))
+		return nil
+	}
+	
+	target.sendMessage(msg)
 	c.sendMessage(msg)
+	
+	if reason, away := target.Away(); away {
+		if reason == "" {
+			c.sendSystemMessage(c.t("away.notice", target.Nickname()))
+		} else {
+			c.sendSystemMessage(c.t("away.notice_reason", target.Nickname(), reason))
+		}
+	}
+	return nil
 }
 
-// sendMessage sends a message to the client
-func (c *Client) sendMessage(msg Message) {
-	var formatted string
-	timeStr := msg.Timestamp.Format("15:04:05")
+// changeNickname renames the client and announces it to the current room
+func (c *Client) changeNickname(nickname string) error {
+	old := c.Nickname()
+	if nickname == old {
+		c.sendSystemMessage(c.t("nick.same", old))
+		return nil
+	}
+	
+	if err := ValidateNickname(nickname, c.config.Nicknames); err != nil {
+		return errors.New(c.nicknameErrorMessage(nickname, err))
+	}
+	if c.config.Sessions != nil && c.config.Sessions.Held(nickname) {
+		return errors.New(c.nicknameErrorMessage(nickname, ErrNicknameTaken))
+	}
+	if err := c.manager.Rename(c, nickname); err != nil {
+		return errors.New(c.nicknameErrorMessage(nickname, err))
+	}
+	
+	c.logger.Info("Nickname changed", "new_nickname", nickname)
+	return nil
+}
+
+// reloadFilter re-reads the word filter list
+func (c *Client) reloadFilter() error {
+	if c.config.Filter == nil {
+		return errors.New(c.t("filter.disabled"))
+	}
+	
+	count, err := c.config.Filter.Reload()
+	if err != nil {
+		c.logger.Error("Failed to reload word filter", "error", err)
+		return err
+	}
+	
+	c.logger.Info("Word filter reloaded", "entries", count)
+	c.sendSystemMessage(c.t("filter.reloaded", count))
+	return nil
+}
+
+// replyPing answers /ping with how long the server took to handle it. The
+// reply is written directly rather than queued so the time to flush it can
+// be measured too.
+func (c *Client) replyPing() error {
+	processing := time.Since(c.lineReadAt)
+	flush, err := c.writeTimed(Message{
+		From:      "System",
+		Content:   c.t("ping.pong", processing.Round(time.Microsecond)),
+		Timestamp: time.Now(),
+		IsSystem:  true,
+	})
+	if err != nil {
+		return err
+	}
+	
+	c.logger.Debug("Ping", "processing", processing, "flush", flush, "total", time.Since(c.lineReadAt))
+	return nil
+}
+
+// ignoreUser hides a user's room messages from this client
+func (c *Client) ignoreUser(nickname string) error {
+	if sameNickname(nickname, c.Nickname()) {
+		return errors.New(c.t("ignore.self"))
+	}
+	target, ok := c.manager.FindClient(nickname)
+	if !ok {
+		return errors.New(c.t("user.not_found", nickname))
+	}
+	
+	c.ignoreMu.Lock()
+	c.ignored[nicknameKey(nickname)] = target.Nickname()
+	c.ignoreMu.Unlock()
+	
+	c.sendSystemMessage(c.t("ignore.added", target.Nickname()))
+	return nil
+}
+
+// unignoreUser shows a previously ignored user's messages again
+func (c *Client) unignoreUser(nickname string) error {
+	c.ignoreMu.Lock()
+	name, ok := c.ignored[nicknameKey(nickname)]
+	delete(c.ignored, nicknameKey(nickname))
+	c.ignoreMu.Unlock()
+	
+	if !ok {
+		return errors.New(c.t("ignore.not", nickname))
+	}
+	c.sendSystemMessage(c.t("ignore.removed", name))
+	return nil
+}
+
+// showIgnored lists the users this client is ignoring
+func (c *Client) showIgnored() {
+	c.ignoreMu.Lock()
+	names := make([]string, 0, len(c.ignored))
+	for _, name := range c.ignored {
+		names = append(names, name)
+	}
+	c.ignoreMu.Unlock()
+	
+	if len(names) == 0 {
+		c.sendSystemMessage(c.t("ignore.none"))
+		return
+	}
+	sort.Strings(names)
+	c.sendSystemMessage(c.t("ignore.list", strings.Join(names, ", ")))
+}
+
+// isIgnored reports whether msg comes from an ignored user. System and
+// private messages are never ignored.
+func (c *Client) isIgnored(msg Message) bool {
+	if msg.IsSystem || msg.IsPrivate {
+		return false
+	}
+	
+	c.ignoreMu.Lock()
+	defer c.ignoreMu.Unlock()
+	
+	_, ignored := c.ignored[nicknameKey(msg.From)]
+	return ignored
+}
+
+// authenticateOperator grants operator status if the password matches
+func (c *Client) authenticateOperator(password string) error {
+	if c.IsOperator() {
+		c.sendSystemMessage(c.t("op.already"))
+		return nil
+	}
+	
+	expected := c.config.OperatorPassword
+	if expected == "" || subtle.ConstantTimeCompare([]byte(password), []byte(expected)) != 1 {
+		c.logger.Warn("Failed operator authentication")
+		return errors.New(c.t("op.bad_password"))
+	}
+	
+	c.SetOperator(true)
+	c.logger.Info("Client is now an operator")
+	c.sendSystemMessage(c.t("op.granted"))
+	return nil
+}
+
+// kickUser disconnects a user from the operator's current room
+func (c *Client) kickUser(nickname, reason string) error {
+	if sameNickname(nickname, c.Nickname()) {
+		return errors.New(c.t("kick.self"))
+	}
+	
+	room := c.Room()
+	target, ok := room.GetClient(nickname)
+	if !ok {
+		return errors.New(c.t("room.no_such_user", room.Name, nickname))
+	}
+	
+	c.logger.Info("User kicked", "target", target.Nickname(), "room", room.Name, "reason", reason)
+	target.kick(c.Nickname(), reason)
+	return nil
+}
+
+// kick disconnects the client, telling it and its room who kicked it and why
+func (c *Client) kick(kickedBy, reason string) {
+	notice := c.t("kick.notice", kickedBy)
+	if reason != "" {
+		notice = c.t("kick.notice_reason", kickedBy, reason)
+	}
+	
+	room := c.Room()
+	c.disconnect(notice)
+	if room == nil {
+		return
+	}
+	
+	// Worded for the room rather than the kicked client
+	announcement := room.language().T("kick.announce", c.Nickname(), kickedBy)
+	if reason != "" {
+		announcement = room.language().T("kick.announce_reason", c.Nickname(), kickedBy, reason)
+	}
+	room.Broadcast(Message{
+		From:      "System",
+		Content:   announcement,
+		Timestamp: time.Now(),
+		IsSystem:  true,
+	})
+}
+
+// banUser bans a user's connection source and disconnects them
+func (c *Client) banUser(nickname, reason string) error {
+	if c.config.Bans == nil {
+		return errors.New(c.t("bans.disabled"))
+	}
+	if sameNickname(nickname, c.Nickname()) {
+		return errors.New(c.t("ban.self"))
+	}
+	
+	target, ok := c.manager.FindClient(nickname)
+	if !ok {
+		return errors.New(c.t("user.not_found", nickname))
+	}
+	
+	source := target.config.Source
+	if source == "" {
+		return errors.New(c.t("ban.no_source", target.Nickname()))
+	}
+	
+	// Record the ban before disconnecting so a quick reconnect is refused
+	if err := c.config.Bans.Ban(source, reason); err != nil {
+		c.logger.Error("Error saving ban", "source", source, "error", err)
+		c.sendSystemMessage(c.t("ban.save_failed", err))
+	}
+	
+	notice := target.t("ban.notice", c.Nickname())
+	if reason != "" {
+		notice = target.t("ban.notice_reason", c.Nickname(), reason)
+	}
+	
+	c.logger.Info("User banned", "target", target.Nickname(), "source", source, "reason", reason)
+	room := target.Room()
+	target.disconnect(notice)
+	
+	if room != nil {
+		announcement := room.language().T("ban.announce", target.Nickname(), c.Nickname())
+		if reason != "" {
+			announcement = room.language().T("ban.announce_reason", target.Nickname(), c.Nickname(), reason)
+		}
+		room.Broadcast(Message{
+			From:      "System",
+			Content:   announcement,
+			Timestamp: time.Now(),
+			IsSystem:  true,
+		})
+	}
+	c.sendSystemMessage(c.t("ban.done", source))
+	return nil
+}
+
+// unbanSource lifts a ban on a connection source
+func (c *Client) unbanSource(source string) error {
+	if c.config.Bans == nil {
+		return errors.New(c.t("bans.disabled"))
+	}
+	
+	removed, err := c.config.Bans.Unban(source)
+	if err != nil {
+		c.logger.Error("Error saving ban list after unban", "source", source, "error", err)
+		c.sendSystemMessage(c.t("unban.save_failed", err))
+	}
+	if !removed {
+		return errors.New(c.t("unban.not_banned", source))
+	}
+	
+	c.logger.Info("Source unbanned", "source", source)
+	c.sendSystemMessage(c.t("unban.done", source))
+	return nil
+}
+
+// showBanList shows all banned connection sources
+func (c *Client) showBanList() error {
+	if c.config.Bans == nil {
+		return errors.New(c.t("bans.disabled"))
+	}
+	
+	bans := c.config.Bans.List()
+	lines := make([]string, 0, len(bans))
+	for _, ban := range bans {
+		line := ban.Source
+		if ban.Reason != "" {
+			line += " - " + ban.Reason
+		}
+		lines = append(lines, line)
+	}
+	
+	return c.write(c.render().FormatBanList(lines) + c.eol)
+}
+
+// joinRoom moves the client into the named room
+func (c *Client) joinRoom(name string) error {
+	room, err := c.manager.Move(c, name)
+	switch {
+	case errors.Is(err, ErrAlreadyInRoom):
+		return errors.New(c.t("room.already_in", c.Room().Name))
+	case errors.Is(err, ErrRoomFull):
+		return errors.New(c.t("room.full", name))
+	case err != nil:
+		return err
+	}
+	
+	if err := c.write(c.render().FormatSystemMessage(c.t("room.now_in", room.Name)) + c.eol); err != nil {
+		return err
+	}
+	if err := c.showTopic(); err != nil {
+		return err
+	}
+	return c.replayHistory()
+}
+
+// showTopic writes the current room's topic, if it has one
+func (c *Client) showTopic() error {
+	topic := c.render().FormatTopic(c.Room().Topic())
+	if topic == "" {
+		return nil
+	}
+	return c.write(topic + c.eol)
+}
+
+// showTopicLog shows who changed the current room's topic, and when
+func (c *Client) showTopicLog() error {
+	if c.config.TopicLogOperatorsOnly && !c.IsOperator() {
+		return errors.New(c.t("permission.operator", "/topiclog"))
+	}
+	
+	room := c.Room()
+	now := time.Now()
+	changes := room.TopicLog()
+	entries := make([]ui.TopicLogEntry, 0, len(changes))
+	for _, change := range changes {
+		entries = append(entries, ui.TopicLogEntry{
+			Topic: change.Topic,
+			SetBy: change.SetBy,
+			Age:   now.Sub(change.At),
+		})
+	}
+	return c.write(c.render().FormatTopicLog(room.Name, entries, c.Width()) + c.eol)
+}
+
+// replayHistory writes the current room's recent messages to the client
+func (c *Client) replayHistory() error {
+	messages := c.Room().History()
+	if len(messages) == 0 {
+		return nil
+	}
+	return c.writeHistory(messages)
+}
+
+// showHistory writes up to count of the current room's most recent messages
+// to the client
+func (c *Client) showHistory(count int) error {
+	room := c.Room()
+	messages := room.History()
+	if len(messages) == 0 {
+		c.sendSystemMessage(c.t("history.none", room.Name))
+		return nil
+	}
+	if count < len(messages) {
+		messages = messages[len(messages)-count:]
+	}
+	return c.writeHistory(messages)
+}
+
+// writeHistory writes earlier messages to the client under a heading. JSON
+// clients get them marked as history instead.
+func (c *Client) writeHistory(messages []Message) error {
+	if c.config.JSON {
+		for _, msg := range messages {
+			if c.isIgnored(msg) {
+				continue
+			}
+			msg.IsHistory = true
+			if err := c.writeJSON(msg); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	
+	var sb strings.Builder
+	sb.WriteString(c.render().FormatSystemMessage(c.t("history.title", len(messages))) + c.eol)
+	for _, msg := range messages {
+		if c.isIgnored(msg) {
+			continue
+		}
+		sb.WriteString(c.formatMessage(msg))
+	}
+	
+	return c.write(sb.String() + c.eol)
+}
+
+// showUserList shows the list of users in the room
+func (c *Client) showUserList() error {
+	room := c.Room()
+	now := time.Now()
+	users := room.GetUserList(c.IsOperator())
+	entries := make([]ui.UserListEntry, 0, len(users))
+	for _, user := range users {
+		entries = append(entries, ui.UserListEntry{
+			Nickname:  user.Nickname,
+			Connected: now.Sub(user.JoinedAt),
+			Idle:      now.Sub(user.LastActive),
+			Away:      user.Away,
+			DND:       user.DND,
+			Invisible: user.Invisible,
+		})
+		if c.IsOperator() {
+			entries[len(entries)-1].Source = user.Source
+		}
+	}
+	msg := c.render().FormatUserList(room.Name, entries, room.MaxUsers, c.Width())
+	return c.write(msg + c.eol)
+}
+
+// showNames lists the nicknames in the room on one line, for scripts and
+// bots: separated by spaces, or as a JSON array if asJSON is set
+func (c *Client) showNames(asJSON bool) error {
+	users := c.Room().GetUserList(c.IsOperator())
+	names := make([]string, 0, len(users))
+	for _, user := range users {
+		names = append(names, user.Nickname)
+	}
+	
+	if !asJSON {
+		return c.write(strings.Join(names, " ") + c.eol)
+	}
+	data, err := json.Marshal(names)
+	if err != nil {
+		return fmt.Errorf("error encoding names: %w", err)
+	}
+	return c.write(string(data) + c.eol)
+}
+
+// showWhois shows details about a user anywhere on the server. Operators
+// also see where they connected from and whether they are hidden or muted.
+func (c *Client) showWhois(nickname string) error {
+	operator := c.IsOperator()
+	target, ok := c.manager.FindClient(nickname)
+	var room *Room
+	if ok {
+		room = target.Room()
+	}
+	if room == nil || (target.Invisible() && !operator && target != c) {
+		return errors.New(c.t("user.not_found", nickname))
+	}
+	
+	now := time.Now()
+	awayMessage, away := target.Away()
+	entry := ui.WhoisEntry{
+		Nickname:    target.Nickname(),
+		Room:        room.Name,
+		Connected:   now.Sub(target.JoinedAt),
+		Idle:        now.Sub(target.LastActive()),
+		Away:        away,
+		AwayMessage: awayMessage,
+		Operator:    target.IsOperator(),
+	}
+	if operator {
+		entry.Invisible = target.Invisible()
+		if until, muted := target.Muted(); muted {
+			entry.Muted = until.Sub(now)
+		}
+		entry.SendLatency, entry.WorstLatency = target.SendLatency()
+		entry.Source = target.config.Source
+		entry.Identity = target.config.Identity
+	}
+	return c.write(c.render().FormatWhois(entry) + c.eol)
+}
+
+// showRoomList shows the list of open rooms
+func (c *Client) showRoomList() error {
+	rooms := c.manager.Rooms()
+	names := make([]string, 0, len(rooms))
+	counts := make([]int, 0, len(rooms))
+	for _, room := range rooms {
+		names = append(names, room.Name)
+		counts = append(counts, room.UserCount())
+	}
+	
+	msg := c.render().FormatRoomList(names, counts, c.Room().Name)
+	return c.write(msg + c.eol)
+}
+
+// exportHistory saves the current room's recent history to a file on the server
+func (c *Client) exportHistory() error {
+	if c.config.ExportDir == "" {
+		return errors.New(c.t("export.disabled"))
+	}
+	
+	room := c.Room()
+	messages := room.History()
+	if len(messages) == 0 {
+		return errors.New(c.t("export.empty", room.Name))
+	}
+	
+	path, err := exportTranscript(c.config.ExportDir, room.Name, messages)
+	if err != nil {
+		c.logger.Error("Error exporting history", "room", room.Name, "error", err)
+		return errors.New(c.t("export.failed"))
+	}
+	
+	c.logger.Info("History exported", "room", room.Name, "path", path, "messages", len(messages))
+	c.sendSystemMessage(c.t("export.done", len(messages), path))
+	return nil
+}
+
+// showStats shows server statistics
+func (c *Client) showStats() error {
+	if c.config.Stats == nil {
+		return errors.New(c.t("stats.unavailable"))
+	}
+	
+	stats := c.config.Stats.Stats()
+	msg := c.render().FormatStats(stats.Uptime, stats.Messages, stats.Users, stats.PeakUsers, stats.Rooms)
+	return c.write(msg + c.eol)
+}
+
+// announce sends an operator announcement to every room on the server
+func (c *Client) announce(content string) error {
+	c.logger.Info("Announcement", "source", c.Source(), "content", content)
+	c.manager.Broadcast(Message{
+		From:           c.Nickname(),
+		Content:        content,
+		Timestamp:      time.Now(),
+		IsSystem:       true,
+		IsAnnouncement: true,
+	})
+	return nil
+}
+
+// showVersion shows the server's build details
+func (c *Client) showVersion() error {
+	build := c.config.Build
+	msg := c.render().FormatVersion(build.Version, build.Commit, build.BuildDate, build.GoVersion)
+	return c.write(msg + c.eol)
+}
+
+// showHelp shows the help message
+func (c *Client) showHelp() error {
+	commands, operatorCommands := helpCommands()
+	if c.config.TopicLogOperatorsOnly {
+		// /topiclog is listed with /topic among the operator commands
+		commands = slices.DeleteFunc(commands, func(name string) bool { return name == "topiclog" })
+		operatorCommands = slices.Insert(operatorCommands, slices.Index(operatorCommands, "topic")+1, "topiclog")
+	}
+	if !c.IsOperator() {
+		operatorCommands = nil
+	}
+	helpMsg := c.render().FormatHelp(commands, c.macroNames(), operatorCommands, c.Width())
+	return c.write(helpMsg + c.eol)
+}
+
+// Nickname returns the client's current nickname
+func (c *Client) Nickname() string {
+	c.nickMu.RLock()
+	defer c.nickMu.RUnlock()
+	
+	return c.nickname
+}
+
+// setNickname changes the client's nickname. Rooms key clients by
+// nickname, so renames must go through RoomManager.Rename.
+func (c *Client) setNickname(nickname string) {
+	c.nickMu.Lock()
+	defer c.nickMu.Unlock()
+	
+	c.nickname = nickname
+}
+
+// LastActive returns when the client last sent a message
+func (c *Client) LastActive() time.Time {
+	c.activityMu.Lock()
+	defer c.activityMu.Unlock()
+	
+	return c.lastActive
+}
+
+// markActive records that the client just sent something
+func (c *Client) markActive() {
+	c.activityMu.Lock()
+	defer c.activityMu.Unlock()
+	
+	c.lastActive = time.Now()
+}
+
+// Invisible reports whether the client is hidden with /invisible
+func (c *Client) Invisible() bool {
+	return c.invisible.Load()
+}
+
+// DoNotDisturb reports whether the client refuses private messages with /dnd
+func (c *Client) DoNotDisturb() bool {
+	return c.dnd.Load()
+}
+
+// Away reports whether the client is away, along with the reason they gave
+func (c *Client) Away() (string, bool) {
+	c.awayMu.Lock()
+	defer c.awayMu.Unlock()
 	
-	// Log the message for debugging
-	log.Printf("Sending message from %s to %s: %s", msg.From, c.Nickname, msg.Content)
+	return c.awayMessage, c.away
+}
+
+// setAway marks the client as away with an optional reason
+func (c *Client) setAway(reason string) {
+	c.awayMu.Lock()
+	defer c.awayMu.Unlock()
+	
+	c.away = true
+	c.awayMessage = reason
+}
+
+// setBack clears the client's away status, reporting whether it was set
+func (c *Client) setBack() bool {
+	c.awayMu.Lock()
+	defer c.awayMu.Unlock()
+	
+	wasAway := c.away
+	c.away = false
+	c.awayMessage = ""
+	return wasAway
+}
+
+// Source describes where the client connected from: its Tailscale
+// identity when known, otherwise the ban source
+func (c *Client) Source() string {
+	if c.config.Identity != "" {
+		return c.config.Identity
+	}
+	return c.config.Source
+}
+
+// IsOperator reports whether the client has operator rights
+func (c *Client) IsOperator() bool {
+	return c.operator.Load()
+}
+
+// SetOperator grants or revokes operator rights
+func (c *Client) SetOperator(operator bool) {
+	c.operator.Store(operator)
+}
+
+// Width returns the client's terminal width in columns
+func (c *Client) Width() int {
+	if width := c.width.Load(); width > 0 {
+		return int(width)
+	}
+	return DefaultTerminalWidth
+}
+
+// setWindowSize records the window size reported by the client
+func (c *Client) setWindowSize(width, height int) {
+	c.logger.Debug("Client window size changed", "width", width, "height", height)
+	c.width.Store(int32(width))
+}
+
+// SetColor switches the client between styled and plain text output
+func (c *Client) SetColor(enabled bool) {
+	renderer := ui.NewPlainRenderer(c.config.Catalog)
+	if enabled {
+		renderer = ui.NewRenderer(c.config.Theme, c.config.Catalog)
+	}
+	c.renderer.Store(renderer.WithMarkdown(!c.markdownOff.Load()))
+}
+
+// t returns the text of a message in the client's language
+func (c *Client) t(id string, args ...any) string {
+	return c.config.Catalog.T(id, args...)
+}
+
+// Location returns the time zone the client sees timestamps in
+func (c *Client) Location() *time.Location {
+	if loc := c.location.Load(); loc != nil {
+		return loc
+	}
+	if c.config.Location != nil {
+		return c.config.Location
+	}
+	return time.Local
+}
+
+// TimeFormat returns the format the client sees timestamps in
+func (c *Client) TimeFormat() timeFormat {
+	if format := c.timeFormat.Load(); format != nil {
+		return *format
+	}
+	format, _ := lookupTimeFormat(DefaultTimeFormat)
+	return format
+}
+
+// setTimezone changes the time zone timestamps are shown in, given an IANA
+// name such as "America/New_York"
+func (c *Client) setTimezone(name string) error {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return errors.New(c.t("tz.unknown", name))
+	}
 	
-	if msg.IsSystem {
-		formatted = ui.FormatSystemMessage(msg.Content) + "\r\n"
+	c.location.Store(loc)
+	c.sendSystemMessage(c.t("tz.set", loc))
+	return nil
+}
+
+// render returns the renderer used to format output for this client
+func (c *Client) render() *ui.Renderer {
+	return c.renderer.Load()
+}
+
+// disconnect writes a final system message to the client and closes its
+// connection, causing Handle to return and the client to leave its room
+func (c *Client) disconnect(message string) {
+	c.ended.Store(true)
+	if err := c.write(c.render().FormatSystemMessage(message) + c.eol); err != nil {
+		c.logger.Debug("Error notifying client before disconnect", "error", err)
+	}
+	if err := c.conn.Close(); err != nil {
+		c.logger.Debug("Error closing connection", "error", err)
+	}
+}
+
+// Room returns the room the client is currently in
+func (c *Client) Room() *Room {
+	c.roomMu.RLock()
+	defer c.roomMu.RUnlock()
+	
+	return c.room
+}
+
+// setRoom updates the client's current room
+func (c *Client) setRoom(room *Room) {
+	c.roomMu.Lock()
+	defer c.roomMu.Unlock()
+	
+	c.room = room
+}
+
+// sendSystemMessage sends a system message to the client
+func (c *Client) sendSystemMessage(message string) {
+	msg := Message{
+		From:      "System",
+		Content:   message,
+		Timestamp: time.Now(),
+		IsSystem:  true,
+	}
+	
+	c.sendMessage(msg)
+}
+
+// formatMessage renders a message from this client's point of view
+func (c *Client) formatMessage(msg Message) string {
+	var formatted string
+	timeStr := msg.Timestamp.In(c.Location()).Format(c.TimeFormat().Layout)
+	
+	if msg.IsAnnouncement {
+		formatted = c.render().FormatAnnouncement(msg.From, msg.Content, timeStr) + c.eol
+	} else if msg.IsReport {
+		formatted = c.formatReport(msg) + c.eol
+	} else if msg.IsSystem {
+		formatted = c.render().FormatSystemMessage(msg.Content) + c.eol
+	} else if msg.IsPrivate {
+		formatted = c.render().FormatPrivateMessage(msg.From, msg.To, msg.Content, timeStr, msg.From == c.Nickname()) + c.eol
+	} else if msg.IsAction && msg.From == c.Nickname() {
+		formatted = c.render().FormatSelfActionMessage(msg.From, msg.Content, timeStr) + c.eol
 	} else if msg.IsAction {
-		formatted = ui.FormatActionMessage(msg.From, msg.Content) + "\r\n"
-	} else if msg.From == c.Nickname {
-		formatted = ui.FormatSelfMessage(msg.Content, timeStr) + "\r\n"
+		formatted = c.render().FormatActionMessage(msg.From, msg.Content, timeStr) + c.eol
+	} else if msg.From == c.Nickname() {
+		formatted = c.render().FormatSelfMessage(msg.Content, timeStr) + c.eol
 	} else {
-		formatted = ui.FormatUserMessage(msg.From, msg.Content, timeStr) + "\r\n"
+		formatted = c.render().FormatUserMessage(msg.From, msg.Color, msg.Content, timeStr) + c.eol
 	}
 	
-	// Use a safer approach to write to client
-	// Create a channel to receive any errors from the goroutine
-	errCh := make(chan error, 1)
+	if msg.Seq > 0 {
+		formatted = fmt.Sprintf("#%d ", msg.Seq) + formatted
+	}
+	return formatted
+}
+
+// sendMessage queues a message for delivery to the client. It never blocks;
+// if the client's queue is full the message is dropped, or the client is
+// disconnected if DisconnectSlow is set. Messages from ignored users are
+// dropped too.
+func (c *Client) sendMessage(msg Message) {
+	if c.isIgnored(msg) {
+		return
+	}
 	
-	// Ensure the write happens without blocking
-	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				log.Printf("Recovered from panic in sendMessage: %v", r)
-				errCh <- fmt.Errorf("panic in sendMessage: %v", r)
-			}
-			close(errCh)
-		}()
-		
-		c.mu.Lock()
-		defer c.mu.Unlock()
-		
-		// Check if connection is still valid
-		if c.conn == nil {
-			errCh <- fmt.Errorf("connection closed")
+	// Nothing more will be written to a client that is being disconnected
+	select {
+	case <-c.quit:
+		return
+	default:
+	}
+	
+	msg.queuedAt = time.Now()
+	select {
+	case c.outbound <- msg:
+	default:
+		if !c.config.DisconnectSlow {
+			c.logger.Warn("Outbound queue is full, dropping message", "from", msg.From)
 			return
 		}
 		
-		if _, err := c.writer.WriteString(formatted); err != nil {
-			errCh <- fmt.Errorf("error writing message: %w", err)
+		// The client has fallen too far behind to catch up. Closing can
+		// block on TLS connections, so it mustn't hold up the room.
+		c.quitOnce.Do(func() {
+			c.logger.Warn("Outbound queue is full, disconnecting client")
+			close(c.quit)
+			go c.conn.Close()
+		})
+	}
+}
+
+// writeLoop delivers queued messages in order until the client is stopped
+func (c *Client) writeLoop() {
+	defer c.forgetLatency()
+	for {
+		select {
+		case <-c.quit:
 			return
+		case msg := <-c.outbound:
+			c.logger.Debug("Sending message", "from", msg.From, "content", msg.Content)
+			
+			if err := c.writeMessage(msg); err != nil {
+				c.logger.Warn("Error sending message", "error", err)
+				continue
+			}
+			c.recordLatency(time.Since(msg.queuedAt))
 		}
-		
-		if err := c.writer.Flush(); err != nil {
-			errCh <- fmt.Errorf("error flushing message: %w", err)
-			return
+	}
+}
+
+// stopWriter stops the writer goroutine, discarding any undelivered messages
+func (c *Client) stopWriter() {
+	c.quitOnce.Do(func() {
+		close(c.quit)
+	})
+}
+
+// writeRaw writes bytes to the client unmodified, e.g. Telnet responses
+func (c *Client) writeRaw(data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	
+	c.armWriteDeadlineLocked()
+	if _, err := c.writer.Write(data); err != nil {
+		return c.checkWriteError(fmt.Errorf("error writing data: %w", err))
+	}
+	return c.checkWriteError(c.writer.Flush())
+}
+
+// armWriteDeadlineLocked bounds the next write so a client that stops
+// reading can't block the goroutine writing to it. c.mu must be held.
+func (c *Client) armWriteDeadlineLocked() {
+	if d, ok := c.conn.(deadlineTransport); ok && c.config.WriteTimeout > 0 {
+		if err := d.SetWriteDeadline(time.Now().Add(c.config.WriteTimeout)); err != nil {
+			c.logger.Warn("Error setting write deadline", "error", err)
 		}
-	}()
+	}
+}
+
+// checkWriteError treats a client whose write failed as dead, whether the
+// write timed out or the peer went away. Its queued messages are discarded
+// and its connection closed, so Handle returns and the client leaves its
+// room. Leaving is left to Handle so it happens exactly once.
+func (c *Client) checkWriteError(err error) error {
+	if err == nil {
+		return nil
+	}
 	
-	// Log any errors (non-blocking)
-	go func() {
-		for err := range errCh {
-			log.Printf("Error sending message to %s: %v", c.Nickname, err)
+	c.evictOnce.Do(func() {
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			c.logger.Warn("Write timed out, disconnecting client", "timeout", c.config.WriteTimeout)
+		} else {
+			c.logger.Info("Write failed, disconnecting client", "error", err)
 		}
-	}()
+		c.stopWriter()
+		c.conn.Close()
+	})
+	return err
 }
 
-// write writes a message to the client
+// writeTimed writes a message immediately, bypassing the outbound queue,
+// and reports how long it took to flush
+func (c *Client) writeTimed(msg Message) (time.Duration, error) {
+	start := time.Now()
+	if err := c.writeMessage(msg); err != nil {
+		return 0, err
+	}
+	return time.Since(start), nil
+}
+
+// writeMessage writes a single chat message to the client
+func (c *Client) writeMessage(msg Message) error {
+	if c.config.JSON {
+		return c.writeJSON(msg)
+	}
+	return c.write(c.formatMessage(msg))
+}
+
+// writeJSON writes a message as a single line of JSON. It bypasses the
+// buffered writer so each message reaches the connection in one Write,
+// which transports like WebSocket turn into one frame.
+func (c *Client) writeJSON(msg Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("error encoding message: %w", err)
+	}
+	
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	
+	c.armWriteDeadlineLocked()
+	if _, err := c.conn.Write(append(data, c.eol...)); err != nil {
+		return c.checkWriteError(fmt.Errorf("error writing message: %w", err))
+	}
+	return nil
+}
+
+// write writes a message to the client. JSON clients receive the text as
+// a system message.
 func (c *Client) write(message string) error {
+	if c.config.JSON {
+		content := strings.TrimRight(message, "\r\n")
+		if content == "" {
+			return nil
+		}
+		return c.writeJSON(Message{
+			From:      "System",
+			Content:   content,
+			Timestamp: time.Now(),
+			IsSystem:  true,
+		})
+	}
+	
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	
@@ -431,12 +1966,13 @@ func (c *Client) write(message string) error {
 		return fmt.Errorf("connection closed")
 	}
 	
+	c.armWriteDeadlineLocked()
 	if _, err := c.writer.WriteString(message); err != nil {
-		return fmt.Errorf("error writing message: %w", err)
+		return c.checkWriteError(fmt.Errorf("error writing message: %w", err))
 	}
 	
 	if err := c.writer.Flush(); err != nil {
-		return fmt.Errorf("error flushing message: %w", err)
+		return c.checkWriteError(fmt.Errorf("error flushing message: %w", err))
 	}
 	
 	return nil
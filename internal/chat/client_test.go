@@ -0,0 +1,518 @@
+package chat
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+// waitForGoroutines waits for the number of goroutines to drop to want,
+// failing the test if it doesn't
+func waitForGoroutines(t *testing.T, want int) {
+	t.Helper()
+	deadline := time.Now().Add(testTimeout)
+	for runtime.NumGoroutine() > want {
+		if time.Now().After(deadline) {
+			buf := make([]byte, 1<<16)
+			t.Fatalf("%d goroutines still running, want %d:\n%s", runtime.NumGoroutine(), want, buf[:runtime.Stack(buf, true)])
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// handle runs the client's Handle in the background, returning a channel
+// closed once it has returned
+func (f *fakeClient) handle(ctx context.Context) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f.Handle(ctx)
+	}()
+	return done
+}
+
+// waitForHandler waits for a channel returned by handle to be closed
+func waitForHandler(t *testing.T, done <-chan struct{}) {
+	t.Helper()
+	select {
+	case <-done:
+	case <-time.After(testTimeout):
+		t.Fatal("Handle did not return")
+	}
+}
+
+func TestHandleCancelledMidReadDoesNotLeak(t *testing.T) {
+	manager := newTestManager(t, 10)
+	before := runtime.NumGoroutine()
+
+	alice := newFakeClient(t, manager, "alice", ClientConfig{})
+	if err := manager.JoinLobby(alice.Client); err != nil {
+		t.Fatalf("joining the lobby: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := alice.handle(ctx)
+
+	// Let the reader block waiting for a line that never comes
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	waitForHandler(t, done)
+
+	// The server closes the connection once Handle returns, which ends the
+	// pending read
+	alice.transport.Close()
+	waitForGoroutines(t, before)
+}
+
+func TestMessageLengthCountsRunes(t *testing.T) {
+	alice := newFakeClient(t, nil, "alice", ClientConfig{MaxMessageLength: 10})
+
+	// Ten characters but thirty bytes
+	message := strings.Repeat("世", 10)
+	if len(message) <= 10 {
+		t.Fatalf("test message is only %d bytes", len(message))
+	}
+	if err := alice.validateMessageLength(message); err != nil {
+		t.Errorf("%d-character message rejected: %v", utf8.RuneCountInString(message), err)
+	}
+	if err := alice.validateMessageLength(message + "界"); err == nil {
+		t.Error("11-character message accepted")
+	}
+}
+
+func TestInvalidUTF8IsReplacedBeforeBroadcast(t *testing.T) {
+	manager := newTestManager(t, 10)
+	alice := newFakeClient(t, manager, "alice", ClientConfig{})
+	bob := newFakeClient(t, manager, "bob", ClientConfig{})
+	for _, c := range []*fakeClient{alice, bob} {
+		if err := manager.JoinLobby(c.Client); err != nil {
+			t.Fatalf("%s joining the lobby: %v", c.Nickname(), err)
+		}
+	}
+
+	alice.handleLine("caf\xe9 \xff\xfeok\n")
+	msg := bob.waitForMessage(t, "alice's message", func(msg Message) bool {
+		return msg.From == "alice"
+	})
+	if !utf8.ValidString(msg.Content) {
+		t.Errorf("broadcast contains invalid UTF-8: %q", msg.Content)
+	}
+	if want := "caf\uFFFD \uFFFDok"; msg.Content != want {
+		t.Errorf("broadcast %q, want %q", msg.Content, want)
+	}
+}
+
+// drain reads and keeps everything from r until it is closed
+func drain(r io.Reader) *lockedBuffer {
+	var buf lockedBuffer
+	go io.Copy(&buf, r)
+	return &buf
+}
+
+// lockedBuffer is a bytes.Buffer safe for concurrent use
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestNewClientOverPipe(t *testing.T) {
+	manager := newTestManager(t, 10)
+	server, user := net.Pipe()
+	defer user.Close()
+	output := drain(user)
+
+	type result struct {
+		client *Client
+		err    error
+	}
+	created := make(chan result, 1)
+	go func() {
+		client, err := NewClient(context.Background(), server, manager, ClientConfig{Logger: discardLogger, NoBanner: true})
+		created <- result{client, err}
+	}()
+	if _, err := user.Write([]byte("alice\r\n")); err != nil {
+		t.Fatalf("sending nickname: %v", err)
+	}
+
+	var client *Client
+	select {
+	case r := <-created:
+		if r.err != nil {
+			t.Fatalf("NewClient: %v", r.err)
+		}
+		client = r.client
+	case <-time.After(testTimeout):
+		t.Fatal("NewClient did not return")
+	}
+	defer client.stopWriter()
+
+	if got := client.Nickname(); got != "alice" {
+		t.Errorf("Nickname() = %q, want alice", got)
+	}
+	if _, ok := manager.Lobby().GetClient("alice"); !ok {
+		t.Error("alice is not in the lobby")
+	}
+	// The Telnet negotiation is written ahead of the welcome text
+	deadline := time.Now().Add(testTimeout)
+	for !strings.HasPrefix(output.String(), string([]byte{telnetIAC, telnetDO, telnetOptNAWS})) {
+		if time.Now().After(deadline) {
+			t.Fatalf("no window size request in %q", output.String())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestWriteTimeoutDisconnectsStalledClient(t *testing.T) {
+	manager := newTestManager(t, 10)
+
+	// Nothing ever reads from the other end of the pipe
+	server, user := net.Pipe()
+	defer user.Close()
+	client := newClient(server, manager, ClientConfig{JSON: true, Logger: discardLogger, WriteTimeout: 50 * time.Millisecond})
+	client.setNickname("alice")
+
+	// Without a writeLoop the join notice stays queued, so the write below
+	// is the one that stalls
+	if err := manager.JoinLobby(client); err != nil {
+		t.Fatalf("joining the lobby: %v", err)
+	}
+	handled := make(chan struct{})
+	go func() {
+		defer close(handled)
+		client.Handle(context.Background())
+	}()
+
+	start := time.Now()
+	err := client.write("hello" + client.eol)
+	if !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("write to a stalled client returned %v, want a deadline error", err)
+	}
+	if elapsed := time.Since(start); elapsed > testTimeout/2 {
+		t.Errorf("write took %v despite a 50ms timeout", elapsed)
+	}
+
+	// The stalled client is disconnected and leaves the room
+	select {
+	case <-handled:
+	case <-time.After(testTimeout):
+		t.Fatal("Handle did not return after the write timed out")
+	}
+	if _, ok := manager.Lobby().GetClient("alice"); ok {
+		t.Error("stalled client is still in the lobby")
+	}
+}
+
+// sendBurst runs n messages through the client's rate limiter, returning
+// how many were allowed
+func sendBurst(c *fakeClient, n int) int {
+	allowed := 0
+	for i := 0; i < n; i++ {
+		if c.checkRateLimit() == nil {
+			allowed++
+		}
+	}
+	return allowed
+}
+
+func TestOperatorRateLimit(t *testing.T) {
+	cfg := ClientConfig{MessageRateLimit: 3, RateLimitWindow: time.Minute}
+
+	user := newFakeClient(t, nil, "user", cfg)
+	if got := sendBurst(user, 10); got != 3 {
+		t.Errorf("user sent %d of 10 messages, want 3", got)
+	}
+
+	// Without an operator limit, operators aren't limited at all
+	op := newFakeClient(t, nil, "op", cfg)
+	op.SetOperator(true)
+	if got := sendBurst(op, 10); got != 10 {
+		t.Errorf("operator sent %d of 10 messages, want 10", got)
+	}
+
+	// With one, they get the higher limit
+	cfg.OperatorRateLimit = 6
+	limited := newFakeClient(t, nil, "limited", cfg)
+	limited.SetOperator(true)
+	if got := sendBurst(limited, 10); got != 6 {
+		t.Errorf("operator with a limit of 6 sent %d of 10 messages", got)
+	}
+}
+
+func TestNewClientCancelledAtNicknamePrompt(t *testing.T) {
+	manager := newTestManager(t, 10)
+	transport := newFakeTransport()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	returned := make(chan error, 1)
+	go func() {
+		_, err := NewClient(ctx, transport, manager, ClientConfig{JSON: true, Logger: discardLogger})
+		returned <- err
+	}()
+
+	// Wait for the prompt, then give up on the user ever answering
+	deadline := time.Now().Add(testTimeout)
+	for transport.Output() == "" {
+		if time.Now().After(deadline) {
+			t.Fatal("no nickname prompt was sent")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+
+	select {
+	case err := <-returned:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("NewClient returned %v, want context.Canceled", err)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("NewClient did not return after the context was cancelled")
+	}
+}
+
+func TestPartialLineBeforeDisconnectIsDelivered(t *testing.T) {
+	manager := newTestManager(t, 10)
+	alice := newFakeClient(t, manager, "alice", ClientConfig{})
+	bob := newFakeClient(t, manager, "bob", ClientConfig{})
+	for _, c := range []*fakeClient{alice, bob} {
+		if err := manager.JoinLobby(c.Client); err != nil {
+			t.Fatalf("%s joining the lobby: %v", c.Nickname(), err)
+		}
+	}
+	done := alice.handle(context.Background())
+
+	if err := alice.transport.Send("hello"); err != nil {
+		t.Fatalf("sending: %v", err)
+	}
+	alice.transport.Hangup()
+	waitForHandler(t, done)
+
+	msg := bob.waitForMessage(t, "alice's last message", func(msg Message) bool {
+		return msg.From == "alice"
+	})
+	if msg.Content != "hello" {
+		t.Errorf("bob received %q, want %q", msg.Content, "hello")
+	}
+	bob.waitForContent(t, "alice has left the room")
+}
+
+// queued returns how many clients are waiting for a place in room
+func queued(room *Room) int {
+	room.mu.RLock()
+	defer room.mu.RUnlock()
+	return len(room.waiting)
+}
+
+func TestQueuedClientCanChatOnceAdmitted(t *testing.T) {
+	manager := newTestManager(t, 2)
+	alice := newFakeClient(t, manager, "alice", ClientConfig{})
+	carol := newFakeClient(t, manager, "carol", ClientConfig{})
+	for _, c := range []*fakeClient{alice, carol} {
+		if err := manager.JoinLobby(c.Client); err != nil {
+			t.Fatalf("%s joining the lobby: %v", c.Nickname(), err)
+		}
+	}
+
+	// Bob connects over Telnet while the lobby is full
+	server, user := net.Pipe()
+	defer user.Close()
+	drain(user)
+	created := make(chan *Client, 1)
+	go func() {
+		client, err := NewClient(context.Background(), server, manager, ClientConfig{Logger: discardLogger, NoBanner: true, MaxQueue: 1, IdleTimeout: time.Minute})
+		if err != nil {
+			t.Errorf("NewClient: %v", err)
+		}
+		created <- client
+	}()
+	if _, err := user.Write([]byte("bob\r\n")); err != nil {
+		t.Fatalf("sending nickname: %v", err)
+	}
+	deadline := time.Now().Add(testTimeout)
+	for queued(manager.Lobby()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("bob was never queued")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	manager.Leave(carol.Client)
+	var bob *Client
+	select {
+	case bob = <-created:
+	case <-time.After(testTimeout):
+		t.Fatal("bob was not admitted")
+	}
+	if bob == nil {
+		return
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		bob.Handle(context.Background())
+	}()
+	defer func() {
+		user.Close()
+		<-done
+	}()
+
+	// A disconnected bob would never read the line, leaving the write stuck
+	user.SetWriteDeadline(time.Now().Add(testTimeout))
+	if _, err := user.Write([]byte("hello after waiting\r\n")); err != nil {
+		t.Fatalf("sending message: %v", err)
+	}
+	alice.waitForContent(t, "hello after waiting")
+	select {
+	case <-done:
+		t.Error("bob was disconnected after being admitted")
+	default:
+	}
+}
+
+// queueClient connects a JSON client called nickname to manager's full
+// lobby over a fakeTransport and waits until it is queued. NewClient's
+// result is sent on the returned channel once it returns.
+func queueClient(t *testing.T, manager *RoomManager, nickname string) (*fakeTransport, <-chan error, <-chan *Client) {
+	t.Helper()
+	transport := newFakeTransport()
+	t.Cleanup(func() { transport.Close() })
+	errs := make(chan error, 1)
+	clients := make(chan *Client, 1)
+	go func() {
+		client, err := NewClient(context.Background(), transport, manager, ClientConfig{JSON: true, Logger: discardLogger, NoBanner: true, MaxQueue: 1})
+		errs <- err
+		clients <- client
+	}()
+	if err := transport.Send(nickname + "\n"); err != nil {
+		t.Fatalf("sending nickname: %v", err)
+	}
+	deadline := time.Now().Add(testTimeout)
+	for queued(manager.Lobby()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("%s was never queued", nickname)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return transport, errs, clients
+}
+
+// Transports without deadlines can't have a pending read interrupted, so
+// the reader that watched the queue must carry on for Handle
+func TestQueuedClientWithoutDeadlines(t *testing.T) {
+	manager := newTestManager(t, 2)
+	alice := newFakeClient(t, manager, "alice", ClientConfig{})
+	carol := newFakeClient(t, manager, "carol", ClientConfig{})
+	for _, c := range []*fakeClient{alice, carol} {
+		if err := manager.JoinLobby(c.Client); err != nil {
+			t.Fatalf("%s joining the lobby: %v", c.Nickname(), err)
+		}
+	}
+	transport, errs, clients := queueClient(t, manager, "bob")
+
+	// Input while waiting is answered but not sent to the room
+	if err := transport.Send("anyone there?\n"); err != nil {
+		t.Fatalf("sending while queued: %v", err)
+	}
+	manager.Leave(carol.Client)
+	if err := <-errs; err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	bob := <-clients
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		bob.Handle(context.Background())
+	}()
+
+	if err := transport.Send("hello after waiting\n"); err != nil {
+		t.Fatalf("sending once admitted: %v", err)
+	}
+	alice.waitForContent(t, "hello after waiting")
+	for _, msg := range alice.ReceivedMessages() {
+		if msg.Content == "anyone there?" {
+			t.Error("input sent while queued reached the room")
+		}
+	}
+
+	transport.Hangup()
+	waitForHandler(t, done)
+}
+
+func TestQuitWhileQueued(t *testing.T) {
+	manager := newTestManager(t, 1)
+	alice := newFakeClient(t, manager, "alice", ClientConfig{})
+	if err := manager.JoinLobby(alice.Client); err != nil {
+		t.Fatalf("alice joining the lobby: %v", err)
+	}
+	transport, errs, _ := queueClient(t, manager, "bob")
+
+	if err := transport.Send("/quit\n"); err != nil {
+		t.Fatalf("sending /quit: %v", err)
+	}
+	select {
+	case err := <-errs:
+		if !errors.Is(err, errLeftQueue) {
+			t.Errorf("NewClient returned %v, want errLeftQueue", err)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("NewClient did not return after /quit")
+	}
+	if n := queued(manager.Lobby()); n != 0 {
+		t.Errorf("%d clients still queued", n)
+	}
+}
+
+func TestHelpListsRestrictedTopicLogForOperators(t *testing.T) {
+	const line = "/topiclog - Show who changed the room topic, and when"
+	manager := newTestManager(t, 10)
+
+	// help returns the help text shown to a client, split at the operator
+	// commands
+	help := func(nickname string, cfg ClientConfig, operator bool) (everyone, operators string) {
+		client := newFakeClient(t, manager, nickname, cfg)
+		client.SetOperator(operator)
+		if err := client.showHelp(); err != nil {
+			t.Fatalf("showing help to %s: %v", nickname, err)
+		}
+		var content string
+		for _, msg := range client.ReceivedMessages() {
+			content += msg.Content
+		}
+		everyone, operators, _ = strings.Cut(content, "Operator Commands:")
+		return everyone, operators
+	}
+
+	everyone, _ := help("alice", ClientConfig{}, false)
+	if !strings.Contains(everyone, line) {
+		t.Errorf("/topiclog missing from the help for everyone:\n%s", everyone)
+	}
+
+	restricted := ClientConfig{TopicLogOperatorsOnly: true}
+	everyone, operators := help("bob", restricted, false)
+	if strings.Contains(everyone+operators, line) {
+		t.Errorf("/topiclog listed for a user who can't use it:\n%s", everyone)
+	}
+	everyone, operators = help("carol", restricted, true)
+	if strings.Contains(everyone, line) || !strings.Contains(operators, line) {
+		t.Errorf("/topiclog not listed only among the operator commands:\n%s\n---\n%s", everyone, operators)
+	}
+}
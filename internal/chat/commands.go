@@ -0,0 +1,710 @@
+package chat
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bscott/ts-chat/internal/ui"
+)
+
+// command describes a chat command. handleCommand checks the arguments
+// and operator status before running it, /help lists it and tab completion
+// offers its names.
+type command struct {
+	Name         string   // Including the slash, e.g. "/kick"
+	Aliases      []string // Other names that run the command, e.g. "/w" for "/msg"
+	HasUsage     bool     // Has a usage line, the catalog message "usage.<command>", shown when the arguments don't fit
+	UsageArgs    []any    // Arguments for the usage line's message
+	MinArgs      int      // Fewest arguments accepted
+	MaxArgs      int      // Most arguments accepted
+	Rest         bool     // The last argument takes the rest of the line, spaces included
+	Operator     bool     // Only operators may run the command
+	OperatorHelp bool     // Also listed with the operator commands, for commands with operator-only forms
+	Hidden       bool     // Left out of /help's command list
+	Run          func(c *Client, args []string) error
+}
+
+// errUsage is returned by a command whose arguments don't make sense, so
+// handleCommand shows its usage
+var errUsage = errors.New("invalid command usage")
+
+// usageError is an errUsage that also says what was wrong with the arguments
+type usageError string
+
+func (e usageError) Error() string { return errUsage.Error() + ": " + string(e) }
+func (e usageError) Unwrap() error { return errUsage }
+
+var (
+	commandList  []*command              // Registered commands in /help order
+	commandIndex = map[string]*command{} // Commands by name and alias
+)
+
+// registerCommand adds a command to the registry. Names and aliases must
+// be unique.
+func registerCommand(cmd *command) {
+	for _, name := range append([]string{cmd.Name}, cmd.Aliases...) {
+		if _, exists := commandIndex[name]; exists {
+			panic("chat: command registered twice: " + name)
+		}
+		commandIndex[name] = cmd
+	}
+	commandList = append(commandList, cmd)
+}
+
+// lookupCommand returns the command a name or alias refers to, ignoring case
+func lookupCommand(name string) (*command, bool) {
+	cmd, ok := commandIndex[strings.ToLower(name)]
+	return cmd, ok
+}
+
+// commandNames returns every command name and alias, sorted, for tab
+// completion
+func commandNames() []string {
+	names := make([]string, 0, len(commandIndex))
+	for name := range commandIndex {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// helpCommands returns the commands /help lists for everyone and for
+// operators, without the slash. Each one's line is the catalog message
+// "help.<command>" or "help.op.<command>".
+func helpCommands() (everyone, operator []string) {
+	for _, cmd := range commandList {
+		name := strings.TrimPrefix(cmd.Name, "/")
+		if !cmd.Operator && !cmd.Hidden {
+			everyone = append(everyone, name)
+		}
+		if cmd.Operator || cmd.OperatorHelp {
+			operator = append(operator, name)
+		}
+	}
+	return everyone, operator
+}
+
+// splitArgs splits a command's arguments on whitespace. With rest set, the
+// max'th argument takes the rest of the line.
+func splitArgs(s string, max int, rest bool) []string {
+	if !rest {
+		return strings.Fields(s)
+	}
+
+	var args []string
+	s = strings.TrimSpace(s)
+	for s != "" {
+		i := strings.IndexAny(s, " \t")
+		if len(args) == max-1 || i < 0 {
+			return append(args, s)
+		}
+		args = append(args, s[:i])
+		s = strings.TrimLeft(s[i:], " \t")
+	}
+	return args
+}
+
+// onOff parses the argument of a command such as /color on|off
+func onOff(arg string) (bool, error) {
+	switch strings.ToLower(arg) {
+	case "on":
+		return true, nil
+	case "off":
+		return false, nil
+	}
+	return false, errUsage
+}
+
+func init() {
+	for _, cmd := range builtinCommands {
+		registerCommand(cmd)
+	}
+}
+
+// builtinCommands are the commands every client has, in /help order
+var builtinCommands = []*command{
+	{
+		Name: "/who",
+		Run:  noArgs((*Client).showUserList),
+	},
+	{
+		Name:     "/names",
+		HasUsage: true,
+		MaxArgs:  1,
+		Run: func(c *Client, args []string) error {
+			switch optionalArg(args, 0) {
+			case "":
+				return c.showNames(false)
+			case "--json":
+				return c.showNames(true)
+			}
+			return errUsage
+		},
+	},
+	{
+		Name:     "/whois",
+		HasUsage: true,
+		MinArgs:  1, MaxArgs: 1,
+		Run: func(c *Client, args []string) error {
+			return c.showWhois(args[0])
+		},
+	},
+	{
+		Name:     "/report",
+		HasUsage: true,
+		MinArgs:  1, MaxArgs: 2, Rest: true,
+		Run: func(c *Client, args []string) error {
+			return c.report(args[0], optionalArg(args, 1))
+		},
+	},
+	{
+		Name:     "/me",
+		HasUsage: true,
+		MinArgs:  1, MaxArgs: 1, Rest: true,
+		Run: func(c *Client, args []string) error {
+			action, ok := c.filterContent(args[0])
+			if !ok {
+				return nil
+			}
+			c.broadcastOwn(Message{
+				From:      c.Nickname(),
+				Content:   action,
+				Timestamp: time.Now(),
+				IsAction:  true,
+			})
+			return nil
+		},
+	},
+	{
+		Name:     "/slap",
+		HasUsage: true,
+		MinArgs:  1, MaxArgs: 1,
+		Run: func(c *Client, args []string) error {
+			return c.performAction("/slap", args[0])
+		},
+	},
+	{
+		Name:     "/hug",
+		HasUsage: true,
+		MinArgs:  1, MaxArgs: 1,
+		Run: func(c *Client, args []string) error {
+			return c.performAction("/hug", args[0])
+		},
+	},
+	{
+		Name:     "/roll",
+		HasUsage: true,
+		MaxArgs:  1,
+		Run: func(c *Client, args []string) error {
+			spec := "1d6"
+			if len(args) > 0 {
+				spec = args[0]
+			}
+			count, sides, err := parseDice(spec)
+			if err != nil {
+				return usageError(c.t("dice.invalid", spec))
+			}
+			if count < 1 || count > MaxDice {
+				return errors.New(c.t("dice.count", MaxDice))
+			}
+			if sides < 2 || sides > MaxDiceSide {
+				return errors.New(c.t("dice.sides", MaxDiceSide))
+			}
+			rolls, err := rollDice(count, sides)
+			if err != nil {
+				return err
+			}
+			c.broadcastOwn(Message{
+				From:      c.Nickname(),
+				Content:   formatRoll(c.config.Catalog, count, sides, rolls),
+				Timestamp: time.Now(),
+				IsAction:  true,
+			})
+			return nil
+		},
+	},
+	{
+		Name:     "/msg",
+		Aliases:  []string{"/w"},
+		HasUsage: true,
+		MinArgs:  2, MaxArgs: 2, Rest: true,
+		Run: func(c *Client, args []string) error {
+			return c.sendPrivateMessage(args[0], args[1])
+		},
+	},
+	{
+		Name:     "/nick",
+		HasUsage: true,
+		MinArgs:  1, MaxArgs: 1,
+		Run: func(c *Client, args []string) error {
+			return c.changeNickname(args[0])
+		},
+	},
+	{
+		Name:     "/away",
+		HasUsage: true,
+		MaxArgs:  1, Rest: true,
+		Run: func(c *Client, args []string) error {
+			if len(args) == 0 {
+				c.setAway("")
+				c.sendSystemMessage(c.t("away.on"))
+				return nil
+			}
+			c.setAway(args[0])
+			c.sendSystemMessage(c.t("away.on_reason", args[0]))
+			return nil
+		},
+	},
+	{
+		Name: "/back",
+		Run: func(c *Client, args []string) error {
+			wasDND := c.dnd.Swap(false)
+			if wasDND {
+				c.sendSystemMessage(c.t("dnd.off"))
+			}
+			if !c.setBack() {
+				if !wasDND {
+					c.sendSystemMessage(c.t("away.not"))
+				}
+				return nil
+			}
+			c.sendSystemMessage(c.t("away.off"))
+			return nil
+		},
+	},
+	{
+		Name:     "/mycolor",
+		HasUsage: true,
+		MaxArgs:  1,
+		Run: func(c *Client, args []string) error {
+			if len(args) == 0 {
+				color := c.NickColor()
+				if color == "" {
+					color = c.t("nickcolor.default")
+				}
+				c.sendSystemMessage(c.t("nickcolor.show", color, strings.Join(ui.NickColorNames(), ", ")))
+				return nil
+			}
+			return c.setMyColor(args[0])
+		},
+	},
+	{
+		Name: "/dnd",
+		Run: func(c *Client, args []string) error {
+			dnd := !c.dnd.Load()
+			c.dnd.Store(dnd)
+			if dnd {
+				c.sendSystemMessage(c.t("dnd.on"))
+			} else {
+				c.sendSystemMessage(c.t("dnd.off"))
+			}
+			return nil
+		},
+	},
+	{
+		Name: "/invisible",
+		Run: func(c *Client, args []string) error {
+			invisible := !c.invisible.Load()
+			c.invisible.Store(invisible)
+			c.Room().announceVisibility(c, !invisible)
+			if invisible {
+				c.sendSystemMessage(c.t("invisible.on"))
+			} else {
+				c.sendSystemMessage(c.t("invisible.off"))
+			}
+			return nil
+		},
+	},
+	{
+		Name:     "/join",
+		HasUsage: true,
+		MinArgs:  1, MaxArgs: 1, Rest: true,
+		Run: func(c *Client, args []string) error {
+			return c.joinRoom(args[0])
+		},
+	},
+	{
+		Name: "/leave",
+		Run: func(c *Client, args []string) error {
+			lobby := c.manager.Lobby()
+			if c.Room() == lobby {
+				c.sendSystemMessage(c.t("room.already_in", lobby.Name))
+				return nil
+			}
+			return c.joinRoom(lobby.Name)
+		},
+	},
+	{
+		Name: "/rooms",
+		Run:  noArgs((*Client).showRoomList),
+	},
+	{
+		Name:         "/topic",
+		HasUsage:     true,
+		MaxArgs:      1,
+		Rest:         true,
+		OperatorHelp: true,
+		Run: func(c *Client, args []string) error {
+			if len(args) == 0 {
+				if c.Room().Topic() == "" {
+					c.sendSystemMessage(c.t("topic.none"))
+					return nil
+				}
+				return c.showTopic()
+			}
+			if !c.IsOperator() {
+				return errors.New(c.t("permission.operator", "/topic <text>"))
+			}
+			topic := args[0]
+			if topic == "-" {
+				topic = ""
+			}
+			room := c.Room()
+			c.logger.Info("Topic changed", "room", room.Name, "topic", topic)
+			room.SetTopic(topic, c.Nickname())
+			return nil
+		},
+	},
+	{
+		Name: "/topiclog",
+		Run:  noArgs((*Client).showTopicLog),
+	},
+	{
+		Name:         "/setmaxlen",
+		HasUsage:     true,
+		UsageArgs:    []any{MinRoomMessageLength, MaxRoomMessageLength},
+		MaxArgs:      1,
+		OperatorHelp: true,
+		Run: func(c *Client, args []string) error {
+			room := c.Room()
+			if len(args) == 0 {
+				c.sendSystemMessage(c.t("setmaxlen.show", room.Name, c.maxMessageLength()))
+				return nil
+			}
+			if !c.IsOperator() {
+				return errors.New(c.t("permission.operator", "/setmaxlen"))
+			}
+			limit := 0
+			if args[0] != "-" {
+				n, err := strconv.Atoi(args[0])
+				if err != nil || n < MinRoomMessageLength || n > MaxRoomMessageLength {
+					return errUsage
+				}
+				limit = n
+			}
+			c.logger.Info("Maximum message length changed", "room", room.Name, "limit", limit)
+			room.SetMaxMessageLength(limit, c.Nickname())
+			return nil
+		},
+	},
+	{
+		Name:     "/ignore",
+		HasUsage: true,
+		MaxArgs:  1,
+		Run: func(c *Client, args []string) error {
+			if len(args) == 0 {
+				c.showIgnored()
+				return nil
+			}
+			return c.ignoreUser(args[0])
+		},
+	},
+	{
+		Name:     "/unignore",
+		HasUsage: true,
+		MinArgs:  1, MaxArgs: 1,
+		Run: func(c *Client, args []string) error {
+			return c.unignoreUser(args[0])
+		},
+	},
+	{
+		Name:         "/color",
+		HasUsage:     true,
+		MaxArgs:      2,
+		OperatorHelp: true,
+		Run: func(c *Client, args []string) error {
+			if len(args) == 2 {
+				return c.setUserColor(args[0], args[1])
+			}
+			if len(args) == 0 {
+				if c.render().Colored() {
+					c.sendSystemMessage(c.t("color.is_on"))
+				} else {
+					c.sendSystemMessage(c.t("color.is_off"))
+				}
+				return nil
+			}
+			enabled, err := onOff(args[0])
+			if err != nil {
+				return err
+			}
+			c.SetColor(enabled)
+			if enabled {
+				c.sendSystemMessage(c.t("color.enabled"))
+			} else {
+				c.sendSystemMessage(c.t("color.disabled"))
+			}
+			return nil
+		},
+	},
+	{
+		Name:     "/markdown",
+		HasUsage: true,
+		MaxArgs:  1,
+		Run: func(c *Client, args []string) error {
+			if len(args) == 0 {
+				if c.render().Markdown() {
+					c.sendSystemMessage(c.t("markdown.is_on"))
+				} else {
+					c.sendSystemMessage(c.t("markdown.is_off"))
+				}
+				return nil
+			}
+			enabled, err := onOff(args[0])
+			if err != nil {
+				return err
+			}
+			c.markdownOff.Store(!enabled)
+			if enabled {
+				c.sendSystemMessage(c.t("markdown.enabled"))
+			} else {
+				c.sendSystemMessage(c.t("markdown.disabled"))
+			}
+			c.SetColor(c.render().Colored())
+			return nil
+		},
+	},
+	{
+		Name: "/clear",
+		Run: func(c *Client, args []string) error {
+			seq := c.render().ClearScreen()
+			if seq == "" {
+				c.sendSystemMessage(c.t("clear.no_ansi"))
+				return nil
+			}
+			return c.write(seq)
+		},
+	},
+	{
+		Name:     "/tz",
+		HasUsage: true,
+		MaxArgs:  1,
+		Run: func(c *Client, args []string) error {
+			if len(args) == 0 {
+				c.sendSystemMessage(c.t("tz.show", c.Location()))
+				return nil
+			}
+			return c.setTimezone(args[0])
+		},
+	},
+	{
+		Name:      "/timeformat",
+		HasUsage:  true,
+		UsageArgs: []any{timeFormatNames()},
+		MaxArgs:   1,
+		Run: func(c *Client, args []string) error {
+			if len(args) == 0 {
+				c.sendSystemMessage(c.t("timeformat.show", c.TimeFormat().Name, timeFormatNames()))
+				return nil
+			}
+			format, err := lookupTimeFormat(args[0])
+			if err != nil {
+				return errors.New(c.t("timeformat.unknown", args[0], timeFormatNames()))
+			}
+			c.timeFormat.Store(&format)
+			c.sendSystemMessage(c.t("timeformat.set", format.Name, time.Now().In(c.Location()).Format(format.Layout)))
+			return nil
+		},
+	},
+	{
+		Name:     "/emoji",
+		HasUsage: true,
+		MaxArgs:  1,
+		Run: func(c *Client, args []string) error {
+			if len(args) == 0 {
+				return c.write(c.render().FormatEmojiList(emojiList(), !c.emojiOff.Load(), c.Width()) + c.eol)
+			}
+			enabled, err := onOff(args[0])
+			if err != nil {
+				return err
+			}
+			c.emojiOff.Store(!enabled)
+			if enabled {
+				c.sendSystemMessage(c.t("emoji.enabled"))
+			} else {
+				c.sendSystemMessage(c.t("emoji.disabled"))
+			}
+			return nil
+		},
+	},
+	{
+		Name:     "/history",
+		HasUsage: true,
+		MaxArgs:  1,
+		Run: func(c *Client, args []string) error {
+			count := defaultHistoryCount
+			if len(args) > 0 {
+				n, err := strconv.Atoi(args[0])
+				if err != nil || n <= 0 {
+					return errUsage
+				}
+				count = n
+			}
+			return c.showHistory(count)
+		},
+	},
+	{
+		Name: "/stats",
+		Run:  noArgs((*Client).showStats),
+	},
+	{
+		Name: "/uptime",
+		Run: func(c *Client, args []string) error {
+			if c.config.Stats == nil {
+				return errors.New(c.t("uptime.unavailable"))
+			}
+			c.sendSystemMessage(c.t("uptime.show", ui.FormatUptime(c.config.Stats.Stats().Uptime)))
+			return nil
+		},
+	},
+	{
+		Name: "/version",
+		Run:  noArgs((*Client).showVersion),
+	},
+	{
+		Name: "/ping",
+		Run:  noArgs((*Client).replyPing),
+	},
+	{
+		Name: "/help",
+		Run:  noArgs((*Client).showHelp),
+	},
+	{
+		Name: "/quit",
+		// Anything after /quit is ignored rather than refused, so the
+		// user always gets out
+		MaxArgs: 1, Rest: true,
+		Run: func(c *Client, args []string) error {
+			c.ended.Store(true)
+			// Written directly so the goodbye isn't lost when the connection closes
+			c.write(c.render().FormatSystemMessage(c.t("quit.goodbye")) + c.eol)
+			if err := c.conn.Close(); err != nil {
+				return fmt.Errorf("error closing connection: %w", err)
+			}
+			return nil
+		},
+	},
+	{
+		// /help describes /op to everyone who isn't an operator yet
+		Name:     "/op",
+		HasUsage: true,
+		MinArgs:  1, MaxArgs: 1, Rest: true,
+		Hidden: true,
+		Run: func(c *Client, args []string) error {
+			return c.authenticateOperator(args[0])
+		},
+	},
+	{
+		Name:     "/announce",
+		HasUsage: true,
+		MinArgs:  1, MaxArgs: 1, Rest: true,
+		Operator: true,
+		Run: func(c *Client, args []string) error {
+			return c.announce(args[0])
+		},
+	},
+	{
+		Name:     "/kick",
+		HasUsage: true,
+		MinArgs:  1, MaxArgs: 2, Rest: true,
+		Operator: true,
+		Run: func(c *Client, args []string) error {
+			return c.kickUser(args[0], optionalArg(args, 1))
+		},
+	},
+	{
+		Name:     "/mute",
+		HasUsage: true,
+		MinArgs:  1, MaxArgs: 2,
+		Operator: true,
+		Run: func(c *Client, args []string) error {
+			duration := defaultMuteDuration
+			if len(args) > 1 {
+				d, err := time.ParseDuration(args[1])
+				if err != nil || d <= 0 {
+					return usageError(c.t("mute.not_duration", args[1]))
+				}
+				duration = d
+			}
+			return c.muteUser(args[0], duration)
+		},
+	},
+	{
+		Name:     "/unmute",
+		HasUsage: true,
+		MinArgs:  1, MaxArgs: 1,
+		Operator: true,
+		Run: func(c *Client, args []string) error {
+			return c.unmuteUser(args[0])
+		},
+	},
+	{
+		Name:     "/ban",
+		HasUsage: true,
+		MinArgs:  1, MaxArgs: 2, Rest: true,
+		Operator: true,
+		Run: func(c *Client, args []string) error {
+			return c.banUser(args[0], optionalArg(args, 1))
+		},
+	},
+	{
+		Name:     "/unban",
+		HasUsage: true,
+		MinArgs:  1, MaxArgs: 1, Rest: true,
+		Operator: true,
+		Run: func(c *Client, args []string) error {
+			return c.unbanSource(args[0])
+		},
+	},
+	{
+		Name:     "/banlist",
+		Operator: true,
+		Run:      noArgs((*Client).showBanList),
+	},
+	{
+		Name:     "/filter",
+		HasUsage: true,
+		MinArgs:  1, MaxArgs: 1,
+		Operator: true,
+		Run: func(c *Client, args []string) error {
+			if args[0] != "reload" {
+				return errUsage
+			}
+			return c.reloadFilter()
+		},
+	},
+	{
+		Name:     "/export",
+		Operator: true,
+		Run:      noArgs((*Client).exportHistory),
+	},
+}
+
+// noArgs adapts a method taking no arguments to a command's Run
+func noArgs(run func(*Client) error) func(*Client, []string) error {
+	return func(c *Client, _ []string) error {
+		return run(c)
+	}
+}
+
+// optionalArg returns the i'th argument, or an empty string if it wasn't given
+func optionalArg(args []string, i int) string {
+	if i < len(args) {
+		return args[i]
+	}
+	return ""
+}
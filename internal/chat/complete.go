@@ -0,0 +1,31 @@
+package chat
+
+import "strings"
+
+// completions returns the candidates for the word being typed, ignoring
+// case. A word starting the line with "/" is completed as a command, any
+// other word as the nickname of someone in the client's room.
+func (c *Client) completions(word string, first bool) []string {
+	var candidates []string
+	if first && strings.HasPrefix(word, "/") {
+		candidates = append(commandNames(), c.macroNames()...)
+	} else if room := c.Room(); room != nil {
+		for _, user := range room.GetUserList(c.IsOperator()) {
+			candidates = append(candidates, user.Nickname)
+		}
+	}
+
+	prefix := strings.ToLower(word)
+	var matches []string
+	for _, candidate := range candidates {
+		if strings.HasPrefix(strings.ToLower(candidate), prefix) {
+			matches = append(matches, candidate)
+		}
+	}
+	return matches
+}
+
+// formatCompletions renders the candidates for an ambiguous completion
+func (c *Client) formatCompletions(candidates []string) string {
+	return c.render().FormatSystemMessage(strings.Join(candidates, "  "))
+}
@@ -0,0 +1,74 @@
+package chat
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/bscott/ts-chat/internal/i18n"
+)
+
+// Limits on /roll so a single command can't flood the room
+const (
+	MaxDice     = 100
+	MaxDiceSide = 1000
+)
+
+// parseDice parses dice notation such as "2d20". The count may be omitted,
+// so "d6" rolls one die.
+func parseDice(spec string) (count, sides int, err error) {
+	countStr, sidesStr, ok := strings.Cut(strings.ToLower(spec), "d")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid dice %q", spec)
+	}
+
+	count = 1
+	if countStr != "" {
+		if count, err = strconv.Atoi(countStr); err != nil {
+			return 0, 0, fmt.Errorf("invalid dice %q", spec)
+		}
+	}
+	if sides, err = strconv.Atoi(sidesStr); err != nil {
+		return 0, 0, fmt.Errorf("invalid dice %q", spec)
+	}
+
+	return count, sides, nil
+}
+
+// rollDice rolls count dice with the given number of sides
+func rollDice(count, sides int) ([]int, error) {
+	if count < 1 || count > MaxDice {
+		return nil, fmt.Errorf("you can roll between 1 and %d dice", MaxDice)
+	}
+	if sides < 2 || sides > MaxDiceSide {
+		return nil, fmt.Errorf("dice must have between 2 and %d sides", MaxDiceSide)
+	}
+
+	rolls := make([]int, count)
+	for i := range rolls {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(sides)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to roll dice: %w", err)
+		}
+		rolls[i] = int(n.Int64()) + 1
+	}
+	return rolls, nil
+}
+
+// formatRoll describes a roll in the catalog's language, e.g. "rolls 2d20:
+// 14, 3 (total 17)"
+func formatRoll(catalog *i18n.Catalog, count, sides int, rolls []int) string {
+	total := 0
+	results := make([]string, len(rolls))
+	for i, roll := range rolls {
+		total += roll
+		results[i] = strconv.Itoa(roll)
+	}
+
+	if len(rolls) == 1 {
+		return catalog.T("dice.roll", count, sides, total)
+	}
+	return catalog.T("dice.rolls", count, sides, strings.Join(results, ", "), total)
+}
@@ -0,0 +1,109 @@
+package chat
+
+import (
+	"sort"
+	"strings"
+)
+
+// emojiShortcodes maps the shortcodes users can type, without their colons,
+// to the emoji they expand to
+var emojiShortcodes = map[string]string{
+	"smile":         "😄",
+	"grin":          "😁",
+	"joy":           "😂",
+	"wink":          "😉",
+	"blush":         "😊",
+	"heart_eyes":    "😍",
+	"thinking":      "🤔",
+	"neutral_face":  "😐",
+	"sweat_smile":   "😅",
+	"cry":           "😢",
+	"sob":           "😭",
+	"angry":         "😠",
+	"scream":        "😱",
+	"sunglasses":    "😎",
+	"sleeping":      "😴",
+	"thumbsup":      "👍",
+	"+1":            "👍",
+	"thumbsdown":    "👎",
+	"-1":            "👎",
+	"clap":          "👏",
+	"wave":          "👋",
+	"pray":          "🙏",
+	"muscle":        "💪",
+	"eyes":          "👀",
+	"heart":         "❤️",
+	"broken_heart":  "💔",
+	"fire":          "🔥",
+	"star":          "⭐",
+	"sparkles":      "✨",
+	"tada":          "🎉",
+	"rocket":        "🚀",
+	"coffee":        "☕",
+	"beer":          "🍺",
+	"pizza":         "🍕",
+	"bug":           "🐛",
+	"check":         "✅",
+	"x":             "❌",
+	"warning":       "⚠️",
+	"question":      "❓",
+	"100":           "💯",
+	"shrug":         "🤷",
+	"facepalm":      "🤦",
+	"see_no_evil":   "🙈",
+	"zap":           "⚡",
+	"skull":         "💀",
+	"ghost":         "👻",
+	"robot":         "🤖",
+	"partying_face": "🥳",
+}
+
+// expandEmoji replaces known shortcodes such as :smile: in s with their
+// emoji. Unknown shortcodes are left alone.
+func expandEmoji(s string) string {
+	if !strings.Contains(s, ":") {
+		return s
+	}
+
+	var sb strings.Builder
+	for {
+		start := strings.IndexByte(s, ':')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(s[start+1:], ':')
+		if end < 0 {
+			break
+		}
+		end += start + 1
+
+		if emoji, ok := emojiShortcodes[s[start+1:end]]; ok {
+			sb.WriteString(s[:start])
+			sb.WriteString(emoji)
+			s = s[end+1:]
+			continue
+		}
+
+		// Not a shortcode, so the closing colon may open the next one
+		sb.WriteString(s[:end])
+		s = s[end:]
+	}
+	sb.WriteString(s)
+	return sb.String()
+}
+
+// emojiList returns "emoji :shortcode:" for every shortcode, sorted by
+// shortcode
+func emojiList() []string {
+	codes := make([]string, 0, len(emojiShortcodes))
+	for code := range emojiShortcodes {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	entries := make([]string, 0, len(codes))
+	for _, code := range codes {
+		entries = append(entries, emojiShortcodes[code]+" :"+code+":")
+	}
+	return entries
+}
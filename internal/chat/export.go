@@ -0,0 +1,61 @@
+package chat
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// exportTranscript writes messages to a new timestamped file in dir and
+// returns its path. The file name is derived from the room name with
+// anything but letters, digits, '-' and '_' replaced, so a room name can't
+// point the file outside dir.
+func exportTranscript(dir, roomName string, messages []Message) (string, error) {
+	safe := strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9') {
+			return r
+		}
+		return '_'
+	}, roomName)
+	name := fmt.Sprintf("%s-%s.txt", safe, time.Now().Format("20060102-150405"))
+
+	path := filepath.Join(dir, name)
+	if filepath.Dir(path) != filepath.Clean(dir) {
+		return "", fmt.Errorf("invalid export file name %q", name)
+	}
+
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return "", fmt.Errorf("failed to create export directory: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o640)
+	if err != nil {
+		return "", fmt.Errorf("failed to create export file: %w", err)
+	}
+
+	w := bufio.NewWriter(f)
+	for _, msg := range messages {
+		fmt.Fprintln(w, formatTranscriptLine(msg))
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return "", fmt.Errorf("failed to write export file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return "", fmt.Errorf("failed to write export file: %w", err)
+	}
+
+	return path, nil
+}
+
+// formatTranscriptLine renders a message as a plain text transcript line
+func formatTranscriptLine(msg Message) string {
+	timestamp := msg.Timestamp.Format("2006-01-02 15:04:05")
+	if msg.IsAction {
+		return fmt.Sprintf("[%s] * %s %s", timestamp, msg.From, msg.Content)
+	}
+	return fmt.Sprintf("[%s] %s: %s", timestamp, msg.From, msg.Content)
+}
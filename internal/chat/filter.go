@@ -0,0 +1,12 @@
+package chat
+
+// WordFilter screens message content for banned words. It is implemented by
+// the server so the word list can be reloaded while running.
+type WordFilter interface {
+	// Filter returns content with banned words masked, or false if the
+	// message should be rejected instead
+	Filter(content string) (string, bool)
+
+	// Reload re-reads the word list, returning how many entries it has
+	Reload() (int, error)
+}
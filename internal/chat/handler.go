@@ -0,0 +1,63 @@
+package chat
+
+import (
+	"context"
+	"time"
+)
+
+// HandlerTimeout is how long a MessageHandler has to reply before its
+// reply is dropped
+const HandlerTimeout = 5 * time.Second
+
+// MessageHandler lets bots react to messages in a room without changing
+// the server. Handlers are registered with Room.RegisterHandler or, for
+// every room, RoomManager.RegisterHandler.
+type MessageHandler interface {
+	// Name is the nickname replies are sent under
+	Name() string
+
+	// HandleMessage is called with each user message broadcast in a room.
+	// A non-empty reply is broadcast to the room from Name. ctx is
+	// cancelled once HandlerTimeout passes or the room stops.
+	HandleMessage(ctx context.Context, msg Message) (string, error)
+}
+
+// runHandler passes msg to a handler and broadcasts its reply. It runs in
+// its own goroutine so slow handlers never hold up the room loop.
+func (r *Room) runHandler(h MessageHandler, msg Message) {
+	ctx, cancel := context.WithTimeout(r.ctx, HandlerTimeout)
+	defer cancel()
+
+	type result struct {
+		reply string
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		reply, err := h.HandleMessage(ctx, msg)
+		done <- result{reply, err}
+	}()
+
+	var res result
+	select {
+	case res = <-done:
+	case <-ctx.Done():
+		r.logger.Warn("Message handler timed out", "handler", h.Name())
+		return
+	}
+
+	if res.err != nil {
+		r.logger.Error("Message handler failed", "handler", h.Name(), "error", res.err)
+		return
+	}
+	if res.reply == "" {
+		return
+	}
+
+	r.Broadcast(Message{
+		From:      h.Name(),
+		Content:   res.reply,
+		Timestamp: time.Now(),
+		IsBot:     true,
+	})
+}
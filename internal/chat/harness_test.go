@@ -0,0 +1,169 @@
+package chat
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testTimeout bounds how long tests wait for something to happen
+const testTimeout = 2 * time.Second
+
+// discardLogger keeps test output free of client and room logs
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// fakeAddr is the remote address of a fakeTransport
+type fakeAddr string
+
+func (a fakeAddr) Network() string { return "fake" }
+func (a fakeAddr) String() string  { return string(a) }
+
+// fakeTransport is an in-memory Transport. Input written with Send is read
+// by the client, and everything the client writes is kept for inspection.
+type fakeTransport struct {
+	in        *io.PipeReader
+	inWriter  *io.PipeWriter
+	out       bytes.Buffer
+	mu        sync.Mutex
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// newFakeTransport creates an open fakeTransport
+func newFakeTransport() *fakeTransport {
+	r, w := io.Pipe()
+	return &fakeTransport{in: r, inWriter: w, closed: make(chan struct{})}
+}
+
+func (t *fakeTransport) Read(p []byte) (int, error) { return t.in.Read(p) }
+
+func (t *fakeTransport) Write(p []byte) (int, error) {
+	select {
+	case <-t.closed:
+		return 0, net.ErrClosed
+	default:
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.out.Write(p)
+}
+
+func (t *fakeTransport) Close() error {
+	t.closeOnce.Do(func() {
+		close(t.closed)
+		t.in.CloseWithError(net.ErrClosed)
+	})
+	return nil
+}
+
+func (t *fakeTransport) RemoteAddr() net.Addr { return fakeAddr("fake:1") }
+
+// Send delivers input to the client as if the user had typed it
+func (t *fakeTransport) Send(input string) error {
+	_, err := t.inWriter.Write([]byte(input))
+	return err
+}
+
+// Hangup ends the client's input, as a closed connection would
+func (t *fakeTransport) Hangup() {
+	t.inWriter.Close()
+}
+
+// Output returns everything written to the client so far
+func (t *fakeTransport) Output() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.out.String()
+}
+
+// fakeClient is a Client that lives in memory. It receives JSON, so what it
+// was sent can be inspected as messages.
+type fakeClient struct {
+	*Client
+	transport *fakeTransport
+}
+
+// newFakeClient creates a client with the given nickname, ready to be added
+// to a room with Room.Join or RoomManager.JoinLobby. It is stopped when the
+// test ends.
+func newFakeClient(t testing.TB, manager *RoomManager, nickname string, cfg ClientConfig) *fakeClient {
+	t.Helper()
+	transport := newFakeTransport()
+	cfg.JSON = true
+	if cfg.Logger == nil {
+		cfg.Logger = discardLogger
+	}
+	client := newClient(transport, manager, cfg)
+	client.setNickname(nickname)
+	client.JoinedAt = time.Now()
+	client.lastActive = client.JoinedAt
+	go client.writeLoop()
+	t.Cleanup(func() {
+		client.stopWriter()
+		transport.Close()
+	})
+	return &fakeClient{Client: client, transport: transport}
+}
+
+// ReceivedMessages returns the messages written to the client so far
+func (f *fakeClient) ReceivedMessages() []Message {
+	var messages []Message
+	scanner := bufio.NewScanner(bytes.NewReader([]byte(f.transport.Output())))
+	for scanner.Scan() {
+		var msg Message
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err == nil {
+			messages = append(messages, msg)
+		}
+	}
+	return messages
+}
+
+// waitForMessage waits until the client has received a message matching
+// match, failing the test if none arrives in time
+func (f *fakeClient) waitForMessage(t testing.TB, what string, match func(Message) bool) Message {
+	t.Helper()
+	deadline := time.Now().Add(testTimeout)
+	for {
+		for _, msg := range f.ReceivedMessages() {
+			if match(msg) {
+				return msg
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s never received %s; got %+v", f.Nickname(), what, f.ReceivedMessages())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// waitForContent waits until the client has received a message with the
+// given content
+func (f *fakeClient) waitForContent(t testing.TB, content string) Message {
+	t.Helper()
+	return f.waitForMessage(t, "\""+content+"\"", func(msg Message) bool {
+		return msg.Content == content
+	})
+}
+
+// newTestRoom creates a room that is stopped when the test ends
+func newTestRoom(t testing.TB, maxUsers int) *Room {
+	t.Helper()
+	room := NewRoom("test", maxUsers, 10, false, PresenceTemplates{}, discardLogger)
+	t.Cleanup(func() { room.Stop() })
+	return room
+}
+
+// newTestManager creates a room manager whose rooms are stopped when the
+// test ends
+func newTestManager(t testing.TB, maxUsers int) *RoomManager {
+	t.Helper()
+	manager := NewRoomManager("Lobby", maxUsers, 10, false, PresenceTemplates{}, discardLogger)
+	t.Cleanup(func() { manager.Stop() })
+	return manager
+}
@@ -0,0 +1,53 @@
+package chat
+
+import "sync"
+
+// History is a fixed-size ring buffer of recent room messages
+type History struct {
+	messages []Message
+	next     int  // Index the next message will be written to
+	full     bool // Whether the buffer has wrapped around
+	mu       sync.Mutex
+}
+
+// NewHistory creates a history buffer holding up to size messages.
+// A size of zero disables history.
+func NewHistory(size int) *History {
+	if size < 0 {
+		size = 0
+	}
+	return &History{
+		messages: make([]Message, size),
+	}
+}
+
+// Add records a message, evicting the oldest one when the buffer is full
+func (h *History) Add(msg Message) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.messages) == 0 {
+		return
+	}
+
+	h.messages[h.next] = msg
+	h.next = (h.next + 1) % len(h.messages)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// Messages returns the recorded messages, oldest first
+func (h *History) Messages() []Message {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.full {
+		return append([]Message(nil), h.messages[:h.next]...)
+	}
+
+	result := make([]Message, 0, len(h.messages))
+	result = append(result, h.messages[h.next:]...)
+	result = append(result, h.messages[:h.next]...)
+	return result
+}
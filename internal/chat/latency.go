@@ -0,0 +1,57 @@
+package chat
+
+import (
+	"time"
+
+	"github.com/bscott/ts-chat/internal/metrics"
+)
+
+const (
+	// slowSendLatency is the average send latency above which a client is
+	// flagged as slow. It stops being slow once the average drops below half.
+	slowSendLatency = time.Second
+
+	// latencyWeight is how much each new sample moves the average
+	latencyWeight = 0.1
+)
+
+// SendLatency returns the client's moving average and worst time from a
+// message being queued for it to being written to its connection
+func (c *Client) SendLatency() (avg, worst time.Duration) {
+	return time.Duration(c.latencyAvg.Load()), time.Duration(c.latencyMax.Load())
+}
+
+// recordLatency adds a message's send latency to the client's figures,
+// flagging the client when it falls behind. Only the writer goroutine calls
+// it, so the updates don't race with each other.
+func (c *Client) recordLatency(d time.Duration) {
+	avg := time.Duration(c.latencyAvg.Load())
+	if avg == 0 {
+		avg = d
+	} else {
+		avg += time.Duration(latencyWeight * float64(d-avg))
+	}
+	c.latencyAvg.Store(int64(avg))
+	if int64(d) > c.latencyMax.Load() {
+		c.latencyMax.Store(int64(d))
+	}
+
+	switch {
+	case !c.slow && avg > slowSendLatency:
+		c.slow = true
+		metrics.SlowClients.Inc()
+		c.logger.Warn("Client is slow to receive messages", "send_latency", avg.Round(time.Millisecond))
+	case c.slow && avg < slowSendLatency/2:
+		c.slow = false
+		metrics.SlowClients.Dec()
+		c.logger.Info("Client has caught up", "send_latency", avg.Round(time.Millisecond))
+	}
+}
+
+// forgetLatency stops counting the client as slow once its writer stops
+func (c *Client) forgetLatency() {
+	if c.slow {
+		c.slow = false
+		metrics.SlowClients.Dec()
+	}
+}
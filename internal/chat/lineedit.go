@@ -0,0 +1,305 @@
+package chat
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxInputHistory caps the number of sent lines a client can recall
+const maxInputHistory = 50
+
+// Editing keys understood by lineEditor
+const (
+	keyBackspace = 0x08 // Ctrl-H
+	keyKillLine  = 0x15 // Ctrl-U
+	keyEscape    = 0x1b
+	keyDelete    = 0x7f // Sent by most terminals for the backspace key
+)
+
+// editState is the parser state of a lineEditor
+type editState int
+
+const (
+	editData   editState = iota // Ordinary input
+	editEscape                  // Seen ESC
+	editCSI                     // Inside an ESC [ or ESC O sequence
+)
+
+// lineEditor assembles lines from clients that send every keystroke as it
+// is typed, handling backspace and Ctrl-U, recalling earlier lines with the
+// up and down arrows, completing words with Tab, and echoing the result when the server has taken over
+// echoing from the client's terminal. Completed lines are returned with a
+// trailing "\n", so the line reader on top of it works the same for clients
+// in line mode, which simply send whole lines.
+type lineEditor struct {
+	r        io.Reader
+	echo     func([]byte) error // Writes echoed input back to the client
+	echoing  func() bool        // Reports whether the server should echo input
+	state    editState
+	afterCR  bool                                   // The last key was CR, so a following LF is part of it
+	line     []byte                                 // The line being edited
+	history  []string                               // Recently sent lines, oldest first
+	recall   int                                    // Index into history of the recalled line, len(history) when editing a new line
+	draft    []byte                                 // The new line being edited before browsing history
+	complete func(word string, first bool) []string // Candidates for completing a word, first if it starts the line (nil disables completion)
+	list     func(candidates []string) string       // Renders the candidates of an ambiguous completion
+	matches  []string                               // Candidates repeated Tabs cycle through
+	match    int                                    // Index into matches of the candidate shown
+	wordAt   int                                    // Offset in line of the word being completed
+	partial  []byte                                 // Leading bytes of a multibyte character still arriving
+	pending  []byte                                 // Completed lines not yet returned by Read
+	err      error                                  // Read error to return once pending is drained
+	buf      []byte
+}
+
+// newLineEditor wraps r, echoing input with echo while echoing reports true
+func newLineEditor(r io.Reader, echo func([]byte) error, echoing func() bool) *lineEditor {
+	return &lineEditor{
+		r:       r,
+		echo:    echo,
+		echoing: echoing,
+		buf:     make([]byte, 1024),
+	}
+}
+
+// Read returns completed lines, reading and editing input until one is ready
+func (e *lineEditor) Read(p []byte) (int, error) {
+	for len(e.pending) == 0 && e.err == nil {
+		n, err := e.r.Read(e.buf)
+
+		var out []byte
+		for _, b := range e.buf[:n] {
+			out = e.consume(b, out)
+		}
+		if len(out) > 0 && e.echoing() {
+			// A failed echo will surface as an error on the next write
+			_ = e.echo(out)
+		}
+
+		if err != nil {
+			// Hand over what was typed so far, like a line reader would
+			if len(e.partial) > 0 {
+				e.line = append(e.line, string(utf8.RuneError)...)
+				e.partial = nil
+			}
+			e.pending = append(e.pending, e.line...)
+			e.line = nil
+			e.err = err
+		}
+	}
+
+	if len(e.pending) == 0 {
+		// Like bufio.Reader, an error is only returned once, so reading can
+		// go on after a deadline interrupted it
+		err := e.err
+		e.err = nil
+		return 0, err
+	}
+	n := copy(p, e.pending)
+	e.pending = e.pending[n:]
+	return n, nil
+}
+
+// consume applies one byte of input to the line, appending whatever should
+// be echoed to out
+func (e *lineEditor) consume(b byte, out []byte) []byte {
+	if b != '\t' {
+		e.matches = nil
+	}
+
+	// A character cut short by another key is replaced, or dropped if that
+	// key erases it
+	if len(e.partial) > 0 && utf8.RuneStart(b) {
+		e.partial = e.partial[:0]
+		if b == keyBackspace || b == keyDelete {
+			return out
+		}
+		out = e.insert(string(utf8.RuneError), out)
+	}
+
+	switch e.state {
+	case editEscape:
+		// Arrow keys are sent as ESC [ A or ESC O A; other sequences are ignored
+		if b == '[' || b == 'O' {
+			e.state = editCSI
+		} else {
+			e.state = editData
+		}
+		return out
+	case editCSI:
+		if b < 0x40 || b > 0x7e {
+			return out
+		}
+		e.state = editData
+		switch b {
+		case 'A':
+			return e.recallLine(e.recall-1, out)
+		case 'B':
+			return e.recallLine(e.recall+1, out)
+		}
+		return out
+	}
+
+	afterCR := e.afterCR
+	e.afterCR = false
+
+	switch {
+	case b == '\n' && afterCR:
+		// Second half of a CR LF line ending
+	case b == '\r' || b == '\n':
+		e.afterCR = b == '\r'
+		e.remember(string(e.line))
+		e.pending = append(e.pending, e.line...)
+		e.pending = append(e.pending, '\n')
+		e.line = e.line[:0]
+		out = append(out, '\r', '\n')
+	case b == keyBackspace || b == keyDelete:
+		out = e.eraseRune(out)
+	case b == keyKillLine:
+		for len(e.line) > 0 {
+			out = e.eraseRune(out)
+		}
+	case b == keyEscape:
+		e.state = editEscape
+	case b == '\t' && e.complete != nil && e.echoing():
+		out = e.completeWord(out)
+	case b == '\t':
+		e.line = append(e.line, ' ')
+		out = append(out, ' ')
+	case b < 0x20:
+		// Other control keys are ignored
+	case b < utf8.RuneSelf:
+		out = e.insert(string(rune(b)), out)
+	default:
+		out = e.addMultibyte(b, out)
+	}
+	return out
+}
+
+// addMultibyte adds a byte of a multibyte character. The character is held
+// back until all its bytes have arrived, so the line is always valid UTF-8
+// and the client's terminal is never echoed half a character. Bytes that
+// can't be part of a valid character become U+FFFD.
+func (e *lineEditor) addMultibyte(b byte, out []byte) []byte {
+	e.partial = append(e.partial, b)
+	for len(e.partial) > 0 && utf8.FullRune(e.partial) {
+		r, size := utf8.DecodeRune(e.partial)
+		if r == utf8.RuneError {
+			out = e.insert(string(utf8.RuneError), out)
+		} else {
+			out = e.insert(string(e.partial[:size]), out)
+		}
+		e.partial = e.partial[size:]
+	}
+	return out
+}
+
+// insert adds text to the end of the line and echoes it
+func (e *lineEditor) insert(text string, out []byte) []byte {
+	e.line = append(e.line, text...)
+	return append(out, text...)
+}
+
+// remember adds a sent line to the history, skipping blank lines and
+// repeats of the previous line, and stops any browsing
+func (e *lineEditor) remember(line string) {
+	if strings.TrimSpace(line) != "" && (len(e.history) == 0 || e.history[len(e.history)-1] != line) {
+		if len(e.history) == maxInputHistory {
+			e.history = e.history[1:]
+		}
+		e.history = append(e.history, line)
+	}
+	e.recall = len(e.history)
+	e.draft = nil
+}
+
+// recallLine replaces the line with history entry i, or with the draft when
+// i is just past the newest entry, and redraws it on the client's screen
+func (e *lineEditor) recallLine(i int, out []byte) []byte {
+	if i < 0 || i > len(e.history) || i == e.recall {
+		return out
+	}
+	if e.recall == len(e.history) {
+		e.draft = append(e.draft[:0], e.line...)
+	}
+
+	e.recall = i
+	if i == len(e.history) {
+		e.line = append(e.line[:0], e.draft...)
+	} else {
+		e.line = append(e.line[:0], e.history[i]...)
+	}
+
+	return e.redraw(out)
+}
+
+// completeWord completes the word at the end of the line. A single
+// candidate is filled in; with several, their common prefix is filled in
+// and they are listed, and further Tabs cycle through them.
+func (e *lineEditor) completeWord(out []byte) []byte {
+	if e.matches != nil {
+		e.match = (e.match + 1) % len(e.matches)
+		return e.replaceWord(e.matches[e.match], out)
+	}
+
+	e.wordAt = bytes.LastIndexByte(e.line, ' ') + 1
+	word := string(e.line[e.wordAt:])
+	matches := e.complete(word, e.wordAt == 0)
+	switch len(matches) {
+	case 0:
+		// Ring the bell
+		return append(out, '\a')
+	case 1:
+		return e.replaceWord(matches[0]+" ", out)
+	}
+
+	e.matches = matches
+	e.match = -1
+	out = append(out, "\r\n"...)
+	out = append(out, e.list(matches)...)
+	out = append(out, "\r\n"...)
+
+	// Matching ignores case, so the candidates may share less than was typed
+	if prefix := commonPrefix(matches); len(prefix) > len(word) {
+		word = prefix
+	}
+	return e.replaceWord(word, out)
+}
+
+// replaceWord replaces the word being completed and redraws the line
+func (e *lineEditor) replaceWord(word string, out []byte) []byte {
+	e.line = append(e.line[:e.wordAt], word...)
+	return e.redraw(out)
+}
+
+// redraw returns to the start of the client's line, clears it and draws the
+// line being edited
+func (e *lineEditor) redraw(out []byte) []byte {
+	out = append(out, "\r\x1b[K"...)
+	return append(out, e.line...)
+}
+
+// commonPrefix returns the longest prefix shared by all of words
+func commonPrefix(words []string) string {
+	prefix := words[0]
+	for _, word := range words[1:] {
+		for !strings.HasPrefix(word, prefix) {
+			_, size := utf8.DecodeLastRuneInString(prefix)
+			prefix = prefix[:len(prefix)-size]
+		}
+	}
+	return prefix
+}
+
+// eraseRune removes the last character of the line and rubs it out on the
+// client's screen
+func (e *lineEditor) eraseRune(out []byte) []byte {
+	if len(e.line) == 0 {
+		return out
+	}
+	_, size := utf8.DecodeLastRune(e.line)
+	e.line = e.line[:len(e.line)-size]
+	return append(out, '\b', ' ', '\b')
+}
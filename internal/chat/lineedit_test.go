@@ -0,0 +1,63 @@
+package chat
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"unicode/utf8"
+)
+
+// readEditedLine feeds input to a lineEditor one byte at a time and returns
+// the first line it assembles along with every chunk it echoed
+func readEditedLine(t *testing.T, input string) (string, []string) {
+	t.Helper()
+	var echoed []string
+	editor := newLineEditor(iotest.OneByteReader(strings.NewReader(input)), func(b []byte) error {
+		echoed = append(echoed, string(b))
+		return nil
+	}, func() bool { return true })
+
+	line, err := bufio.NewReader(editor).ReadString('\n')
+	if err != nil {
+		t.Fatalf("reading %q: %v", input, err)
+	}
+	return line, echoed
+}
+
+func TestLineEditorMultibyteOneByteAtATime(t *testing.T) {
+	line, echoed := readEditedLine(t, "hé世😀\r\n")
+	if line != "hé世😀\n" {
+		t.Errorf("line = %q, want %q", line, "hé世😀\n")
+	}
+	// Characters are only echoed once all their bytes have arrived
+	for _, chunk := range echoed {
+		if !utf8.ValidString(chunk) {
+			t.Errorf("echoed part of a character: %q", chunk)
+		}
+	}
+	if got := strings.Join(echoed, ""); got != "hé世😀\r\n" {
+		t.Errorf("echoed %q, want %q", got, "hé世😀\r\n")
+	}
+}
+
+func TestLineEditorInvalidUTF8(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"cut short by another character", "a\xe4\xb8b\r\n", "a\uFFFDb\n"},
+		{"cut short by the end of the line", "a\xe4\xb8\r\n", "a\uFFFD\n"},
+		{"stray continuation byte", "a\x80b\r\n", "a\uFFFDb\n"},
+		{"erased before it was finished", "a\xe4\xb8\x7fb\r\n", "ab\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line, _ := readEditedLine(t, tt.input)
+			if line != tt.want {
+				t.Errorf("line = %q, want %q", line, tt.want)
+			}
+		})
+	}
+}
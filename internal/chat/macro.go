@@ -0,0 +1,72 @@
+package chat
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// builtinMacros are the text macros every server has. Configured macros
+// with the same name replace them.
+var builtinMacros = map[string]string{
+	"shrug":     `¯\_(ツ)_/¯`,
+	"tableflip": "(╯°□°)╯︵ ┻━┻",
+	"unflip":    "┬─┬ノ( º _ ºノ)",
+}
+
+// ValidateMacro checks that a configured macro can be used: its name must
+// be a single word that isn't already a command, and its text not empty
+func ValidateMacro(name, text string) error {
+	if name == "" || strings.IndexFunc(name, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '_')
+	}) >= 0 {
+		return fmt.Errorf("macro name %q must be lowercase letters, digits, - and _", name)
+	}
+	if _, ok := lookupCommand("/" + name); ok {
+		return fmt.Errorf("macro name %q is already a command", name)
+	}
+	if strings.TrimSpace(text) == "" {
+		return fmt.Errorf("macro %q has no text", name)
+	}
+	return nil
+}
+
+// macro returns the text of the macro invoked as /name
+func (c *Client) macro(name string) (string, bool) {
+	name = strings.TrimPrefix(name, "/")
+	if text, ok := c.config.Macros[name]; ok {
+		return text, true
+	}
+	text, ok := builtinMacros[name]
+	return text, ok
+}
+
+// macroNames returns every macro as a command, sorted
+func (c *Client) macroNames() []string {
+	names := make([]string, 0, len(builtinMacros)+len(c.config.Macros))
+	for name := range builtinMacros {
+		names = append(names, "/"+name)
+	}
+	for name := range c.config.Macros {
+		if _, ok := builtinMacros[name]; !ok {
+			names = append(names, "/"+name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sendMacro sends text, the macro's output, to the room after whatever the
+// user typed with it
+func (c *Client) sendMacro(text, message string) error {
+	if message = strings.TrimSpace(message); message != "" {
+		text = message + " " + text
+	}
+
+	// The macro text counts towards the limit like anything typed
+	if err := c.validateMessageLength(text); err != nil {
+		return err
+	}
+	c.say(text)
+	return nil
+}
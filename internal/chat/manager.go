@@ -0,0 +1,389 @@
+package chat
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bscott/ts-chat/internal/i18n"
+)
+
+// ErrAlreadyInRoom is returned by Move when the client is already in the
+// room it asked for
+var ErrAlreadyInRoom = errors.New("already in the room")
+
+// RoomManager manages the set of named chat rooms. Rooms are created on
+// demand when a user joins them and destroyed once the last user leaves,
+// except for the lobby which always exists.
+type RoomManager struct {
+	lobby         *Room
+	rooms         map[string]*Room // Keyed by lowercased room name
+	maxUsers      int
+	historySize   int
+	showOccupancy bool
+	presence      PresenceTemplates
+	handlers      []MessageHandler // Registered on every room, including ones created later
+	messageLog    MessageLogger    // Set on every room, including ones created later
+	logSystem     bool
+	numbered      bool          // Number messages in every room, including ones created later
+	catalog       *i18n.Catalog // Language of every room's notices, including rooms created later
+	logger        *slog.Logger
+	mu            sync.Mutex
+	reclaimMu     sync.Mutex // Serializes ReclaimNickname, which probes without holding mu
+}
+
+// NewRoomManager creates a room manager with a default lobby
+func NewRoomManager(lobbyName string, maxUsers, historySize int, showOccupancy bool, presence PresenceTemplates, logger *slog.Logger) *RoomManager {
+	lobby := NewRoom(lobbyName, maxUsers, historySize, showOccupancy, presence, logger)
+	return &RoomManager{
+		lobby:         lobby,
+		rooms:         map[string]*Room{roomKey(lobbyName): lobby},
+		maxUsers:      maxUsers,
+		historySize:   historySize,
+		showOccupancy: showOccupancy,
+		presence:      presence,
+		logger:        logger,
+	}
+}
+
+// roomKey returns the map key for a room name
+func roomKey(name string) string {
+	return strings.ToLower(name)
+}
+
+// Lobby returns the default room new clients are placed in
+func (m *RoomManager) Lobby() *Room {
+	return m.lobby
+}
+
+// Rooms returns all rooms sorted by name
+func (m *RoomManager) Rooms() []*Room {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rooms := make([]*Room, 0, len(m.rooms))
+	for _, room := range m.rooms {
+		rooms = append(rooms, room)
+	}
+	sort.Slice(rooms, func(i, j int) bool {
+		return roomKey(rooms[i].Name) < roomKey(rooms[j].Name)
+	})
+
+	return rooms
+}
+
+// FindClient looks up a client by nickname across all rooms, ignoring case
+func (m *RoomManager) FindClient(nickname string) (*Client, bool) {
+	for _, room := range m.Rooms() {
+		if client, ok := room.GetClient(nickname); ok {
+			return client, true
+		}
+	}
+	return nil, false
+}
+
+// Operators returns the operators connected to any room
+func (m *RoomManager) Operators() []*Client {
+	var operators []*Client
+	for _, room := range m.Rooms() {
+		for _, client := range room.members() {
+			if client.IsOperator() {
+				operators = append(operators, client)
+			}
+		}
+	}
+	return operators
+}
+
+// Kick disconnects a user in any room, telling them and their room that
+// kickedBy kicked them and why
+func (m *RoomManager) Kick(nickname, kickedBy, reason string) error {
+	target, ok := m.FindClient(nickname)
+	if !ok {
+		return fmt.Errorf("no such user: %s", nickname)
+	}
+	target.kick(kickedBy, reason)
+	return nil
+}
+
+// UserCount returns the number of users across all rooms
+func (m *RoomManager) UserCount() int {
+	count := 0
+	for _, room := range m.Rooms() {
+		count += room.UserCount()
+	}
+	return count
+}
+
+// IsNicknameAvailable checks if a nickname is unused in every room.
+// Nicknames that differ only in case count as the same.
+func (m *RoomManager) IsNicknameAvailable(nickname string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, taken := m.findClientLocked(nickname)
+	return !taken
+}
+
+// reclaimTimeout bounds how long ReclaimNickname probes a connection and
+// then waits for it to leave
+const reclaimTimeout = 2 * time.Second
+
+// ReclaimNickname frees a nickname held by a connection that appears dead,
+// so a user whose network dropped can reconnect under their name before the
+// old connection times out. Only a connection from the same source is
+// probed, so nobody can push a live user off their nickname. It reports
+// whether the nickname is now free.
+func (m *RoomManager) ReclaimNickname(nickname, source string) bool {
+	m.reclaimMu.Lock()
+	defer m.reclaimMu.Unlock()
+
+	old, ok := m.FindClient(nickname)
+	if !ok {
+		return m.IsNicknameAvailable(nickname)
+	}
+	if source == "" || old.Source() != source || !old.unresponsive(reclaimTimeout) {
+		return false
+	}
+
+	m.logger.Info("Disconnecting unresponsive client to reclaim its nickname", "nickname", old.Nickname(), "source", source)
+	old.ended.Store(true)
+	old.conn.Close()
+	select {
+	case <-old.left:
+	case <-time.After(reclaimTimeout):
+	}
+	return m.IsNicknameAvailable(nickname)
+}
+
+// maxNicknameSuffix bounds the numbers SuggestNickname tries
+const maxNicknameSuffix = 1000
+
+// SuggestNickname returns the first of nickname2, nickname3, ... that is
+// valid under policy and unused, shortening the nickname when the suffix
+// would make it too long. It reports false if every candidate is taken.
+func (m *RoomManager) SuggestNickname(nickname string, policy NicknamePolicy) (string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	base := []rune(nickname)
+	for n := 2; n < maxNicknameSuffix; n++ {
+		suffix := strconv.Itoa(n)
+		stem := base
+		if policy.MaxLength > 0 && len(stem)+len(suffix) > policy.MaxLength {
+			stem = stem[:max(policy.MaxLength-len(suffix), 0)]
+		}
+
+		candidate := string(stem) + suffix
+		if ValidateNickname(candidate, policy) != nil {
+			continue
+		}
+		if _, taken := m.findClientLocked(candidate); !taken {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+// findClientLocked looks up a client across all rooms. m.mu must be held.
+func (m *RoomManager) findClientLocked(nickname string) (*Client, bool) {
+	for _, room := range m.rooms {
+		if client, ok := room.GetClient(nickname); ok {
+			return client, true
+		}
+	}
+	return nil, false
+}
+
+// Rename changes a client's nickname and announces it in the client's room,
+// returning ErrNicknameTaken if someone else already uses the nickname
+func (m *RoomManager) Rename(c *Client, nickname string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if other, taken := m.findClientLocked(nickname); taken && other != c {
+		return fmt.Errorf("%w: %s", ErrNicknameTaken, nickname)
+	}
+
+	if room := c.Room(); room != nil {
+		room.rename(c, nickname)
+	} else {
+		c.setNickname(nickname)
+	}
+	return nil
+}
+
+// RegisterHandler adds a message handler to every room
+func (m *RoomManager) RegisterHandler(h MessageHandler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.handlers = append(m.handlers, h)
+	for _, room := range m.rooms {
+		room.RegisterHandler(h)
+	}
+}
+
+// SetMessageLogger makes every room pass its messages to l, see
+// Room.SetMessageLogger
+func (m *RoomManager) SetMessageLogger(l MessageLogger, includeSystem bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.messageLog = l
+	m.logSystem = includeSystem
+	for _, room := range m.rooms {
+		room.SetMessageLogger(l, includeSystem)
+	}
+}
+
+// SetSequenceNumbers turns numbering of broadcasts on or off in every room,
+// see Room.SetSequenceNumbers
+func (m *RoomManager) SetSequenceNumbers(enabled bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.numbered = enabled
+	for _, room := range m.rooms {
+		room.SetSequenceNumbers(enabled)
+	}
+}
+
+// SetCatalog sets the language of every room's notices, see Room.SetCatalog
+func (m *RoomManager) SetCatalog(catalog *i18n.Catalog) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.catalog = catalog
+	for _, room := range m.rooms {
+		room.SetCatalog(catalog)
+	}
+}
+
+// Broadcast sends a message to every room
+func (m *RoomManager) Broadcast(msg Message) {
+	for _, room := range m.Rooms() {
+		room.Broadcast(msg)
+	}
+}
+
+// JoinLobby adds a newly connected client to the lobby
+func (m *RoomManager) JoinLobby(c *Client) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.lobby.Join(c); err != nil {
+		return err
+	}
+	c.setRoom(m.lobby)
+	return nil
+}
+
+// QueueForLobby joins a client to the lobby, or if it is full puts the
+// client in the lobby's waiting queue of up to maxQueue clients. It returns
+// a nil entry if the client joined straight away.
+func (m *RoomManager) QueueForLobby(c *Client, maxQueue int, notify func(position int)) (*queueEntry, error) {
+	for {
+		err := m.JoinLobby(c)
+		if !errors.Is(err, ErrRoomFull) {
+			return nil, err
+		}
+
+		entry, err := m.lobby.enqueue(c, maxQueue, notify)
+		if entry != nil || err != nil {
+			return entry, err
+		}
+		// A slot freed up in the meantime, so try joining again
+	}
+}
+
+// Move transfers a client from its current room to the named room,
+// creating the room if it doesn't exist yet
+func (m *RoomManager) Move(c *Client, name string) (*Room, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	from := c.Room()
+	to, exists := m.rooms[roomKey(name)]
+	if !exists {
+		m.logger.Info("Creating room", "room", name)
+		to = NewRoom(name, m.maxUsers, m.historySize, m.showOccupancy, m.presence, m.logger)
+		for _, h := range m.handlers {
+			to.RegisterHandler(h)
+		}
+		if m.messageLog != nil {
+			to.SetMessageLogger(m.messageLog, m.logSystem)
+		}
+		to.SetSequenceNumbers(m.numbered)
+		to.SetCatalog(m.catalog)
+		m.rooms[roomKey(name)] = to
+	}
+
+	if to == from {
+		return nil, ErrAlreadyInRoom
+	}
+
+	if err := to.Join(c); err != nil {
+		m.removeIfEmptyLocked(to)
+		return nil, fmt.Errorf("cannot join %s: %w", to.Name, err)
+	}
+
+	if from != nil {
+		from.Leave(c)
+		m.removeIfEmptyLocked(from)
+	}
+	c.setRoom(to)
+
+	return to, nil
+}
+
+// Leave removes a client from its current room
+func (m *RoomManager) Leave(c *Client) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	room := c.Room()
+	if room == nil {
+		return
+	}
+
+	if err := room.Leave(c); err != nil {
+		// The room stopped first, so its user list is gone already
+		m.logger.Debug("Client left a stopped room", "room", room.Name, "nickname", c.Nickname())
+	}
+	c.setRoom(nil)
+	m.removeIfEmptyLocked(room)
+}
+
+// removeIfEmptyLocked destroys a room once its last user has left.
+// The lobby is never removed. m.mu must be held.
+func (m *RoomManager) removeIfEmptyLocked(room *Room) {
+	if room == m.lobby || room.UserCount() > 0 {
+		return
+	}
+
+	delete(m.rooms, roomKey(room.Name))
+	if err := room.Stop(); err != nil {
+		m.logger.Error("Error stopping room", "room", room.Name, "error", err)
+	}
+}
+
+// Stop shuts down every room
+func (m *RoomManager) Stop() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for key, room := range m.rooms {
+		if err := room.Stop(); err != nil {
+			m.logger.Error("Error stopping room", "room", room.Name, "error", err)
+		}
+		delete(m.rooms, key)
+	}
+
+	return nil
+}
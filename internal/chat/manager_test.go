@@ -0,0 +1,144 @@
+package chat
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+// telnetPeer is how the other end of a test connection behaves
+type telnetPeer int
+
+const (
+	peerLive     telnetPeer = iota // A Telnet client that answers everything
+	peerHalfOpen                   // A Telnet client whose network dropped: writes still succeed, but nothing comes back
+	peerRaw                        // A client such as netcat that doesn't speak Telnet
+)
+
+// connectTelnetPeer connects a client called nickname from source to
+// manager over a pipe and runs it
+func connectTelnetPeer(t *testing.T, manager *RoomManager, nickname, source string, peer telnetPeer) *Client {
+	t.Helper()
+	server, user := net.Pipe()
+	t.Cleanup(func() { user.Close() })
+
+	go func() {
+		askedSize := []byte{telnetIAC, telnetDO, telnetOptNAWS}
+		probe := []byte{telnetIAC, telnetDO, telnetOptTimingMark}
+		buf := make([]byte, 4096)
+		for {
+			n, err := user.Read(buf)
+			if err != nil {
+				return
+			}
+			if peer != peerRaw && bytes.Contains(buf[:n], askedSize) {
+				go user.Write([]byte{telnetIAC, telnetWONT, telnetOptNAWS})
+			}
+			if peer == peerLive && bytes.Contains(buf[:n], probe) {
+				go user.Write([]byte{telnetIAC, telnetWILL, telnetOptTimingMark})
+			}
+		}
+	}()
+	go user.Write([]byte(nickname + "\r\n"))
+
+	client, err := NewClient(context.Background(), server, manager, ClientConfig{Logger: discardLogger, NoBanner: true, Source: source})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		client.Handle(context.Background())
+	}()
+	t.Cleanup(func() {
+		server.Close()
+		<-done
+	})
+	return client
+}
+
+func TestReclaimNicknameTelnet(t *testing.T) {
+	manager := newTestManager(t, 10)
+	connectTelnetPeer(t, manager, "alice", "10.0.0.1", peerLive)
+	ghost := connectTelnetPeer(t, manager, "bob", "10.0.0.2", peerHalfOpen)
+	connectTelnetPeer(t, manager, "carol", "10.0.0.3", peerRaw)
+
+	// A live client answers the probe and keeps its nickname
+	if manager.ReclaimNickname("alice", "10.0.0.1") {
+		t.Error("reclaimed the nickname of a client that answered")
+	}
+	if _, ok := manager.FindClient("alice"); !ok {
+		t.Error("live client was disconnected")
+	}
+
+	// A client that can't be probed is assumed to be there
+	if manager.ReclaimNickname("carol", "10.0.0.3") {
+		t.Error("reclaimed the nickname of a client that doesn't speak Telnet")
+	}
+
+	// Someone else can't take a nickname however dead its holder is
+	if manager.ReclaimNickname("bob", "10.0.0.4") {
+		t.Error("reclaimed a nickname for a different source")
+	}
+
+	// Writes to a half-open connection succeed, but nothing comes back
+	if !manager.ReclaimNickname("bob", "10.0.0.2") {
+		t.Error("could not reclaim the nickname of a client that never answered")
+	}
+	select {
+	case <-ghost.left:
+	case <-time.After(testTimeout):
+		t.Error("unresponsive client was not disconnected")
+	}
+}
+
+// pingTransportFunc is a fakeTransport whose Ping calls ping
+type pingTransportFunc struct {
+	*fakeTransport
+	ping func(ctx context.Context) error
+}
+
+func (p pingTransportFunc) Ping(ctx context.Context) error { return p.ping(ctx) }
+
+func TestReclaimNicknameWebSocket(t *testing.T) {
+	manager := newTestManager(t, 10)
+	connect := func(nickname string, ping func(ctx context.Context) error) *Client {
+		transport := pingTransportFunc{newFakeTransport(), ping}
+		client := newClient(transport, manager, ClientConfig{JSON: true, Logger: discardLogger, Source: "10.0.0.1"})
+		client.setNickname(nickname)
+		go client.writeLoop()
+		if err := manager.JoinLobby(client); err != nil {
+			t.Fatalf("%s joining the lobby: %v", nickname, err)
+		}
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			client.Handle(context.Background())
+		}()
+		t.Cleanup(func() {
+			transport.Close()
+			<-done
+		})
+		return client
+	}
+
+	connect("alice", func(ctx context.Context) error { return nil })
+	ghost := connect("bob", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	if manager.ReclaimNickname("alice", "10.0.0.1") {
+		t.Error("reclaimed the nickname of a client that answered the ping")
+	}
+	if !manager.ReclaimNickname("bob", "10.0.0.1") {
+		t.Error("could not reclaim the nickname of a client that never answered the ping")
+	}
+	select {
+	case <-ghost.left:
+	case <-time.After(testTimeout):
+		t.Error("unresponsive client was not disconnected")
+	}
+}
@@ -0,0 +1,10 @@
+package chat
+
+// MessageLogger records the messages broadcast in rooms, e.g. to keep an
+// audit log. It is implemented by the server so the storage can be swapped
+// without touching the rooms.
+type MessageLogger interface {
+	// LogMessage is called from the room loop with each message broadcast
+	// in room, so it must not block
+	LogMessage(room string, msg Message)
+}
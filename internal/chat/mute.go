@@ -0,0 +1,76 @@
+package chat
+
+import (
+	"errors"
+	"time"
+
+	"github.com/bscott/ts-chat/internal/ui"
+)
+
+// defaultMuteDuration is how long /mute silences a user when no duration
+// is given
+const defaultMuteDuration = 10 * time.Minute
+
+// Muted reports whether the client is muted, along with when the mute
+// ends. A mute that has run out is cleared.
+func (c *Client) Muted() (time.Time, bool) {
+	c.muteMu.Lock()
+	defer c.muteMu.Unlock()
+
+	if !c.mutedUntil.IsZero() && !time.Now().Before(c.mutedUntil) {
+		c.mutedUntil = time.Time{}
+	}
+	return c.mutedUntil, !c.mutedUntil.IsZero()
+}
+
+// mute stops the client's messages reaching its room until the given time
+func (c *Client) mute(until time.Time) {
+	c.muteMu.Lock()
+	defer c.muteMu.Unlock()
+
+	c.mutedUntil = until
+}
+
+// unmute lifts the client's mute, reporting whether it was muted
+func (c *Client) unmute() bool {
+	c.muteMu.Lock()
+	defer c.muteMu.Unlock()
+
+	muted := time.Now().Before(c.mutedUntil)
+	c.mutedUntil = time.Time{}
+	return muted
+}
+
+// muteUser silences a user anywhere on the server for a duration. They stay
+// connected and can still read.
+func (c *Client) muteUser(nickname string, duration time.Duration) error {
+	if sameNickname(nickname, c.Nickname()) {
+		return errors.New(c.t("mute.self"))
+	}
+	target, ok := c.manager.FindClient(nickname)
+	if !ok {
+		return errors.New(c.t("user.not_found", nickname))
+	}
+
+	target.mute(time.Now().Add(duration))
+	c.logger.Info("User muted", "target", target.Nickname(), "duration", duration)
+	target.sendSystemMessage(target.t("mute.notice", c.Nickname(), ui.FormatUptime(duration)))
+	c.sendSystemMessage(c.t("mute.done", target.Nickname(), ui.FormatUptime(duration)))
+	return nil
+}
+
+// unmuteUser lifts a user's mute before it runs out
+func (c *Client) unmuteUser(nickname string) error {
+	target, ok := c.manager.FindClient(nickname)
+	if !ok {
+		return errors.New(c.t("user.not_found", nickname))
+	}
+	if !target.unmute() {
+		return errors.New(c.t("unmute.not_muted", target.Nickname()))
+	}
+
+	c.logger.Info("User unmuted", "target", target.Nickname())
+	target.sendSystemMessage(target.t("unmute.notice", c.Nickname()))
+	c.sendSystemMessage(c.t("unmute.done", target.Nickname()))
+	return nil
+}
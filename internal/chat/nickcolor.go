@@ -0,0 +1,75 @@
+package chat
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/bscott/ts-chat/internal/ui"
+)
+
+// NickColor returns the color others see the client's nickname in, or an
+// empty string for the color derived from the name
+func (c *Client) NickColor() string {
+	if color := c.nickColor.Load(); color != nil {
+		return *color
+	}
+	return ""
+}
+
+// parseColorArg checks a color given to /mycolor or /color against the
+// server's theme. "off" clears the color and gives an empty string.
+func (c *Client) parseColorArg(arg string) (string, error) {
+	if c.config.Theme != nil && !c.config.Theme.NickColors {
+		return "", errors.New(c.t("nickcolor.disabled"))
+	}
+	if strings.EqualFold(arg, "off") {
+		return "", nil
+	}
+	color, err := ui.ParseNickColor(arg)
+	if err != nil {
+		return "", errors.New(c.t("nickcolor.unknown", arg, strings.Join(ui.NickColorNames(), ", ")))
+	}
+	return color, nil
+}
+
+// setMyColor changes the client's own nickname color
+func (c *Client) setMyColor(arg string) error {
+	color, err := c.parseColorArg(arg)
+	if err != nil {
+		return err
+	}
+	c.nickColor.Store(&color)
+	if color == "" {
+		c.sendSystemMessage(c.t("nickcolor.reset"))
+	} else {
+		c.sendSystemMessage(c.t("nickcolor.set", color))
+	}
+	return nil
+}
+
+// setUserColor changes another user's nickname color for the rest of their
+// session
+func (c *Client) setUserColor(nickname, arg string) error {
+	if !c.IsOperator() {
+		return errors.New(c.t("permission.operator", "/color <nickname> <color|off>"))
+	}
+	color, err := c.parseColorArg(arg)
+	if err != nil {
+		return err
+	}
+	target, ok := c.manager.FindClient(nickname)
+	if !ok {
+		return errors.New(c.t("user.not_found", nickname))
+	}
+
+	target.nickColor.Store(&color)
+	c.logger.Info("Nickname color set", "target", target.Nickname(), "color", color)
+	if color == "" {
+		c.sendSystemMessage(c.t("nickcolor.user_reset", target.Nickname()))
+		target.sendSystemMessage(target.t("nickcolor.reset_by", c.Nickname()))
+	} else {
+		c.sendSystemMessage(c.t("nickcolor.user_set", target.Nickname(), color))
+		target.sendSystemMessage(target.t("nickcolor.set_by", c.Nickname(), color))
+	}
+	return nil
+}
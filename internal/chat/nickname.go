@@ -0,0 +1,84 @@
+package chat
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Default nickname rules, used when ClientConfig leaves them unset
+const (
+	DefaultMinNicknameLength = 1
+	DefaultMaxNicknameLength = 20
+	DefaultNicknameSymbols   = "-_."
+)
+
+// Errors returned by ValidateNickname. They are wrapped with details, so
+// compare with errors.Is.
+var (
+	ErrNicknameEmpty    = errors.New("nickname cannot be empty")
+	ErrNicknameTooShort = errors.New("nickname is too short")
+	ErrNicknameTooLong  = errors.New("nickname is too long")
+	ErrNicknameInvalid  = errors.New("nickname contains characters that are not allowed")
+	ErrNicknameReserved = errors.New("nickname is reserved")
+	ErrNicknameTaken    = errors.New("nickname is already taken")
+)
+
+// NicknamePolicy describes which nicknames users may choose
+type NicknamePolicy struct {
+	MinLength int      // Minimum length in characters
+	MaxLength int      // Maximum length in characters
+	Symbols   string   // Characters allowed besides letters and digits
+	Reserved  []string // Nicknames nobody may use, compared case-insensitively
+}
+
+// nicknameKey returns the canonical form of a nickname. Uniqueness is
+// enforced on this form so "Bob" and "bob" can't both be connected, while
+// the nickname as typed is kept for display.
+func nicknameKey(nickname string) string {
+	return strings.ToLower(nickname)
+}
+
+// sameNickname reports whether two nicknames refer to the same user
+func sameNickname(a, b string) bool {
+	return nicknameKey(a) == nicknameKey(b)
+}
+
+// ValidateNickname checks a nickname against a policy. "System" is always
+// reserved since it is used as the sender of server notices. Uniqueness is
+// not checked here as it depends on who is connected.
+func ValidateNickname(nickname string, policy NicknamePolicy) error {
+	if nickname == "" {
+		return ErrNicknameEmpty
+	}
+
+	length := utf8.RuneCountInString(nickname)
+	if length < policy.MinLength {
+		return fmt.Errorf("%w (minimum %d characters)", ErrNicknameTooShort, policy.MinLength)
+	}
+	if policy.MaxLength > 0 && length > policy.MaxLength {
+		return fmt.Errorf("%w (maximum %d characters)", ErrNicknameTooLong, policy.MaxLength)
+	}
+
+	for _, r := range nickname {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune(policy.Symbols, r) {
+			if policy.Symbols == "" {
+				return fmt.Errorf("%w (use letters and digits only)", ErrNicknameInvalid)
+			}
+			return fmt.Errorf("%w (use letters, digits and %s)", ErrNicknameInvalid, policy.Symbols)
+		}
+	}
+
+	if strings.EqualFold(nickname, "system") {
+		return fmt.Errorf("%w: %s", ErrNicknameReserved, nickname)
+	}
+	for _, reserved := range policy.Reserved {
+		if strings.EqualFold(nickname, reserved) {
+			return fmt.Errorf("%w: %s", ErrNicknameReserved, nickname)
+		}
+	}
+
+	return nil
+}
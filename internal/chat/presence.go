@@ -0,0 +1,79 @@
+package chat
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	"github.com/bscott/ts-chat/internal/i18n"
+)
+
+// PresenceTemplates render the notices shown when users join and leave a
+// room. A nil template uses the catalog's wording, "presence.joined" or
+// "presence.left".
+type PresenceTemplates struct {
+	Join  *template.Template
+	Leave *template.Template
+}
+
+// presenceData is what join and leave templates are executed with
+type presenceData struct {
+	Nickname string
+	RoomName string
+}
+
+// ParsePresenceTemplates parses join and leave templates, leaving either one
+// nil if empty so the catalog's wording is used. Templates are test-run so
+// mistakes such as unknown fields are caught up front rather than when
+// someone joins.
+func ParsePresenceTemplates(join, leave string) (PresenceTemplates, error) {
+	var templates PresenceTemplates
+	var err error
+	if templates.Join, err = parsePresenceTemplate("join", join); err != nil {
+		return PresenceTemplates{}, err
+	}
+	if templates.Leave, err = parsePresenceTemplate("leave", leave); err != nil {
+		return PresenceTemplates{}, err
+	}
+	return templates, nil
+}
+
+// parsePresenceTemplate parses and test-runs a single template, returning
+// nil for empty text
+func parsePresenceTemplate(name, text string) (*template.Template, error) {
+	if text == "" {
+		return nil, nil
+	}
+
+	tmpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s template: %w", name, err)
+	}
+	if err := tmpl.Execute(io.Discard, presenceData{Nickname: "nickname", RoomName: "room"}); err != nil {
+		return nil, fmt.Errorf("invalid %s template: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+// joined renders the notice for a user joining room
+func (p PresenceTemplates) joined(catalog *i18n.Catalog, nickname, room string) string {
+	return renderPresence(p.Join, catalog, "presence.joined", nickname, room)
+}
+
+// left renders the notice for a user leaving room
+func (p PresenceTemplates) left(catalog *i18n.Catalog, nickname, room string) string {
+	return renderPresence(p.Leave, catalog, "presence.left", nickname, room)
+}
+
+// renderPresence executes tmpl, falling back to the catalog message id if
+// there is no template or it fails
+func renderPresence(tmpl *template.Template, catalog *i18n.Catalog, id, nickname, room string) string {
+	if tmpl != nil {
+		var sb strings.Builder
+		if err := tmpl.Execute(&sb, presenceData{Nickname: nickname, RoomName: room}); err == nil {
+			return sb.String()
+		}
+	}
+	return catalog.T(id, nickname)
+}
@@ -0,0 +1,104 @@
+package chat
+
+import "errors"
+
+// ErrQueueFull is returned when a room and its waiting queue are both full
+var ErrQueueFull = errors.New("waiting queue is full")
+
+// queueEntry is a client waiting for space in a full room
+type queueEntry struct {
+	client   *Client
+	admitted chan error         // Receives the join result once a slot frees
+	closed   <-chan struct{}    // Closed if the room stops first
+	notify   func(position int) // Told the client's place in the queue whenever it changes
+}
+
+// enqueue adds a client to the room's waiting queue, holding at most
+// maxQueue clients. It returns a nil entry if the room has space after all,
+// in which case the caller should try joining again.
+func (r *Room) enqueue(c *Client, maxQueue int, notify func(position int)) (*queueEntry, error) {
+	r.mu.Lock()
+	if len(r.clients) < r.MaxUsers {
+		r.mu.Unlock()
+		return nil, nil
+	}
+	if len(r.waiting) >= maxQueue {
+		r.mu.Unlock()
+		return nil, ErrQueueFull
+	}
+
+	entry := &queueEntry{
+		client:   c,
+		admitted: make(chan error, 1),
+		closed:   r.ctx.Done(),
+		notify:   notify,
+	}
+	r.waiting = append(r.waiting, entry)
+	position := len(r.waiting)
+	r.mu.Unlock()
+
+	r.logger.Info("Client queued", "nickname", c.Nickname(), "position", position)
+	notify(position)
+	return entry, nil
+}
+
+// dequeue removes a client that gave up waiting. It reports false if the
+// client was already admitted or turned away, in which case the result is
+// waiting in entry.admitted.
+func (r *Room) dequeue(entry *queueEntry) bool {
+	r.mu.Lock()
+	index := -1
+	for i, e := range r.waiting {
+		if e == entry {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		r.mu.Unlock()
+		return false
+	}
+	r.waiting = append(r.waiting[:index], r.waiting[index+1:]...)
+	behind := append([]*queueEntry(nil), r.waiting[index:]...)
+	r.mu.Unlock()
+
+	notifyPositions(behind, index+1)
+	return true
+}
+
+// admitWaitingLocked moves queued clients into the room while there is
+// space, returning the clients admitted and the entries still waiting.
+// r.mu must be held.
+func (r *Room) admitWaitingLocked() (admitted []*Client, waiting []*queueEntry) {
+	moved := false
+	for len(r.waiting) > 0 && len(r.clients) < r.MaxUsers {
+		entry := r.waiting[0]
+		r.waiting = r.waiting[1:]
+		moved = true
+
+		// The nickname isn't held while queued, so someone else may have
+		// taken it in the meantime
+		key := nicknameKey(entry.client.Nickname())
+		if _, taken := r.clients[key]; taken {
+			entry.admitted <- ErrNicknameTaken
+			continue
+		}
+
+		r.clients[key] = entry.client
+		entry.admitted <- nil
+		admitted = append(admitted, entry.client)
+	}
+
+	if !moved {
+		return admitted, nil
+	}
+	return admitted, append([]*queueEntry(nil), r.waiting...)
+}
+
+// notifyPositions tells queued clients their new places, the first of them
+// being at position first
+func notifyPositions(entries []*queueEntry, first int) {
+	for i, entry := range entries {
+		entry.notify(first + i)
+	}
+}
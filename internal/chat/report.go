@@ -0,0 +1,88 @@
+package chat
+
+import (
+	"errors"
+	"time"
+
+	"github.com/bscott/ts-chat/internal/ui"
+)
+
+const (
+	// reportInterval is how long a user must wait between reports, so
+	// /report can't be used to flood the operators
+	reportInterval = time.Minute
+
+	// reportContext is how many of the reported user's recent messages are
+	// included with a report
+	reportContext = 5
+)
+
+// report flags a user to every operator online, along with the reason and
+// the user's last few messages in their room
+func (c *Client) report(nickname, reason string) error {
+	if sameNickname(nickname, c.Nickname()) {
+		return errors.New(c.t("report.self"))
+	}
+	target, ok := c.manager.FindClient(nickname)
+	var room *Room
+	if ok {
+		room = target.Room()
+	}
+	if room == nil || (target.Invisible() && !c.IsOperator()) {
+		return errors.New(c.t("user.not_found", nickname))
+	}
+	if wait := reportInterval - time.Since(c.lastReport); wait > 0 {
+		return errors.New(c.t("report.wait", ui.FormatUptime(wait)))
+	}
+
+	operators := c.manager.Operators()
+	if len(operators) == 0 {
+		return errors.New(c.t("report.no_operators"))
+	}
+
+	var recent []Message
+	for _, msg := range room.History() {
+		if sameNickname(msg.From, target.Nickname()) {
+			recent = append(recent, msg)
+		}
+	}
+	if len(recent) > reportContext {
+		recent = recent[len(recent)-reportContext:]
+	}
+
+	msg := Message{
+		From:      c.Nickname(),
+		To:        target.Nickname(),
+		Content:   reason,
+		Timestamp: time.Now(),
+		IsSystem:  true,
+		IsReport:  true,
+		Context:   recent,
+	}
+	for _, op := range operators {
+		op.sendMessage(msg)
+	}
+
+	c.lastReport = time.Now()
+	c.logger.Warn("User reported", "target", target.Nickname(), "room", room.Name, "reason", reason, "operators", len(operators))
+	c.sendSystemMessage(c.t("report.sent", target.Nickname()))
+	return nil
+}
+
+// formatReport formats a report for an operator, with the reported user's
+// messages timestamped in the operator's time zone
+func (c *Client) formatReport(msg Message) string {
+	entry := ui.ReportEntry{
+		Reporter: msg.From,
+		Target:   msg.To,
+		Reason:   msg.Content,
+	}
+	for _, m := range msg.Context {
+		entry.Context = append(entry.Context, ui.ReportLine{
+			Timestamp: m.Timestamp.In(c.Location()).Format(c.TimeFormat().Layout),
+			Content:   m.Content,
+			Action:    m.IsAction,
+		})
+	}
+	return c.render().FormatReport(entry)
+}
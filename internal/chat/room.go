@@ -2,50 +2,118 @@ package chat
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"sort"
 	"sync"
 	"time"
+
+	"github.com/bscott/ts-chat/internal/i18n"
+	"github.com/bscott/ts-chat/internal/metrics"
+)
+
+// ErrRoomFull is returned by Join when the room has reached its capacity
+var ErrRoomFull = errors.New("room is full")
+
+// ErrRoomClosed is returned by Join, Leave and Broadcast once the room has
+// been stopped
+var ErrRoomClosed = errors.New("room is closed")
+
+// Bounds on the message length limit a room can be given with
+// SetMaxMessageLength. The minimum leaves room to type /setmaxlen again.
+const (
+	MinRoomMessageLength = 20
+	MaxRoomMessageLength = 10000
 )
 
 // Message represents a chat message
 type Message struct {
-	From      string
-	Content   string
-	Timestamp time.Time
-	IsSystem  bool
-	IsAction  bool
+	From      string    `json:"from"`
+	Content   string    `json:"content"`
+	Timestamp time.Time `json:"timestamp"`
+	IsSystem  bool      `json:"system,omitempty"`
+	IsAction  bool      `json:"action,omitempty"`
+	IsPrivate bool      `json:"private,omitempty"` // Private message delivered only to From and To
+	To        string    `json:"to,omitempty"`      // Recipient nickname for private messages
+	IsBot     bool      `json:"bot,omitempty"`     // Reply from a MessageHandler
+	IsAnnouncement bool `json:"announcement,omitempty"` // Operator /announce sent to every room, also marked IsSystem
+	IsHistory bool      `json:"history,omitempty"` // Sent earlier and replayed from the room's history
+	IsReport  bool      `json:"report,omitempty"`  // /report about To sent to operators, with Content as the reason; also marked IsSystem
+	Context   []Message `json:"context,omitempty"` // The reported user's recent messages, for reports
+	Seq       uint64    `json:"seq,omitempty"`     // Position among the room's broadcasts when the room numbers them
+	Color     string    `json:"color,omitempty"`   // Sender's nickname color set with /mycolor or /color, see ui.ParseNickColor
+	sender    *Client   // Client that already echoed the message to itself, skipped by the room
+	queuedAt  time.Time // When the message was queued for a client, for measuring send latency
 }
 
 // Room represents a chat room
 type Room struct {
 	Name      string
 	MaxUsers  int
-	clients   map[string]*Client
+	showOccupancy bool // Add "(3/10 users)" to join and leave notices
+	presence  PresenceTemplates // Wording of join and leave notices
+	clients   map[string]*Client // Keyed by nicknameKey
+	topic     string // Protected by mu
+	topicLog  []TopicChange // Recent topic changes, oldest first, at most TopicLogSize; protected by mu
+	maxMessageLength int // Overrides the server's message length limit when above 0; protected by mu
+	handlers  []MessageHandler // Protected by mu
+	messageLog MessageLogger   // nil when messages aren't logged; protected by mu
+	logSystem bool             // Also log system messages; protected by mu
+	numbered  bool             // Number broadcasts, see SetSequenceNumbers; protected by mu
+	catalog   *i18n.Catalog    // Language of the room's notices, nil for English; protected by mu
+	seq       uint64           // Last sequence number; protected by mu
+	waiting   []*queueEntry    // Clients queued for a place, oldest first; protected by mu
+	history   *History
+	logger    *slog.Logger
 	broadcast chan Message
-	join      chan *Client
-	leave     chan *Client
+	join      chan clientRequest
+	leave     chan clientRequest
 	mu        sync.RWMutex
 	ctx       context.Context
 	cancel    context.CancelFunc
 	done      chan struct{}
+	stopOnce  sync.Once
 }
 
-// NewRoom creates a new chat room
-func NewRoom(name string, maxUsers int) *Room {
+// UserInfo describes a user in a room
+type UserInfo struct {
+	Nickname   string
+	JoinedAt   time.Time // When the user connected
+	LastActive time.Time // When the user last sent a message
+	Away       bool      // Whether the user has marked themselves away
+	DND        bool      // Whether the user refuses private messages with /dnd
+	Invisible  bool      // Whether the user is hidden with /invisible
+	Source     string    // Where the user connected from, see Client.Source
+}
+
+// clientRequest asks the room loop to add or remove a client and reports
+// the outcome once the change has been applied
+type clientRequest struct {
+	client *Client
+	result chan error
+}
+
+// NewRoom creates a new chat room that remembers the last historySize messages
+func NewRoom(name string, maxUsers, historySize int, showOccupancy bool, presence PresenceTemplates, logger *slog.Logger) *Room {
 	ctx, cancel := context.WithCancel(context.Background())
 	room := &Room{
 		Name:      name,
 		MaxUsers:  maxUsers,
+		showOccupancy: showOccupancy,
+		presence:  presence,
 		clients:   make(map[string]*Client),
+		history:   NewHistory(historySize),
+		logger:    logger.With("room", name),
 		broadcast: make(chan Message),
-		join:      make(chan *Client),
-		leave:     make(chan *Client),
+		join:      make(chan clientRequest),
+		leave:     make(chan clientRequest),
 		ctx:       ctx,
 		cancel:    cancel,
 		done:      make(chan struct{}),
 	}
 	
+	metrics.Rooms.Inc()
 	go room.run()
 	return room
 }
@@ -56,12 +124,13 @@ func (r *Room) run() {
 	for {
 		select {
 		case <-r.ctx.Done():
-			log.Printf("Room '%s' is shutting down", r.Name)
+			r.logger.Debug("Room loop is shutting down")
 			return
-		case client := <-r.join:
-			r.addClient(client)
-		case client := <-r.leave:
-			r.removeClient(client)
+		case req := <-r.join:
+			req.result <- r.addClient(req.client)
+		case req := <-r.leave:
+			r.removeClient(req.client)
+			req.result <- nil
 		case msg := <-r.broadcast:
 			r.broadcastMessage(msg)
 		}
@@ -69,116 +138,419 @@ func (r *Room) run() {
 }
 
 // addClient adds a client to the room
-func (r *Room) addClient(c *Client) {
+func (r *Room) addClient(c *Client) error {
 	r.mu.Lock()
-	defer r.mu.Unlock()
 	
-	// Check if room is full
+	// Check if room is full; notifying the client is left to the caller
 	if len(r.clients) >= r.MaxUsers {
-		// Send message but don't close connection here
-		// Connection handling should be done by the caller
-		c.sendSystemMessage("Sorry, the room is full. Try again later.")
-		// Signal that the client wasn't added by setting a flag
-		c.fullRoomRejection = true
-		return
+		r.mu.Unlock()
+		return ErrRoomFull
+	}
+	
+	// Nicknames are checked before joining, but two clients may have
+	// picked the same one concurrently
+	key := nicknameKey(c.Nickname())
+	if _, exists := r.clients[key]; exists {
+		r.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrNicknameTaken, c.Nickname())
 	}
 	
 	// Add client to the room
-	r.clients[c.Nickname] = c
+	r.clients[key] = c
+	count := len(r.clients)
+	r.mu.Unlock()
 	
-	// Notify everyone that a new user has joined
-	systemMsg := Message{
+	// Notify everyone that a new user has joined. The lock must be released
+	// first since broadcastMessage takes it too.
+	if !c.Invisible() {
+		r.broadcastMessage(r.presenceMessage(r.presence.joined(r.language(), c.Nickname(), r.Name), count))
+	}
+	return nil
+}
+
+// presenceMessage builds a join or leave notice, adding the room's
+// occupancy if enabled. count must have been read under r.mu.
+func (r *Room) presenceMessage(content string, count int) Message {
+	if r.showOccupancy {
+		content = r.language().T("room.occupancy", content, count, r.MaxUsers)
+	}
+	return Message{
 		From:      "System",
-		Content:   fmt.Sprintf("%s has joined the room", c.Nickname),
+		Content:   content,
 		Timestamp: time.Now(),
 		IsSystem:  true,
 	}
-	r.broadcastMessage(systemMsg)
 }
 
 // removeClient removes a client from the room
 func (r *Room) removeClient(c *Client) {
 	r.mu.Lock()
-	defer r.mu.Unlock()
+	key := nicknameKey(c.Nickname())
+	exists := r.clients[key] == c
+	if exists {
+		delete(r.clients, key)
+	}
+	count := len(r.clients)
 	
-	if _, exists := r.clients[c.Nickname]; exists {
-		delete(r.clients, c.Nickname)
-		
+	// Give the free slot to whoever has waited longest
+	admitted, waiting := r.admitWaitingLocked()
+	r.mu.Unlock()
+	
+	if exists && !c.Invisible() {
 		// Notify everyone that a user has left
-		systemMsg := Message{
-			From:      "System",
-			Content:   fmt.Sprintf("%s has left the room", c.Nickname),
-			Timestamp: time.Now(),
-			IsSystem:  true,
+		r.broadcastMessage(r.presenceMessage(r.presence.left(r.language(), c.Nickname(), r.Name), count))
+	}
+	
+	for _, client := range admitted {
+		count++
+		if !client.Invisible() {
+			r.broadcastMessage(r.presenceMessage(r.presence.joined(r.language(), client.Nickname(), r.Name), count))
 		}
-		r.broadcastMessage(systemMsg)
 	}
+	notifyPositions(waiting, 1)
 }
 
 // broadcastMessage sends a message to all clients
 func (r *Room) broadcastMessage(msg Message) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	
+	// Don't keep the sender reachable from history
+	sender := msg.sender
+	msg.sender = nil
+	
+	if r.numbered {
+		r.seq++
+		msg.Seq = r.seq
+	}
+	
+	// Join/leave notices are noise when replayed, so only user messages are kept
+	if !msg.IsSystem {
+		r.history.Add(msg)
+		metrics.MessagesTotal.Inc()
+	}
+	
+	r.logger.Debug("Broadcasting message", "from", msg.From, "clients", len(r.clients))
+	for _, client := range r.clients {
+		if client == sender {
+			continue
+		}
+		client.sendMessage(msg) // Queued, so this never blocks the room
+	}
+	
+	if r.messageLog != nil && (!msg.IsSystem || r.logSystem) {
+		r.messageLog.LogMessage(r.Name, msg)
+	}
+
+	// Handlers only see user messages, so bots can't answer each other
+	if !msg.IsSystem && !msg.IsPrivate && !msg.IsBot {
+		for _, h := range r.handlers {
+			go r.runHandler(h, msg)
+		}
+	}
+}
+
+// RegisterHandler adds a handler that sees every user message in the room
+func (r *Room) RegisterHandler(h MessageHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	
+	r.handlers = append(r.handlers, h)
+}
+
+// SetMessageLogger makes the room pass its messages to l. System messages
+// such as join and leave notices are only logged if includeSystem is set.
+func (r *Room) SetMessageLogger(l MessageLogger, includeSystem bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.messageLog = l
+	r.logSystem = includeSystem
+}
+
+// SetSequenceNumbers turns numbering of the room's broadcasts on or off.
+// Numbered messages show their position in the room, which makes dropped
+// or reordered messages easy to spot.
+func (r *Room) SetSequenceNumbers(enabled bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	
+	r.numbered = enabled
+}
+
+// numbersMessages reports whether the room numbers its broadcasts
+func (r *Room) numbersMessages() bool {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	
-	log.Printf("Broadcasting message from %s to %d clients", msg.From, len(r.clients))
-	for nickname, client := range r.clients {
-		log.Printf("Sending to client: %s", nickname)
-		go client.sendMessage(msg) // Use goroutine to avoid blocking
-	}
+	return r.numbered
+}
+
+// SetCatalog sets the language of the room's notices, such as join, leave
+// and topic changes. A nil catalog is English.
+func (r *Room) SetCatalog(catalog *i18n.Catalog) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	
+	r.catalog = catalog
+}
+
+// language returns the catalog the room's notices are worded with
+func (r *Room) language() *i18n.Catalog {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	
+	return r.catalog
 }
 
-// Join adds a client to the room
-func (r *Room) Join(client *Client) {
-	r.join <- client
+// Join adds a client to the room, returning ErrRoomFull if there is no space
+// or ErrRoomClosed if the room has been stopped
+func (r *Room) Join(client *Client) error {
+	req := clientRequest{client: client, result: make(chan error, 1)}
+	select {
+	case r.join <- req:
+		return <-req.result
+	case <-r.ctx.Done():
+		return ErrRoomClosed
+	}
 }
 
-// Leave removes a client from the room
-func (r *Room) Leave(client *Client) {
-	r.leave <- client
+// Leave removes a client from the room. Once the room has been stopped
+// there is nothing to leave, and it returns ErrRoomClosed.
+func (r *Room) Leave(client *Client) error {
+	req := clientRequest{client: client, result: make(chan error, 1)}
+	select {
+	case r.leave <- req:
+		return <-req.result
+	case <-r.ctx.Done():
+		return ErrRoomClosed
+	}
 }
 
-// Broadcast sends a message to all clients
-func (r *Room) Broadcast(msg Message) {
-	r.broadcast <- msg
+// Broadcast sends a message to all clients. Messages sent after the room
+// has been stopped are dropped with ErrRoomClosed.
+func (r *Room) Broadcast(msg Message) error {
+	select {
+	case r.broadcast <- msg:
+		return nil
+	case <-r.ctx.Done():
+		return ErrRoomClosed
+	}
 }
 
-// GetUserList returns a list of all users in the room
-func (r *Room) GetUserList() []string {
+// GetUserList returns the users in the room sorted by nickname. Invisible
+// users are only included if includeInvisible is set, as it is for operators.
+func (r *Room) GetUserList(includeInvisible bool) []UserInfo {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	
-	users := make([]string, 0, len(r.clients))
-	for nickname := range r.clients {
-		users = append(users, nickname)
+	users := make([]UserInfo, 0, len(r.clients))
+	for _, client := range r.clients {
+		invisible := client.Invisible()
+		if invisible && !includeInvisible {
+			continue
+		}
+		_, away := client.Away()
+		users = append(users, UserInfo{
+			Nickname:   client.Nickname(),
+			JoinedAt:   client.JoinedAt,
+			LastActive: client.LastActive(),
+			Away:       away,
+			DND:        client.DoNotDisturb(),
+			Invisible:  invisible,
+			Source:     client.Source(),
+		})
 	}
+	sort.Slice(users, func(i, j int) bool {
+		return nicknameKey(users[i].Nickname) < nicknameKey(users[j].Nickname)
+	})
 	
 	return users
 }
 
-// IsNicknameAvailable checks if a nickname is available
-func (r *Room) IsNicknameAvailable(nickname string) bool {
+// TopicLogSize is how many topic changes a room remembers for /topiclog
+const TopicLogSize = 20
+
+// TopicChange records a change of a room's topic
+type TopicChange struct {
+	Topic string    // The new topic, empty if it was cleared
+	SetBy string    // Nickname of the user who changed it
+	At    time.Time // When it was changed
+}
+
+// Topic returns the room's topic, or an empty string if none is set
+func (r *Room) Topic() string {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	
-	_, exists := r.clients[nickname]
-	return !exists
+	return r.topic
 }
 
-// Stop gracefully shuts down the room
-func (r *Room) Stop() error {
-	log.Printf("Stopping room '%s'", r.Name)
+// MaxMessageLength returns the room's message length limit, or 0 if the
+// server's limit applies
+func (r *Room) MaxMessageLength() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	
-	// Cancel the context to signal the run loop to exit
-	r.cancel()
+	return r.maxMessageLength
+}
+
+// SetMaxMessageLength changes the room's message length limit and announces
+// it to everyone in the room. A limit of 0 restores the server's limit.
+func (r *Room) SetMaxMessageLength(limit int, setBy string) {
+	r.mu.Lock()
+	r.maxMessageLength = limit
+	r.mu.Unlock()
 	
-	// Wait for the run goroutine to finish
-	<-r.done
+	content := r.language().T("room.maxlen_set", setBy, limit)
+	if limit == 0 {
+		content = r.language().T("room.maxlen_reset", setBy)
+	}
+	r.Broadcast(Message{
+		From:      "System",
+		Content:   content,
+		Timestamp: time.Now(),
+		IsSystem:  true,
+	})
+}
+
+// TopicLog returns the room's recent topic changes, oldest first
+func (r *Room) TopicLog() []TopicChange {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	
-	// Close all channels
-	close(r.broadcast)
-	close(r.join)
-	close(r.leave)
+	return append([]TopicChange(nil), r.topicLog...)
+}
+
+// SetTopic changes the room's topic and announces it to everyone in the room
+func (r *Room) SetTopic(topic, setBy string) {
+	r.mu.Lock()
+	r.topic = topic
+	r.topicLog = append(r.topicLog, TopicChange{Topic: topic, SetBy: setBy, At: time.Now()})
+	if len(r.topicLog) > TopicLogSize {
+		r.topicLog = r.topicLog[len(r.topicLog)-TopicLogSize:]
+	}
+	r.mu.Unlock()
+	
+	content := r.language().T("topic.changed", setBy, topic)
+	if topic == "" {
+		content = r.language().T("topic.cleared", setBy)
+	}
+	r.Broadcast(Message{
+		From:      "System",
+		Content:   content,
+		Timestamp: time.Now(),
+		IsSystem:  true,
+	})
+}
+
+// History returns the room's recent messages, oldest first
+func (r *Room) History() []Message {
+	return r.history.Messages()
+}
+
+// UserCount returns the number of users in the room
+func (r *Room) UserCount() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	
-	log.Printf("Room '%s' stopped", r.Name)
+	return len(r.clients)
+}
+
+// members returns the clients in the room
+func (r *Room) members() []*Client {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	
+	clients := make([]*Client, 0, len(r.clients))
+	for _, client := range r.clients {
+		clients = append(clients, client)
+	}
+	return clients
+}
+
+// GetClient looks up a client in the room by nickname, ignoring case
+func (r *Room) GetClient(nickname string) (*Client, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	
+	client, exists := r.clients[nicknameKey(nickname)]
+	return client, exists
+}
+
+// rename moves a client to a new nickname and announces the change
+func (r *Room) rename(c *Client, nickname string) {
+	r.mu.Lock()
+	old := c.Nickname()
+	delete(r.clients, nicknameKey(old))
+	r.clients[nicknameKey(nickname)] = c
+	c.setNickname(nickname)
+	r.mu.Unlock()
+	
+	msg := Message{
+		From:      "System",
+		Content:   r.language().T("nick.changed", old, nickname),
+		Timestamp: time.Now(),
+		IsSystem:  true,
+	}
+	if c.Invisible() {
+		c.sendMessage(msg)
+		return
+	}
+	r.Broadcast(msg)
+}
+
+// announceVisibility tells the room that c has turned /invisible on or off,
+// worded as if they had left or joined so the user list stays consistent
+func (r *Room) announceVisibility(c *Client, visible bool) {
+	r.mu.RLock()
+	count := len(r.clients)
+	r.mu.RUnlock()
+	
+	content := r.presence.left(r.language(), c.Nickname(), r.Name)
+	if visible {
+		content = r.presence.joined(r.language(), c.Nickname(), r.Name)
+	}
+	msg := r.presenceMessage(content, count)
+	msg.sender = c
+	r.Broadcast(msg)
+}
+
+// IsNicknameAvailable checks if a nickname is available, ignoring case
+func (r *Room) IsNicknameAvailable(nickname string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	
+	_, exists := r.clients[nicknameKey(nickname)]
+	return !exists
+}
+
+// Running reports whether the room's event loop is still running
+func (r *Room) Running() bool {
+	select {
+	case <-r.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// Stop gracefully shuts down the room. The channels are left open since
+// clients may still be calling Join, Leave or Broadcast; those calls see
+// the cancelled context and return instead. Stop is safe to call more
+// than once.
+func (r *Room) Stop() error {
+	r.stopOnce.Do(func() {
+		r.logger.Info("Stopping room")
+		
+		// Cancel the context to signal the run loop to exit
+		r.cancel()
+		
+		// Wait for the run goroutine to finish
+		<-r.done
+		metrics.Rooms.Dec()
+		
+		r.logger.Info("Room stopped")
+	})
 	return nil
 }
\ No newline at end of file
@@ -0,0 +1,214 @@
+package chat
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bscott/ts-chat/internal/i18n"
+)
+
+func TestRoomJoinLeaveNotifications(t *testing.T) {
+	room := newTestRoom(t, 10)
+	alice := newFakeClient(t, nil, "alice", ClientConfig{})
+	bob := newFakeClient(t, nil, "bob", ClientConfig{})
+
+	if err := room.Join(alice.Client); err != nil {
+		t.Fatalf("alice joining: %v", err)
+	}
+	if err := room.Join(bob.Client); err != nil {
+		t.Fatalf("bob joining: %v", err)
+	}
+	joined := alice.waitForContent(t, "bob has joined the room")
+	if !joined.IsSystem {
+		t.Errorf("join notice should be a system message: %+v", joined)
+	}
+
+	if err := room.Leave(bob.Client); err != nil {
+		t.Fatalf("bob leaving: %v", err)
+	}
+	alice.waitForContent(t, "bob has left the room")
+
+	if got := room.UserCount(); got != 1 {
+		t.Errorf("UserCount() = %d after bob left, want 1", got)
+	}
+}
+
+func TestRoomNoticesUseCatalog(t *testing.T) {
+	catalog, err := i18n.New("de")
+	if err != nil {
+		t.Fatal(err)
+	}
+	room := newTestRoom(t, 10)
+	room.SetCatalog(catalog)
+	alice := newFakeClient(t, nil, "alice", ClientConfig{})
+	bob := newFakeClient(t, nil, "bob", ClientConfig{})
+
+	if err := room.Join(alice.Client); err != nil {
+		t.Fatalf("alice joining: %v", err)
+	}
+	if err := room.Join(bob.Client); err != nil {
+		t.Fatalf("bob joining: %v", err)
+	}
+	alice.waitForContent(t, "bob hat den Raum betreten")
+
+	room.SetTopic("Go", "bob")
+	alice.waitForContent(t, "bob hat das Thema geändert: Go")
+}
+
+func TestRoomRejectsJoinWhenFull(t *testing.T) {
+	room := newTestRoom(t, 1)
+	alice := newFakeClient(t, nil, "alice", ClientConfig{})
+	bob := newFakeClient(t, nil, "bob", ClientConfig{})
+
+	if err := room.Join(alice.Client); err != nil {
+		t.Fatalf("alice joining: %v", err)
+	}
+	if err := room.Join(bob.Client); !errors.Is(err, ErrRoomFull) {
+		t.Fatalf("joining a full room returned %v, want ErrRoomFull", err)
+	}
+
+	if got := room.UserCount(); got != 1 {
+		t.Errorf("UserCount() = %d, want 1", got)
+	}
+	if _, ok := room.GetClient("bob"); ok {
+		t.Error("bob was added to a full room")
+	}
+	for _, msg := range alice.ReceivedMessages() {
+		if msg.Content == "bob has joined the room" {
+			t.Errorf("alice was told bob joined a full room: %+v", msg)
+		}
+	}
+}
+
+func TestBroadcastPreservesOrder(t *testing.T) {
+	room := newTestRoom(t, 10)
+	bob := newFakeClient(t, nil, "bob", ClientConfig{})
+	if err := room.Join(bob.Client); err != nil {
+		t.Fatalf("bob joining: %v", err)
+	}
+
+	const count = 100
+	for i := 0; i < count; i++ {
+		msg := Message{From: "alice", Content: fmt.Sprintf("message %d", i), Timestamp: time.Now()}
+		if err := room.Broadcast(msg); err != nil {
+			t.Fatalf("broadcasting message %d: %v", i, err)
+		}
+	}
+	bob.waitForContent(t, fmt.Sprintf("message %d", count-1))
+
+	var got []string
+	for _, msg := range bob.ReceivedMessages() {
+		if msg.From == "alice" {
+			got = append(got, msg.Content)
+		}
+	}
+	if len(got) != count {
+		t.Fatalf("bob received %d messages, want %d", len(got), count)
+	}
+	for i, content := range got {
+		if want := fmt.Sprintf("message %d", i); content != want {
+			t.Fatalf("message %d was %q, want %q", i, content, want)
+		}
+	}
+}
+
+func TestBroadcastDuringStop(t *testing.T) {
+	room := newTestRoom(t, 10)
+	bob := newFakeClient(t, nil, "bob", ClientConfig{})
+	if err := room.Join(bob.Client); err != nil {
+		t.Fatalf("bob joining: %v", err)
+	}
+
+	// A send on a closed channel would panic and fail the test
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				room.Broadcast(Message{From: "alice", Content: "spam", Timestamp: time.Now()})
+			}
+		}()
+	}
+	time.Sleep(time.Millisecond)
+	room.Stop()
+	wg.Wait()
+
+	if err := room.Broadcast(Message{From: "alice", Content: "late"}); !errors.Is(err, ErrRoomClosed) {
+		t.Errorf("Broadcast after Stop returned %v, want ErrRoomClosed", err)
+	}
+}
+
+// discardTransport is a Transport that throws away everything written to it
+type discardTransport struct{}
+
+func (discardTransport) Read(p []byte) (int, error)  { return 0, io.EOF }
+func (discardTransport) Write(p []byte) (int, error) { return len(p), nil }
+func (discardTransport) Close() error                { return nil }
+func (discardTransport) RemoteAddr() net.Addr        { return fakeAddr("discard:1") }
+
+// BenchmarkBroadcast broadcasts to a full room and reports the most
+// goroutines running beyond those the room and its clients start with,
+// which stays flat however many messages are sent
+func BenchmarkBroadcast(b *testing.B) {
+	const clients = 50
+	room := newTestRoom(b, clients)
+	for i := 0; i < clients; i++ {
+		c := newClient(discardTransport{}, nil, ClientConfig{JSON: true, Logger: discardLogger})
+		c.setNickname(fmt.Sprintf("user%d", i))
+		go c.writeLoop()
+		b.Cleanup(c.stopWriter)
+		if err := room.Join(c); err != nil {
+			b.Fatalf("joining: %v", err)
+		}
+	}
+
+	baseline := runtime.NumGoroutine()
+	peak := baseline
+	msg := Message{From: "user0", Content: "hello", Timestamp: time.Now()}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := room.Broadcast(msg); err != nil {
+			b.Fatalf("broadcasting: %v", err)
+		}
+		if n := runtime.NumGoroutine(); n > peak {
+			peak = n
+		}
+	}
+	b.StopTimer()
+
+	b.ReportMetric(float64(peak-baseline), "extra-goroutines")
+	if peak > baseline {
+		b.Errorf("goroutines grew from %d to %d while broadcasting", baseline, peak)
+	}
+}
+
+func TestStoppedRoomReturnsErrRoomClosed(t *testing.T) {
+	room := newTestRoom(t, 10)
+	alice := newFakeClient(t, nil, "alice", ClientConfig{})
+	room.Stop()
+
+	calls := map[string]func() error{
+		"Join":      func() error { return room.Join(alice.Client) },
+		"Leave":     func() error { return room.Leave(alice.Client) },
+		"Broadcast": func() error { return room.Broadcast(Message{From: "alice", Content: "hello"}) },
+	}
+	for name, call := range calls {
+		result := make(chan error, 1)
+		go func() { result <- call() }()
+		select {
+		case err := <-result:
+			if !errors.Is(err, ErrRoomClosed) {
+				t.Errorf("%s after Stop returned %v, want ErrRoomClosed", name, err)
+			}
+		case <-time.After(testTimeout):
+			t.Fatalf("%s blocked on a stopped room", name)
+		}
+	}
+}
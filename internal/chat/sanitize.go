@@ -0,0 +1,79 @@
+package chat
+
+import "strings"
+
+// sanitizeInput removes terminal escape sequences and control characters
+// from user input so one user can't move the cursor, recolor or otherwise
+// take over another user's terminal. Tabs become spaces; all other C0 and
+// C1 control characters are dropped, along with any escape sequence they
+// introduce. s must be valid UTF-8.
+func sanitizeInput(s string) string {
+	var sb strings.Builder
+	sb.Grow(len(s))
+
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\t':
+			sb.WriteRune(' ')
+		case r == 0x1b:
+			i = skipEscape(runes, i)
+		case r == 0x9b:
+			// 8-bit CSI
+			i = skipCSI(runes, i+1)
+		case r == 0x9d:
+			// 8-bit OSC
+			i = skipString(runes, i+1)
+		case r < 0x20 || r == 0x7f || (r >= 0x80 && r < 0xa0):
+			// Other control characters are dropped
+		default:
+			sb.WriteRune(r)
+		}
+	}
+
+	return sb.String()
+}
+
+// skipEscape skips the escape sequence starting with the ESC at runes[i],
+// returning the index of its last rune
+func skipEscape(runes []rune, i int) int {
+	if i+1 >= len(runes) {
+		return i
+	}
+	switch runes[i+1] {
+	case '[':
+		return skipCSI(runes, i+2)
+	case ']', 'P', '_', '^', 'X':
+		// OSC, DCS, APC, PM and SOS carry a string up to a terminator
+		return skipString(runes, i+2)
+	default:
+		// Two-character sequence such as ESC c
+		return i + 1
+	}
+}
+
+// skipCSI skips the parameters and final byte of a control sequence whose
+// parameters start at runes[i], returning the index of its last rune
+func skipCSI(runes []rune, i int) int {
+	for ; i < len(runes); i++ {
+		if runes[i] >= 0x40 && runes[i] <= 0x7e {
+			return i
+		}
+	}
+	return len(runes) - 1
+}
+
+// skipString skips a control string starting at runes[i] up to and
+// including its BEL or ST terminator, returning the index of its last rune
+func skipString(runes []rune, i int) int {
+	for ; i < len(runes); i++ {
+		switch {
+		case runes[i] == 0x07 || runes[i] == 0x9c:
+			return i
+		case runes[i] == 0x1b && i+1 < len(runes) && runes[i+1] == '\\':
+			return i + 1
+		}
+	}
+	return len(runes) - 1
+}
@@ -0,0 +1,31 @@
+package chat
+
+import "time"
+
+// Session is what a user gets back by resuming with their token
+type Session struct {
+	Nickname string
+	Room     string
+}
+
+// SessionStore hands out resume tokens and keeps the sessions of users whose
+// connection dropped, so they can reconnect and pick up where they left
+// off. It is implemented by the server.
+type SessionStore interface {
+	// Issue returns a new resume token for a user who just joined
+	Issue() string
+
+	// Suspend keeps a session under its token until the grace window
+	// passes, holding the nickname for its owner in the meantime
+	Suspend(token string, s Session)
+
+	// Resume returns the session kept under token and forgets it, or false
+	// if there is none or it has expired
+	Resume(token string) (Session, bool)
+
+	// Held reports whether nickname belongs to a suspended session
+	Held(nickname string) bool
+
+	// Window returns how long suspended sessions are kept
+	Window() time.Duration
+}
@@ -0,0 +1,18 @@
+package chat
+
+import "time"
+
+// Stats is a snapshot of server-wide statistics
+type Stats struct {
+	Uptime    time.Duration // Time since the server started
+	Messages  int64         // Messages broadcast since the server started
+	Users     int           // Users currently connected
+	PeakUsers int           // Most users connected at once
+	Rooms     int           // Rooms currently open
+}
+
+// StatsProvider reports server statistics for /stats. It is implemented by
+// the server, which sees every connection.
+type StatsProvider interface {
+	Stats() Stats
+}
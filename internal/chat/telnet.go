@@ -0,0 +1,267 @@
+package chat
+
+import (
+	"io"
+	"sync/atomic"
+)
+
+// maxSubnegotiation caps the subnegotiation data we buffer from a client
+const maxSubnegotiation = 64
+
+// Telnet protocol bytes (RFC 854)
+const (
+	telnetSE   = 240 // End of subnegotiation
+	telnetNOP  = 241 // No operation
+	telnetSB   = 250 // Start of subnegotiation
+	telnetWILL = 251
+	telnetWONT = 252
+	telnetDO   = 253
+	telnetDONT = 254
+	telnetIAC  = 255 // Interpret as command
+)
+
+// Telnet options we negotiate
+const (
+	telnetOptEcho       = 1  // RFC 857
+	telnetOptSGA        = 3  // Suppress go-ahead, RFC 858
+	telnetOptTimingMark = 6  // RFC 860, used to check that a client is still there
+	telnetOptNAWS       = 31 // Negotiate about window size, RFC 1073
+)
+
+// telnetState is the parser state of a telnetReader
+type telnetState int
+
+const (
+	stateData   telnetState = iota // Ordinary input
+	stateIAC                       // Seen IAC
+	stateOption                    // Seen IAC WILL/WONT/DO/DONT, expecting the option
+	stateSub                       // Inside a subnegotiation
+	stateSubIAC                    // Seen IAC inside a subnegotiation
+	stateCR                        // Seen CR, a following NUL is dropped
+)
+
+// telnetReader strips Telnet commands from a client's input stream so only
+// the text the user typed reaches the line reader, and answers option
+// negotiation. We agree to suppress go-ahead, to echo input and to receive
+// the client's window size; every other option is refused. Together ECHO
+// and SGA switch clients to sending each keystroke as it is typed.
+type telnetReader struct {
+	r           io.Reader
+	reply       func([]byte) error      // Sends negotiation responses to the client
+	onResize    func(width, height int) // Called when the client reports its window size
+	state       telnetState
+	verb        byte          // Pending WILL/WONT/DO/DONT
+	local       map[byte]bool // Options enabled on our side
+	remote      map[byte]bool // Options enabled on the client's side
+	requested   map[byte]bool // Options we asked the client for that are awaiting an answer
+	offered     map[byte]bool // Options we offered the client that are awaiting an answer
+	sub         []byte        // Subnegotiation data collected so far
+	negotiating atomic.Bool   // The client has negotiated an option, so it will answer a timing mark
+	buf         []byte
+}
+
+// newTelnetReader wraps r, sending negotiation responses with reply and
+// reporting window size changes to onResize
+func newTelnetReader(r io.Reader, reply func([]byte) error, onResize func(width, height int)) *telnetReader {
+	return &telnetReader{
+		r:         r,
+		reply:     reply,
+		onResize:  onResize,
+		local:     make(map[byte]bool),
+		remote:    make(map[byte]bool),
+		requested: make(map[byte]bool),
+		offered:   make(map[byte]bool),
+		buf:       make([]byte, 1024),
+	}
+}
+
+// requestWindowSize asks the client to report its window size. Clients
+// only send NAWS when asked, so this must be sent when the connection opens.
+func (t *telnetReader) requestWindowSize() error {
+	t.requested[telnetOptNAWS] = true
+	return t.reply([]byte{telnetIAC, telnetDO, telnetOptNAWS})
+}
+
+// requestCharacterMode offers to echo input and suppress go-ahead, which
+// makes clients send keystrokes one at a time instead of whole lines
+func (t *telnetReader) requestCharacterMode() error {
+	// Options stay off until the client agrees, so clients that don't
+	// speak Telnet, such as netcat, keep echoing their own input
+	t.offered[telnetOptEcho] = true
+	t.offered[telnetOptSGA] = true
+	return t.reply([]byte{telnetIAC, telnetWILL, telnetOptEcho, telnetIAC, telnetWILL, telnetOptSGA})
+}
+
+// speaksTelnet reports whether the client has shown it understands Telnet
+// commands. Clients such as netcat pass them through as text instead.
+func (t *telnetReader) speaksTelnet() bool {
+	return t.negotiating.Load()
+}
+
+// echoing reports whether the client agreed to let us echo its input
+func (t *telnetReader) echoing() bool {
+	return t.local[telnetOptEcho]
+}
+
+// Read reads user data, discarding any Telnet commands in between
+func (t *telnetReader) Read(p []byte) (int, error) {
+	for {
+		size := len(p)
+		if size > len(t.buf) {
+			size = len(t.buf)
+		}
+
+		n, err := t.r.Read(t.buf[:size])
+		out := 0
+		for _, b := range t.buf[:n] {
+			if t.consume(b) {
+				p[out] = b
+				out++
+			}
+		}
+
+		// Keep reading if the whole chunk was protocol traffic
+		if out > 0 || err != nil {
+			return out, err
+		}
+	}
+}
+
+// consume advances the parser by one byte, reporting whether it is user data
+func (t *telnetReader) consume(b byte) bool {
+	switch t.state {
+	case stateIAC:
+		switch b {
+		case telnetIAC:
+			// Escaped 0xFF data byte
+			t.state = stateData
+			return true
+		case telnetWILL, telnetWONT, telnetDO, telnetDONT:
+			t.verb = b
+			t.state = stateOption
+		case telnetSB:
+			t.sub = t.sub[:0]
+			t.state = stateSub
+		default:
+			// Two-byte commands such as NOP, AYT or GA carry no data
+			t.state = stateData
+		}
+		return false
+
+	case stateOption:
+		t.negotiate(t.verb, b)
+		t.state = stateData
+		return false
+
+	case stateSub:
+		if b == telnetIAC {
+			t.state = stateSubIAC
+		} else if len(t.sub) < maxSubnegotiation {
+			t.sub = append(t.sub, b)
+		}
+		return false
+
+	case stateSubIAC:
+		switch b {
+		case telnetSE:
+			t.subnegotiate(t.sub)
+			t.state = stateData
+		case telnetIAC:
+			// Escaped 0xFF inside the subnegotiation, e.g. a width of 255
+			if len(t.sub) < maxSubnegotiation {
+				t.sub = append(t.sub, b)
+			}
+			t.state = stateSub
+		default:
+			t.state = stateSub
+		}
+		return false
+
+	case stateCR:
+		t.state = stateData
+		if b == 0 {
+			// CR NUL is how Telnet sends a bare carriage return
+			return false
+		}
+	}
+
+	switch b {
+	case telnetIAC:
+		t.state = stateIAC
+		return false
+	case '\r':
+		t.state = stateCR
+	}
+	return true
+}
+
+// negotiate answers an option request. Like RFC 1143 we only acknowledge
+// changes to an option's state, which prevents negotiation loops.
+func (t *telnetReader) negotiate(verb, option byte) {
+	t.negotiating.Store(true)
+
+	// A timing mark is never turned on; WILL or WONT just answers our probe
+	if option == telnetOptTimingMark && (verb == telnetWILL || verb == telnetWONT) {
+		return
+	}
+
+	var response byte
+	switch verb {
+	case telnetDO:
+		offered := t.offered[option]
+		delete(t.offered, option)
+		if option != telnetOptSGA && option != telnetOptEcho {
+			response = telnetWONT
+		} else if !t.local[option] {
+			t.local[option] = true
+			// Answering our own WILL would start a loop
+			if !offered {
+				response = telnetWILL
+			}
+		}
+	case telnetDONT:
+		delete(t.offered, option)
+		if t.local[option] {
+			t.local[option] = false
+			response = telnetWONT
+		}
+	case telnetWILL:
+		requested := t.requested[option]
+		delete(t.requested, option)
+		if option != telnetOptSGA && option != telnetOptNAWS {
+			response = telnetDONT
+		} else if !t.remote[option] {
+			t.remote[option] = true
+			// Answering our own DO would start a loop
+			if !requested {
+				response = telnetDO
+			}
+		}
+	case telnetWONT:
+		requested := t.requested[option]
+		delete(t.requested, option)
+		if t.remote[option] && !requested {
+			response = telnetDONT
+		}
+		t.remote[option] = false
+	}
+
+	if response == 0 {
+		return
+	}
+	// A failed reply will surface as an error on the next write
+	_ = t.reply([]byte{telnetIAC, response, option})
+}
+
+// subnegotiate handles the data of a completed IAC SB ... IAC SE sequence
+func (t *telnetReader) subnegotiate(data []byte) {
+	// NAWS sends the width and height as 16-bit big-endian values
+	if len(data) != 5 || data[0] != telnetOptNAWS || !t.remote[telnetOptNAWS] {
+		return
+	}
+	width := int(data[1])<<8 | int(data[2])
+	height := int(data[3])<<8 | int(data[4])
+	if t.onResize != nil {
+		t.onResize(width, height)
+	}
+}
@@ -0,0 +1,41 @@
+package chat
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+)
+
+func TestTelnetReaderStripsNegotiation(t *testing.T) {
+	input := []byte("hel")
+	input = append(input, telnetIAC, telnetWILL, telnetOptNAWS)
+	input = append(input, "lo"...)
+	input = append(input, telnetIAC, telnetSB, telnetOptNAWS, 0, 100, 0, 40, telnetIAC, telnetSE)
+	input = append(input, " world\r\n"...)
+
+	var replies [][]byte
+	var width, height int
+	telnet := newTelnetReader(bytes.NewReader(input), func(b []byte) error {
+		replies = append(replies, append([]byte(nil), b...))
+		return nil
+	}, func(w, h int) {
+		width, height = w, h
+	})
+
+	line, err := bufio.NewReader(telnet).ReadString('\n')
+	if err != nil {
+		t.Fatalf("reading line: %v", err)
+	}
+	if line != "hello world\r\n" {
+		t.Errorf("read %q, want %q", line, "hello world\r\n")
+	}
+
+	// An unrequested WILL NAWS is accepted, after which the size is used
+	want := []byte{telnetIAC, telnetDO, telnetOptNAWS}
+	if len(replies) != 1 || !bytes.Equal(replies[0], want) {
+		t.Errorf("replies = %v, want [%v]", replies, want)
+	}
+	if width != 100 || height != 40 {
+		t.Errorf("window size = %dx%d, want 100x40", width, height)
+	}
+}
@@ -0,0 +1,46 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultTimeFormat is the timestamp format used until a client picks another
+const DefaultTimeFormat = "time"
+
+// timeFormat is a named timestamp layout users can pick with /timeformat
+type timeFormat struct {
+	Name   string
+	Layout string
+}
+
+// timeFormats lists the layouts users may choose from. Only these are
+// accepted, so arbitrary user input is never used as a layout.
+var timeFormats = []timeFormat{
+	{"time", "15:04:05"},
+	{"short", "15:04"},
+	{"12h", "3:04:05 PM"},
+	{"12h-short", "3:04 PM"},
+	{"datetime", "2006-01-02 15:04:05"},
+	{"iso", time.RFC3339},
+}
+
+// lookupTimeFormat finds a format by name or by its exact layout
+func lookupTimeFormat(nameOrLayout string) (timeFormat, error) {
+	for _, f := range timeFormats {
+		if strings.EqualFold(f.Name, nameOrLayout) || f.Layout == nameOrLayout {
+			return f, nil
+		}
+	}
+	return timeFormat{}, fmt.Errorf("unknown time format %q (available: %s)", nameOrLayout, timeFormatNames())
+}
+
+// timeFormatNames returns the available format names for usage messages
+func timeFormatNames() string {
+	names := make([]string, len(timeFormats))
+	for i, f := range timeFormats {
+		names[i] = f.Name
+	}
+	return strings.Join(names, ", ")
+}
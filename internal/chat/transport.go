@@ -0,0 +1,36 @@
+package chat
+
+import (
+	"context"
+	"io"
+	"net"
+	"time"
+)
+
+// Transport is the connection a client talks over. Any net.Conn satisfies
+// it, so TCP and Tailscale connections are used as they are; other
+// transports such as WebSocket or in-memory pipes only need these methods.
+type Transport interface {
+	io.ReadWriteCloser
+	RemoteAddr() net.Addr
+}
+
+// Line endings written to clients
+const (
+	TelnetLineEnding = "\r\n" // Telnet's network virtual terminal expects CR LF
+	StreamLineEnding = "\n"   // Raw streams such as WebSocket and JSON clients
+)
+
+// deadlineTransport is implemented by transports that support I/O
+// deadlines. Idle timeouts and keepalive write timeouts are only enforced
+// on such transports.
+type deadlineTransport interface {
+	SetReadDeadline(t time.Time) error
+	SetWriteDeadline(t time.Time) error
+}
+
+// pingTransport is implemented by transports with a liveness check of their
+// own, such as WebSocket pings. Ping returns once the peer has answered.
+type pingTransport interface {
+	Ping(ctx context.Context) error
+}
@@ -0,0 +1,9 @@
+package chat
+
+// BuildInfo describes the running binary for /version
+type BuildInfo struct {
+	Version   string // Release version, injected with -ldflags at build time
+	Commit    string // Git commit the binary was built from
+	BuildDate string // When the binary was built
+	GoVersion string // Go toolchain the binary was built with
+}
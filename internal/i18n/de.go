@@ -0,0 +1,317 @@
+package i18n
+
+// german is the German catalog
+var german = map[string]string{
+	// Connecting
+	"reject.busy":          "Der Server ist ausgelastet, versuche es gleich noch einmal.",
+	"reject.full":          "Der Server ist voll. Versuche es später noch einmal.",
+	"reject.banned":        "Du bist auf diesem Server gesperrt.",
+	"reject.per_source":    "Zu viele Verbindungen von deiner Adresse. Versuche es später noch einmal.",
+	"reject.stopping":      "Der Server wird heruntergefahren.",
+	"server.shutting_down": "Der Server wird in %s heruntergefahren",
+	"welcome.title":        "Willkommen beim Tailscale Terminal Chat",
+	"welcome.room":         "Willkommen in %s, %s!",
+	"welcome.hint":         "Schreibe eine Nachricht und drücke Enter zum Senden. Mit /help siehst du alle Befehle.",
+	"welcome.ready":        "Schreibe eine Nachricht und drücke Enter zum Senden. Befehle zeigt /help.",
+	"nickname.prompt":      "Bitte gib deinen Spitznamen ein: ",
+	"nickname.empty":       "Der Spitzname darf nicht leer sein. Bitte versuche es noch einmal.",
+	"nickname.reserved":    "Der Spitzname '%s' ist reserviert. Bitte wähle einen anderen.",
+	"nickname.taken":       "Der Spitzname '%s' ist schon vergeben. Bitte wähle einen anderen.",
+	"nickname.invalid":     "Ungültiger Spitzname: %v. Bitte versuche es noch einmal.",
+	"nickname.renamed":     "Der Spitzname '%s' ist schon vergeben, du heißt jetzt '%s'. Mit /nick kannst du ihn ändern.",
+	"session.token":        "Falls deine Verbindung abbricht, verbinde dich innerhalb von %s neu und gib bei der Frage nach dem Spitznamen /resume %s ein, um weiterzumachen.",
+	"session.resumed":      "Willkommen zurück! Deine Sitzung wurde fortgesetzt.",
+	"session.invalid":      "Dieses Token ist unbekannt oder abgelaufen. Bitte gib stattdessen einen Spitznamen ein.",
+	"join.full":            "Der Raum ist leider voll. Versuche es später noch einmal.",
+	"join.queue_full":      "Der Raum und seine Warteschlange sind leider voll. Versuche es später noch einmal.",
+	"join.left_queue":      "Du hast die Warteschlange verlassen. Tschüss!",
+	"queue.position":       "Der Raum ist voll. Du bist Nummer %d in der Warteschlange und kommst hinein, sobald ein Platz frei wird.",
+	"queue.waiting":        "Du wartest noch auf einen Platz. Mit /quit verlässt du die Warteschlange.",
+	"idle.disconnected":    "Die Verbindung wurde wegen Inaktivität getrennt",
+	"quit.goodbye":         "Tschüss!",
+	"error":                "Fehler: %v",
+	"command.unknown":      "Unbekannter Befehl: %s",
+	"message.too_long":     "Nachricht zu lang (höchstens %d Zeichen)",
+	"message.rate_limit":   "zu viele Nachrichten (höchstens %d pro %s). Versuche es in %.1f Sekunden noch einmal",
+	"message.flood_muted":  "du hast das Nachrichtenlimit wiederholt überschritten und bist für %s stummgeschaltet",
+	"message.filtered":     "Deine Nachricht wurde nicht gesendet, weil sie gesperrte Wörter enthält",
+	"permission.operator":  "keine Berechtigung: %s ist Operatoren vorbehalten",
+
+	// Output headings
+	"you":           "Du",
+	"help.title":    "Verfügbare Befehle:",
+	"users.title":   "Benutzer in %s (%s):",
+	"rooms.title":   "Offene Räume:",
+	"whois.title":   "Über %s:",
+	"report.title":  "Meldung von %s über %s",
+	"stats.title":   "Serverstatistik:",
+	"version.title": "Serverversion:",
+	"topic.title":   "Thema:",
+	"motd.title":    "Nachricht des Tages:",
+	"emoji.title":   "Emoji-Kürzel:",
+	"emoji.on":      "(an, ausschalten mit /emoji off)",
+	"emoji.off":     "(aus, einschalten mit /emoji on)",
+	"bans.title":    "Gesperrt (%d):",
+	"bans.none":     "Keine Sperren",
+
+	// Output labels
+	"users.nickname":     "Spitzname",
+	"users.connected":    "Verbunden",
+	"users.idle":         "Untätig",
+	"users.source":       "Herkunft",
+	"users.away":         "[abwesend]",
+	"users.dnd":          "[nicht stören]",
+	"users.invisible":    "[unsichtbar]",
+	"field.uptime":       "Laufzeit",
+	"field.messages":     "Nachrichten",
+	"field.users":        "Benutzer",
+	"field.rooms":        "Räume",
+	"field.room":         "Raum",
+	"field.connected":    "Verbunden",
+	"field.idle":         "Untätig",
+	"field.away":         "Abwesend",
+	"field.operator":     "Operator",
+	"field.invisible":    "Unsichtbar",
+	"field.muted":        "Stumm",
+	"field.latency":      "Latenz",
+	"field.source":       "Herkunft",
+	"field.identity":     "Identität",
+	"field.version":      "Version",
+	"field.commit":       "Commit",
+	"field.built":        "Erstellt",
+	"field.go":           "Go",
+	"stats.users":        "%d (Höchststand %d)",
+	"whois.yes":          "ja",
+	"whois.muted":        "noch %s",
+	"whois.latency":      "%s im Schnitt, höchstens %s",
+	"report.reason":      "Grund: %s",
+	"report.no_reason":   "(keiner angegeben)",
+	"report.no_messages": "Keine aktuellen Nachrichten",
+
+	// /topiclog
+	"topiclog.title":   "Themenverlauf von %s:",
+	"topiclog.none":    "Das Thema wurde nicht geändert",
+	"topiclog.cleared": "(gelöscht)",
+	"topiclog.ago":     "vor %s",
+
+	// /help, one line per command
+	"help.who":        "/who - Alle Benutzer im Raum anzeigen",
+	"help.names":      "/names [--json] - Die Spitznamen im Raum in einer Zeile auflisten, für Skripte",
+	"help.me":         "/me <Aktion> - Eine Aktion ausführen",
+	"help.macros":     "%s [Nachricht] - Eine Nachricht mit dem Text des Makros am Ende senden, z. B. hängt /shrug ¯\\_(ツ)_/¯ an",
+	"help.slap":       "/slap <Spitzname> - Einem Benutzer im Raum mit einer großen Forelle eins überziehen",
+	"help.hug":        "/hug <Spitzname> - Einen Benutzer im Raum umarmen",
+	"help.roll":       "/roll [NdM] - Würfeln, z. B. /roll 2d20 (Standard 1d6)",
+	"help.msg":        "/msg <Spitzname> <Nachricht> - Private Nachricht senden (auch: /w)",
+	"help.nick":       "/nick <Spitzname> - Spitznamen ändern",
+	"help.away":       "/away [Nachricht] - Dich als abwesend markieren",
+	"help.back":       "/back - Abwesenheit beenden",
+	"help.dnd":        "/dnd - Private Nachrichten abweisen, bis du wieder /dnd oder /back verwendest",
+	"help.invisible":  "/invisible - Dich in /who und bei Betreten/Verlassen verbergen oder wieder zeigen",
+	"help.join":       "/join <Raum> - Einen Raum betreten oder erstellen",
+	"help.leave":      "/leave - Zurück in die Lobby",
+	"help.whois":      "/whois <Spitzname> - Details über einen Benutzer anzeigen",
+	"help.report":     "/report <Spitzname> [Grund] - Die Operatoren auf das Verhalten eines Benutzers hinweisen",
+	"help.rooms":      "/rooms - Offene Räume auflisten",
+	"help.topic":      "/topic - Thema des Raums anzeigen",
+	"help.topiclog":   "/topiclog - Anzeigen, wer das Thema des Raums wann geändert hat",
+	"help.setmaxlen":  "/setmaxlen - Maximale Nachrichtenlänge im Raum anzeigen",
+	"help.ignore":     "/ignore [Spitzname] - Nachrichten eines Benutzers ausblenden oder ignorierte Benutzer auflisten",
+	"help.unignore":   "/unignore <Spitzname> - Nachrichten eines Benutzers wieder anzeigen",
+	"help.color":      "/color on|off - Farbige Ausgabe ein- oder ausschalten",
+	"help.mycolor":    "/mycolor <Farbe|off> - Die Farbe wählen, in der andere deinen Spitznamen sehen",
+	"help.markdown":   "/markdown on|off - *Fett* und _kursiv_ in Nachrichten anzeigen oder so, wie sie getippt wurden",
+	"help.clear":      "/clear - Bildschirm leeren",
+	"help.tz":         "/tz [Zone] - Zeitzone anzeigen oder setzen, z. B. /tz Europe/Berlin",
+	"help.timeformat": "/timeformat [Format] - Zeitformat anzeigen oder setzen: time, short, 12h, 12h-short, datetime oder iso",
+	"help.emoji":      "/emoji [on|off] - Emoji-Kürzel wie :smile: auflisten oder ihre Umwandlung ein- oder ausschalten",
+	"help.history":    "/history [Anzahl] - Die letzten Nachrichten im Raum anzeigen (Standard 20)",
+	"help.stats":      "/stats - Serverstatistik anzeigen",
+	"help.uptime":     "/uptime - Anzeigen, wie lange der Server schon läuft",
+	"help.version":    "/version - Serverversion anzeigen",
+	"help.ping":       "/ping - Prüfen, wie schnell der Server antwortet",
+	"help.help":       "/help - Diese Hilfe anzeigen",
+	"help.quit":       "/quit - Den Chat verlassen",
+	"help.op":         "/op <Passwort> - Operator werden",
+
+	// /help for operators
+	"help.op.title":     "Befehle für Operatoren:",
+	"help.op.topic":     "/topic <Text> - Thema des Raums setzen (- löscht es)",
+	"help.op.topiclog":  "/topiclog - Anzeigen, wer das Thema des Raums wann geändert hat",
+	"help.op.setmaxlen": "/setmaxlen <n> - Maximale Nachrichtenlänge im Raum setzen (- stellt den Serverstandard wieder her)",
+	"help.op.announce":  "/announce <Nachricht> - Eine Ankündigung an alle Räume senden",
+	"help.op.kick":      "/kick <Spitzname> [Grund] - Einen Benutzer aus dem Raum entfernen",
+	"help.op.mute":      "/mute <Spitzname> [Dauer] - Einen Benutzer eine Weile stummschalten, standardmäßig 10m",
+	"help.op.unmute":    "/unmute <Spitzname> - Eine Stummschaltung vorzeitig aufheben",
+	"help.op.ban":       "/ban <Spitzname> [Grund] - Die Adresse eines Benutzers sperren",
+	"help.op.unban":     "/unban <Adresse> - Eine Sperre aufheben",
+	"help.op.banlist":   "/banlist - Gesperrte Adressen anzeigen",
+	"help.op.filter":    "/filter reload - Wortfilter neu laden",
+	"help.op.color":     "/color <Spitzname> <Farbe|off> - Die Farbe festlegen, in der ein Spitzname angezeigt wird",
+	"help.op.export":    "/export - Den Verlauf des Raums in eine Datei auf dem Server speichern",
+
+	// Usage, one line per command that takes arguments
+	"usage.names":      "/names [--json]",
+	"usage.whois":      "/whois <Spitzname>",
+	"usage.report":     "/report <Spitzname> [Grund]",
+	"usage.me":         "/me <Aktion>",
+	"usage.slap":       "/slap <Spitzname>",
+	"usage.hug":        "/hug <Spitzname>",
+	"usage.roll":       "/roll [NdM], z. B. /roll 2d20 (Standard 1d6)",
+	"usage.msg":        "/msg <Spitzname> <Nachricht>",
+	"usage.nick":       "/nick <Spitzname>",
+	"usage.away":       "/away [Nachricht]",
+	"usage.mycolor":    "/mycolor <Farbe|off>",
+	"usage.join":       "/join <Raum>",
+	"usage.topic":      "/topic <Text>",
+	"usage.setmaxlen":  "/setmaxlen <%d-%d> oder /setmaxlen - für den Serverstandard",
+	"usage.ignore":     "/ignore [Spitzname]",
+	"usage.unignore":   "/unignore <Spitzname>",
+	"usage.color":      "/color on|off oder, für Operatoren, /color <Spitzname> <Farbe|off>",
+	"usage.markdown":   "/markdown on|off",
+	"usage.tz":         "/tz <Zone>, z. B. /tz Europe/Berlin",
+	"usage.timeformat": "/timeformat <Format>, eines von: %s",
+	"usage.emoji":      "/emoji [on|off]",
+	"usage.history":    "/history [Anzahl]",
+	"usage.op":         "/op <Passwort>",
+	"usage.announce":   "/announce <Nachricht>",
+	"usage.kick":       "/kick <Spitzname> [Grund]",
+	"usage.mute":       "/mute <Spitzname> [Dauer], z. B. /mute bob 30m",
+	"usage.unmute":     "/unmute <Spitzname>",
+	"usage.ban":        "/ban <Spitzname> [Grund]",
+	"usage.unban":      "/unban <Adresse>",
+	"usage.filter":     "/filter reload",
+
+	// Command replies
+	"command.usage":                "Verwendung: %s",
+	"command.not_found":            "unbekannter Befehl: %s",
+	"command.invalid_usage":        "falsche Verwendung von %s",
+	"command.invalid_usage_reason": "falsche Verwendung von %s: %s",
+	"read.error":                   "Fehler beim Lesen der Nachricht: %v",
+	"user.not_found":               "kein solcher Benutzer: %s",
+	"room.no_such_user":            "kein solcher Benutzer in %s: %s",
+	"away.on":                      "Du bist jetzt als abwesend markiert",
+	"away.on_reason":               "Du bist jetzt als abwesend markiert: %s",
+	"away.not":                     "Du bist nicht als abwesend markiert",
+	"away.off":                     "Du bist nicht mehr als abwesend markiert",
+	"away.notice":                  "%s ist abwesend",
+	"away.notice_reason":           "%s ist abwesend: %s",
+	"muted.remaining":              "Du bist noch %s stummgeschaltet",
+	"msg.self":                     "Du kannst dir selbst keine private Nachricht schicken",
+	"nick.same":                    "Du heißt bereits %s",
+	"room.already_in":              "Du bist bereits in %s",
+	"room.full":                    "%s ist voll",
+	"room.now_in":                  "Du bist jetzt in %s",
+	"topic.none":                   "Es ist kein Thema gesetzt",
+	"setmaxlen.show":               "Maximale Nachrichtenlänge in %s: %d Zeichen",
+	"color.is_on":                  "Farbe ist an. Verwendung: /color on|off",
+	"color.is_off":                 "Farbe ist aus. Verwendung: /color on|off",
+	"color.enabled":                "Farbe eingeschaltet",
+	"color.disabled":               "Farbe ausgeschaltet",
+	"markdown.is_on":               "Markdown ist an. Verwendung: /markdown on|off",
+	"markdown.is_off":              "Markdown ist aus. Verwendung: /markdown on|off",
+	"markdown.enabled":             "Markdown eingeschaltet: *fett* und _kursiv_ werden formatiert angezeigt",
+	"markdown.disabled":            "Markdown ausgeschaltet: Nachrichten werden so angezeigt, wie sie getippt wurden",
+	"clear.no_ansi":                "/clear braucht ANSI-Unterstützung; schalte sie mit /color on ein",
+	"tz.show":                      "Deine Zeitzone ist %s. Verwendung: /tz <Zone>, z. B. /tz Europe/Berlin",
+	"tz.set":                       "Zeitzone auf %s gesetzt",
+	"tz.unknown":                   "unbekannte Zeitzone %q (verwende einen IANA-Namen wie Europe/Berlin oder UTC)",
+	"timeformat.show":              "Dein Zeitformat ist %s. Verwendung: /timeformat <Format>, eines von: %s",
+	"timeformat.set":               "Zeitformat auf %s gesetzt, z. B. %s",
+	"timeformat.unknown":           "unbekanntes Zeitformat %q (verfügbar: %s)",
+	"emoji.enabled":                "Emoji-Kürzel werden umgewandelt",
+	"emoji.disabled":               "Emoji-Kürzel werden so gesendet, wie sie getippt wurden",
+	"uptime.unavailable":           "die Laufzeit ist auf diesem Server nicht verfügbar",
+	"uptime.show":                  "Laufzeit des Servers: %s",
+	"stats.unavailable":            "Statistiken sind auf diesem Server nicht verfügbar",
+	"ping.pong":                    "Pong! Verarbeitungszeit des Servers: %s",
+	"history.none":                 "Keine neuen Nachrichten in %s",
+	"history.title":                "Die letzten %d Nachrichten:",
+	"export.disabled":              "Exporte sind auf diesem Server nicht aktiviert",
+	"export.empty":                 "%s hat keinen Verlauf zum Exportieren",
+	"export.failed":                "Export fehlgeschlagen",
+	"dice.invalid":                 "ungültige Würfel %q",
+	"dice.count":                   "du kannst zwischen 1 und %d Würfel werfen",
+	"dice.sides":                   "Würfel müssen zwischen 2 und %d Seiten haben",
+	"dice.roll":                    "würfelt %dd%d: %d",
+	"dice.rolls":                   "würfelt %dd%d: %s (Summe %d)",
+	"action.slap":                  "zieht %s mit einer großen Forelle eins über",
+	"action.hug":                   "umarmt %s",
+	"export.done":                  "%d Nachrichten nach %s exportiert",
+
+	// /dnd
+	"dnd.on":     "Bitte nicht stören ist an: private Nachrichten an dich werden abgewiesen, und die Absender erfahren es",
+	"dnd.off":    "Bitte nicht stören ist aus",
+	"dnd.notice": "%s nimmt gerade keine privaten Nachrichten an (bitte nicht stören)",
+
+	// /mycolor and /color
+	"nickcolor.default":    "die aus deinem Namen gewählte",
+	"nickcolor.show":       "Deine Spitznamenfarbe ist %s. Farben: %s, #RRGGBB oder 0-255",
+	"nickcolor.disabled":   "Spitznamenfarben sind auf diesem Server ausgeschaltet",
+	"nickcolor.unknown":    "unbekannte Farbe %q: verwende %s, eine Hex-Farbe wie #FF8800 oder eine Zahl von 0 bis 255",
+	"nickcolor.reset":      "Dein Spitzname wird wieder in seiner üblichen Farbe angezeigt",
+	"nickcolor.set":        "Deine Spitznamenfarbe ist jetzt %s",
+	"nickcolor.user_reset": "Der Spitzname von %s wird wieder in seiner üblichen Farbe angezeigt",
+	"nickcolor.user_set":   "Die Spitznamenfarbe von %s ist jetzt %s",
+	"nickcolor.reset_by":   "%s hat deine Spitznamenfarbe zurückgesetzt",
+	"nickcolor.set_by":     "%s hat deine Spitznamenfarbe auf %s gesetzt",
+
+	// /invisible
+	"invisible.on":  "Du bist jetzt unsichtbar: /who zeigt dich nicht an, und dein Kommen und Gehen wird nicht angekündigt",
+	"invisible.off": "Du bist wieder sichtbar",
+
+	// /ignore and /unignore
+	"ignore.self":    "du kannst dich nicht selbst ignorieren",
+	"ignore.added":   "%s wird ignoriert",
+	"ignore.not":     "du ignorierst %s nicht",
+	"ignore.removed": "%s wird nicht mehr ignoriert",
+	"ignore.none":    "Du ignorierst niemanden",
+	"ignore.list":    "Ignoriert: %s",
+
+	// /report
+	"report.self":         "du kannst dich nicht selbst melden",
+	"report.wait":         "du kannst in %s wieder etwas melden",
+	"report.no_operators": "es sind keine Operatoren online, die die Meldung empfangen könnten",
+	"report.sent":         "Deine Meldung über %s wurde an die Operatoren geschickt",
+
+	// Operators
+	"op.already":         "Du bist bereits Operator",
+	"op.bad_password":    "falsches Operator-Passwort",
+	"op.granted":         "Du bist jetzt Operator",
+	"filter.disabled":    "der Wortfilter ist auf diesem Server nicht aktiviert",
+	"filter.reloaded":    "Wortfilter mit %d Einträgen neu geladen",
+	"kick.self":          "du kannst dich nicht selbst hinauswerfen",
+	"kick.notice":        "Du wurdest von %s hinausgeworfen",
+	"kick.notice_reason": "Du wurdest von %s hinausgeworfen: %s",
+	"mute.self":          "du kannst dich nicht selbst stummschalten",
+	"mute.not_duration":  "%s ist keine Dauer",
+	"mute.notice":        "Du wurdest von %s für %s stummgeschaltet. Mitlesen kannst du weiterhin",
+	"mute.done":          "%s ist für %s stummgeschaltet",
+	"unmute.not_muted":   "%s ist nicht stummgeschaltet",
+	"unmute.notice":      "%s hat deine Stummschaltung aufgehoben",
+	"unmute.done":        "%s ist nicht mehr stummgeschaltet",
+	"bans.disabled":      "Sperren sind auf diesem Server nicht aktiviert",
+	"ban.self":           "du kannst dich nicht selbst sperren",
+	"ban.no_source":      "die Verbindungsquelle von %s ist unbekannt",
+	"ban.save_failed":    "Warnung: die Sperre konnte nicht gespeichert werden: %v",
+	"ban.notice":         "Du wurdest von %s gesperrt",
+	"ban.notice_reason":  "Du wurdest von %s gesperrt: %s",
+	"ban.done":           "%s gesperrt",
+	"unban.save_failed":  "Warnung: die Sperrliste konnte nicht gespeichert werden: %v",
+	"unban.not_banned":   "%s ist nicht gesperrt",
+	"unban.done":         "Sperre für %s aufgehoben",
+
+	// Room notices, seen by everyone in the room
+	"presence.joined":      "%s hat den Raum betreten",
+	"presence.left":        "%s hat den Raum verlassen",
+	"room.occupancy":       "%s (%d/%d Benutzer)",
+	"room.maxlen_set":      "%s hat die maximale Nachrichtenlänge auf %d Zeichen gesetzt",
+	"room.maxlen_reset":    "%s hat die maximale Nachrichtenlänge auf den Serverstandard zurückgesetzt",
+	"topic.changed":        "%s hat das Thema geändert: %s",
+	"topic.cleared":        "%s hat das Thema gelöscht",
+	"nick.changed":         "%s heißt jetzt %s",
+	"kick.announce":        "%s wurde von %s hinausgeworfen",
+	"kick.announce_reason": "%s wurde von %s hinausgeworfen: %s",
+	"ban.announce":         "%s wurde von %s gesperrt",
+	"ban.announce_reason":  "%s wurde von %s gesperrt: %s",
+}
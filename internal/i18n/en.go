@@ -0,0 +1,317 @@
+package i18n
+
+// english is the default catalog. Every message ID must be present here.
+var english = map[string]string{
+	// Connecting
+	"reject.busy":          "Server busy, try again shortly.",
+	"reject.full":          "The server is full. Try again later.",
+	"reject.banned":        "You are banned from this server.",
+	"reject.per_source":    "Too many connections from your address. Try again later.",
+	"reject.stopping":      "The server is shutting down.",
+	"server.shutting_down": "The server is shutting down in %s",
+	"welcome.title":        "Welcome to Tailscale Terminal Chat",
+	"welcome.room":         "Welcome to %s, %s!",
+	"welcome.hint":         "Type a message and press Enter to send. Use /help to see available commands.",
+	"welcome.ready":        "Type a message and press Enter to send. Type /help for commands.",
+	"nickname.prompt":      "Please enter your nickname: ",
+	"nickname.empty":       "Nickname cannot be empty. Please try again.",
+	"nickname.reserved":    "Nickname '%s' is reserved. Please choose another nickname.",
+	"nickname.taken":       "Nickname '%s' is already taken. Please choose another nickname.",
+	"nickname.invalid":     "Invalid nickname: %v. Please try again.",
+	"nickname.renamed":     "Nickname '%s' is already taken, so you'll be known as '%s'. Use /nick to change it.",
+	"session.token":        "If your connection drops, reconnect within %s and enter /resume %s at the nickname prompt to pick up where you left off.",
+	"session.resumed":      "Welcome back! Your session has been resumed.",
+	"session.invalid":      "That resume token is unknown or has expired. Please enter a nickname instead.",
+	"join.full":            "Sorry, the room is full. Try again later.",
+	"join.queue_full":      "Sorry, the room and its waiting queue are full. Try again later.",
+	"join.left_queue":      "You left the queue. Goodbye!",
+	"queue.position":       "The room is full. You are number %d in the queue and will join when a place frees up.",
+	"queue.waiting":        "You are still waiting for a place. Type /quit to leave the queue.",
+	"idle.disconnected":    "You have been disconnected due to inactivity",
+	"quit.goodbye":         "Goodbye!",
+	"error":                "Error: %v",
+	"command.unknown":      "Unknown command: %s",
+	"message.too_long":     "message too long (max %d characters)",
+	"message.rate_limit":   "rate limit exceeded (max %d messages per %s). Try again in %.1f seconds",
+	"message.flood_muted":  "you kept exceeding the rate limit and are muted for %s",
+	"message.filtered":     "Your message was not sent because it contains blocked words",
+	"permission.operator":  "permission denied: %s requires operator status",
+
+	// Output headings
+	"you":           "You",
+	"help.title":    "Available Commands:",
+	"users.title":   "Users in %s (%s):",
+	"rooms.title":   "Open rooms:",
+	"whois.title":   "About %s:",
+	"report.title":  "Report from %s about %s",
+	"stats.title":   "Server statistics:",
+	"version.title": "Server version:",
+	"topic.title":   "Topic:",
+	"motd.title":    "Message of the day:",
+	"emoji.title":   "Emoji shortcodes:",
+	"emoji.on":      "(on, turn off with /emoji off)",
+	"emoji.off":     "(off, turn on with /emoji on)",
+	"bans.title":    "Banned (%d):",
+	"bans.none":     "No bans",
+
+	// Output labels
+	"users.nickname":     "Nickname",
+	"users.connected":    "Connected",
+	"users.idle":         "Idle",
+	"users.source":       "Source",
+	"users.away":         "[away]",
+	"users.dnd":          "[dnd]",
+	"users.invisible":    "[invisible]",
+	"field.uptime":       "Uptime",
+	"field.messages":     "Messages",
+	"field.users":        "Users",
+	"field.rooms":        "Rooms",
+	"field.room":         "Room",
+	"field.connected":    "Connected",
+	"field.idle":         "Idle",
+	"field.away":         "Away",
+	"field.operator":     "Operator",
+	"field.invisible":    "Invisible",
+	"field.muted":        "Muted",
+	"field.latency":      "Latency",
+	"field.source":       "Source",
+	"field.identity":     "Identity",
+	"field.version":      "Version",
+	"field.commit":       "Commit",
+	"field.built":        "Built",
+	"field.go":           "Go",
+	"stats.users":        "%d (peak %d)",
+	"whois.yes":          "yes",
+	"whois.muted":        "%s left",
+	"whois.latency":      "%s average, %s worst",
+	"report.reason":      "Reason: %s",
+	"report.no_reason":   "(none given)",
+	"report.no_messages": "No recent messages",
+
+	// /topiclog
+	"topiclog.title":   "Topic history of %s:",
+	"topiclog.none":    "The topic hasn't been changed",
+	"topiclog.cleared": "(cleared)",
+	"topiclog.ago":     "%s ago",
+
+	// /help, one line per command
+	"help.who":        "/who - Show all users in the room",
+	"help.names":      "/names [--json] - List the nicknames in the room on one line, for scripts",
+	"help.me":         "/me <action> - Perform an action",
+	"help.macros":     "%s [message] - Send a message ending in the macro's text, e.g. /shrug adds ¯\\_(ツ)_/¯",
+	"help.slap":       "/slap <nickname> - Slap a user in the room around a bit with a large trout",
+	"help.hug":        "/hug <nickname> - Hug a user in the room",
+	"help.roll":       "/roll [NdM] - Roll dice, e.g. /roll 2d20 (default 1d6)",
+	"help.msg":        "/msg <nickname> <message> - Send a private message (alias: /w)",
+	"help.nick":       "/nick <nickname> - Change your nickname",
+	"help.away":       "/away [message] - Mark yourself as away",
+	"help.back":       "/back - Clear your away status",
+	"help.dnd":        "/dnd - Turn private messages away until you use /dnd or /back again",
+	"help.invisible":  "/invisible - Hide from /who and join/leave notices, or show yourself again",
+	"help.join":       "/join <room> - Join or create a room",
+	"help.leave":      "/leave - Return to the lobby",
+	"help.whois":      "/whois <nickname> - Show details about a user",
+	"help.report":     "/report <nickname> [reason] - Alert the operators to a user's behavior",
+	"help.rooms":      "/rooms - List open rooms",
+	"help.topic":      "/topic - Show the room topic",
+	"help.topiclog":   "/topiclog - Show who changed the room topic, and when",
+	"help.setmaxlen":  "/setmaxlen - Show the room's maximum message length",
+	"help.ignore":     "/ignore [nickname] - Hide a user's messages, or list ignored users",
+	"help.unignore":   "/unignore <nickname> - Show a user's messages again",
+	"help.color":      "/color on|off - Turn colored output on or off",
+	"help.mycolor":    "/mycolor <color|off> - Choose the color others see your nickname in",
+	"help.markdown":   "/markdown on|off - Show *bold* and _italic_ in messages, or show them as typed",
+	"help.clear":      "/clear - Clear your screen",
+	"help.tz":         "/tz [zone] - Show or set your time zone, e.g. /tz Europe/Berlin",
+	"help.timeformat": "/timeformat [format] - Show or set your timestamp format: time, short, 12h, 12h-short, datetime or iso",
+	"help.emoji":      "/emoji [on|off] - List emoji shortcodes such as :smile:, or turn expanding them on or off",
+	"help.history":    "/history [count] - Show the room's most recent messages (default 20)",
+	"help.stats":      "/stats - Show server statistics",
+	"help.uptime":     "/uptime - Show how long the server has been running",
+	"help.version":    "/version - Show the server version",
+	"help.ping":       "/ping - Check how quickly the server responds",
+	"help.help":       "/help - Show this help message",
+	"help.quit":       "/quit - Leave the chat",
+	"help.op":         "/op <password> - Become an operator",
+
+	// /help for operators
+	"help.op.title":     "Operator Commands:",
+	"help.op.topic":     "/topic <text> - Set the room topic (- clears it)",
+	"help.op.topiclog":  "/topiclog - Show who changed the room topic, and when",
+	"help.op.setmaxlen": "/setmaxlen <n> - Set the room's maximum message length (- restores the server default)",
+	"help.op.announce":  "/announce <message> - Send an announcement to every room",
+	"help.op.kick":      "/kick <nickname> [reason] - Remove a user from the room",
+	"help.op.mute":      "/mute <nickname> [duration] - Silence a user for a while, 10m by default",
+	"help.op.unmute":    "/unmute <nickname> - Lift a mute early",
+	"help.op.ban":       "/ban <nickname> [reason] - Ban a user's address",
+	"help.op.unban":     "/unban <address> - Lift a ban",
+	"help.op.banlist":   "/banlist - Show banned addresses",
+	"help.op.filter":    "/filter reload - Reload the word filter",
+	"help.op.color":     "/color <nickname> <color|off> - Set the color a user's nickname is shown in",
+	"help.op.export":    "/export - Save the room's recent history to a file on the server",
+
+	// Usage, one line per command that takes arguments
+	"usage.names":      "/names [--json]",
+	"usage.whois":      "/whois <nickname>",
+	"usage.report":     "/report <nickname> [reason]",
+	"usage.me":         "/me <action>",
+	"usage.slap":       "/slap <nickname>",
+	"usage.hug":        "/hug <nickname>",
+	"usage.roll":       "/roll [NdM], e.g. /roll 2d20 (default 1d6)",
+	"usage.msg":        "/msg <nickname> <message>",
+	"usage.nick":       "/nick <nickname>",
+	"usage.away":       "/away [message]",
+	"usage.mycolor":    "/mycolor <color|off>",
+	"usage.join":       "/join <room>",
+	"usage.topic":      "/topic <text>",
+	"usage.setmaxlen":  "/setmaxlen <%d-%d>, or /setmaxlen - for the server default",
+	"usage.ignore":     "/ignore [nickname]",
+	"usage.unignore":   "/unignore <nickname>",
+	"usage.color":      "/color on|off, or /color <nickname> <color|off> for operators",
+	"usage.markdown":   "/markdown on|off",
+	"usage.tz":         "/tz <zone>, e.g. /tz Europe/Berlin",
+	"usage.timeformat": "/timeformat <format>, one of: %s",
+	"usage.emoji":      "/emoji [on|off]",
+	"usage.history":    "/history [count]",
+	"usage.op":         "/op <password>",
+	"usage.announce":   "/announce <message>",
+	"usage.kick":       "/kick <nickname> [reason]",
+	"usage.mute":       "/mute <nickname> [duration], e.g. /mute bob 30m",
+	"usage.unmute":     "/unmute <nickname>",
+	"usage.ban":        "/ban <nickname> [reason]",
+	"usage.unban":      "/unban <address>",
+	"usage.filter":     "/filter reload",
+
+	// Command replies
+	"command.usage":                "Usage: %s",
+	"command.not_found":            "unknown command: %s",
+	"command.invalid_usage":        "invalid %s command usage",
+	"command.invalid_usage_reason": "invalid %s command usage: %s",
+	"read.error":                   "Error reading message: %v",
+	"user.not_found":               "no such user: %s",
+	"room.no_such_user":            "no such user in %s: %s",
+	"away.on":                      "You are now marked as away",
+	"away.on_reason":               "You are now marked as away: %s",
+	"away.not":                     "You are not marked as away",
+	"away.off":                     "You are no longer marked as away",
+	"away.notice":                  "%s is away",
+	"away.notice_reason":           "%s is away: %s",
+	"muted.remaining":              "You are muted for another %s",
+	"msg.self":                     "You cannot send a private message to yourself",
+	"nick.same":                    "You are already known as %s",
+	"room.already_in":              "You are already in %s",
+	"room.full":                    "%s is full",
+	"room.now_in":                  "You are now in %s",
+	"topic.none":                   "No topic is set",
+	"setmaxlen.show":               "Maximum message length in %s: %d characters",
+	"color.is_on":                  "Color is on. Usage: /color on|off",
+	"color.is_off":                 "Color is off. Usage: /color on|off",
+	"color.enabled":                "Color enabled",
+	"color.disabled":               "Color disabled",
+	"markdown.is_on":               "Markdown is on. Usage: /markdown on|off",
+	"markdown.is_off":              "Markdown is off. Usage: /markdown on|off",
+	"markdown.enabled":             "Markdown enabled: *bold* and _italic_ are shown styled",
+	"markdown.disabled":            "Markdown disabled: messages are shown as typed",
+	"clear.no_ansi":                "/clear needs ANSI support; turn it on with /color on",
+	"tz.show":                      "Your time zone is %s. Usage: /tz <zone>, e.g. /tz Europe/Berlin",
+	"tz.set":                       "Time zone set to %s",
+	"tz.unknown":                   "unknown time zone %q (use an IANA name such as Europe/Berlin or UTC)",
+	"timeformat.show":              "Your time format is %s. Usage: /timeformat <format>, one of: %s",
+	"timeformat.set":               "Time format set to %s, e.g. %s",
+	"timeformat.unknown":           "unknown time format %q (available: %s)",
+	"emoji.enabled":                "Emoji shortcodes will be expanded",
+	"emoji.disabled":               "Emoji shortcodes will be sent as typed",
+	"uptime.unavailable":           "uptime is not available on this server",
+	"uptime.show":                  "Server uptime: %s",
+	"stats.unavailable":            "statistics are not available on this server",
+	"ping.pong":                    "Pong! Server processing time: %s",
+	"history.none":                 "No recent messages in %s",
+	"history.title":                "Last %d messages:",
+	"export.disabled":              "exports are not enabled on this server",
+	"export.empty":                 "%s has no history to export",
+	"export.failed":                "export failed",
+	"dice.invalid":                 "invalid dice %q",
+	"dice.count":                   "you can roll between 1 and %d dice",
+	"dice.sides":                   "dice must have between 2 and %d sides",
+	"dice.roll":                    "rolls %dd%d: %d",
+	"dice.rolls":                   "rolls %dd%d: %s (total %d)",
+	"action.slap":                  "slaps %s around a bit with a large trout",
+	"action.hug":                   "hugs %s",
+	"export.done":                  "Exported %d messages to %s",
+
+	// /dnd
+	"dnd.on":     "Do not disturb is on: private messages to you are turned away, and their senders are told",
+	"dnd.off":    "Do not disturb is off",
+	"dnd.notice": "%s is not taking private messages right now (do not disturb)",
+
+	// /mycolor and /color
+	"nickcolor.default":    "the one picked from your name",
+	"nickcolor.show":       "Your nickname color is %s. Colors: %s, #RRGGBB or 0-255",
+	"nickcolor.disabled":   "nickname colors are turned off on this server",
+	"nickcolor.unknown":    "unknown color %q: use %s, a hex color such as #FF8800, or a number from 0 to 255",
+	"nickcolor.reset":      "Your nickname is shown in its usual color again",
+	"nickcolor.set":        "Your nickname color is now %s",
+	"nickcolor.user_reset": "%s's nickname is shown in its usual color again",
+	"nickcolor.user_set":   "%s's nickname color is now %s",
+	"nickcolor.reset_by":   "%s reset your nickname color",
+	"nickcolor.set_by":     "%s set your nickname color to %s",
+
+	// /invisible
+	"invisible.on":  "You are now invisible: you're hidden from /who and your joins and leaves aren't announced",
+	"invisible.off": "You are visible again",
+
+	// /ignore and /unignore
+	"ignore.self":    "you cannot ignore yourself",
+	"ignore.added":   "Ignoring %s",
+	"ignore.not":     "you are not ignoring %s",
+	"ignore.removed": "No longer ignoring %s",
+	"ignore.none":    "You are not ignoring anyone",
+	"ignore.list":    "Ignoring: %s",
+
+	// /report
+	"report.self":         "you cannot report yourself",
+	"report.wait":         "you can send another report in %s",
+	"report.no_operators": "no operators are online to receive the report",
+	"report.sent":         "Your report about %s was sent to the operators",
+
+	// Operators
+	"op.already":         "You are already an operator",
+	"op.bad_password":    "invalid operator password",
+	"op.granted":         "You are now an operator",
+	"filter.disabled":    "word filtering is not enabled on this server",
+	"filter.reloaded":    "Word filter reloaded with %d entries",
+	"kick.self":          "you cannot kick yourself",
+	"kick.notice":        "You were kicked by %s",
+	"kick.notice_reason": "You were kicked by %s: %s",
+	"mute.self":          "you cannot mute yourself",
+	"mute.not_duration":  "%s is not a duration",
+	"mute.notice":        "You were muted by %s for %s. You can still read the room",
+	"mute.done":          "%s is muted for %s",
+	"unmute.not_muted":   "%s is not muted",
+	"unmute.notice":      "You were unmuted by %s",
+	"unmute.done":        "%s is no longer muted",
+	"bans.disabled":      "bans are not enabled on this server",
+	"ban.self":           "you cannot ban yourself",
+	"ban.no_source":      "connection source for %s is unknown",
+	"ban.save_failed":    "Warning: ban could not be saved: %v",
+	"ban.notice":         "You were banned by %s",
+	"ban.notice_reason":  "You were banned by %s: %s",
+	"ban.done":           "Banned %s",
+	"unban.save_failed":  "Warning: ban list could not be saved: %v",
+	"unban.not_banned":   "%s is not banned",
+	"unban.done":         "Unbanned %s",
+
+	// Room notices, seen by everyone in the room
+	"presence.joined":      "%s has joined the room",
+	"presence.left":        "%s has left the room",
+	"room.occupancy":       "%s (%d/%d users)",
+	"room.maxlen_set":      "%s set the maximum message length to %d characters",
+	"room.maxlen_reset":    "%s reset the maximum message length to the server default",
+	"topic.changed":        "%s changed the topic to: %s",
+	"topic.cleared":        "%s cleared the topic",
+	"nick.changed":         "%s is now known as %s",
+	"kick.announce":        "%s was kicked by %s",
+	"kick.announce_reason": "%s was kicked by %s: %s",
+	"ban.announce":         "%s was banned by %s",
+	"ban.announce_reason":  "%s was banned by %s: %s",
+}
@@ -0,0 +1,75 @@
+// Package i18n holds translations of the text users see in the chat
+package i18n
+
+import (
+	"fmt"
+	"sort"
+)
+
+// DefaultLanguage is the language used when none is configured, and the one
+// missing translations fall back to
+const DefaultLanguage = "en"
+
+// catalogs holds the translations by language code. Each maps a message ID
+// to a fmt format string.
+var catalogs = map[string]map[string]string{
+	"en": english,
+	"de": german,
+}
+
+// Catalog looks up the text of messages in one language
+type Catalog struct {
+	lang     string
+	messages map[string]string
+}
+
+// New returns the catalog for a language code such as "en" or "de"
+func New(lang string) (*Catalog, error) {
+	if lang == "" {
+		lang = DefaultLanguage
+	}
+	messages, ok := catalogs[lang]
+	if !ok {
+		return nil, fmt.Errorf("unknown language %q (available: %v)", lang, Languages())
+	}
+	return &Catalog{lang: lang, messages: messages}, nil
+}
+
+// Languages returns the codes of all available languages
+func Languages() []string {
+	langs := make([]string, 0, len(catalogs))
+	for lang := range catalogs {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// Language returns the catalog's language code
+func (c *Catalog) Language() string {
+	if c == nil {
+		return DefaultLanguage
+	}
+	return c.lang
+}
+
+// T returns the text of a message formatted with args. Messages missing
+// from the catalog fall back to English, and unknown IDs are returned as is
+// so they are easy to spot. A nil catalog is English.
+func (c *Catalog) T(id string, args ...any) string {
+	format, ok := "", false
+	if c != nil {
+		format, ok = c.messages[id]
+	}
+	if !ok {
+		format, ok = english[id]
+	}
+	if !ok {
+		return id
+	}
+
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
@@ -0,0 +1,115 @@
+// Package metrics exposes server counters in the Prometheus text format.
+//
+// The exposition is written by hand rather than with the Prometheus client
+// library on purpose: a handful of counters and gauges don't justify the
+// dependency tree client_golang brings in. Should histograms or labels be
+// needed, that trade-off is worth revisiting.
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// Chat server metrics
+var (
+	MessagesTotal       = newCounter("ts_chat_messages_total", "Total number of chat messages broadcast to rooms.")
+	RateLimitedTotal    = newCounter("ts_chat_rate_limited_total", "Total number of messages rejected by the rate limiter.")
+	ConnectionsRejected = newCounter("ts_chat_connections_rejected_total", "Total number of connections turned away by the accept rate limiter or the connection limit.")
+	ConnectionsActive   = newGauge("ts_chat_connections_active", "Number of currently open client connections.")
+	Rooms               = newGauge("ts_chat_rooms", "Number of open chat rooms.")
+	SlowClients         = newGauge("ts_chat_slow_clients", "Number of clients whose messages take over a second on average to be written.")
+)
+
+// metric is implemented by every metric type so it can be exported
+type metric interface {
+	write(w http.ResponseWriter)
+}
+
+var (
+	registry   []metric
+	registryMu sync.Mutex
+)
+
+// register adds a metric to the set served by Handler
+func register(m metric) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	registry = append(registry, m)
+}
+
+// Counter is a metric that only ever increases
+type Counter struct {
+	name  string
+	help  string
+	value atomic.Int64
+}
+
+func newCounter(name, help string) *Counter {
+	c := &Counter{name: name, help: help}
+	register(c)
+	return c
+}
+
+// Inc increments the counter by one
+func (c *Counter) Inc() {
+	c.value.Add(1)
+}
+
+// Value returns the current count
+func (c *Counter) Value() int64 {
+	return c.value.Load()
+}
+
+func (c *Counter) write(w http.ResponseWriter) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n%s %d\n", c.name, c.help, c.name, c.name, c.Value())
+}
+
+// Gauge is a metric that can go up and down
+type Gauge struct {
+	name  string
+	help  string
+	value atomic.Int64
+}
+
+func newGauge(name, help string) *Gauge {
+	g := &Gauge{name: name, help: help}
+	register(g)
+	return g
+}
+
+// Inc increments the gauge by one
+func (g *Gauge) Inc() {
+	g.value.Add(1)
+}
+
+// Dec decrements the gauge by one
+func (g *Gauge) Dec() {
+	g.value.Add(-1)
+}
+
+// Value returns the current value
+func (g *Gauge) Value() int64 {
+	return g.value.Load()
+}
+
+func (g *Gauge) write(w http.ResponseWriter) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s %d\n", g.name, g.help, g.name, g.name, g.Value())
+}
+
+// Handler returns an HTTP handler serving all metrics
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+		registryMu.Lock()
+		defer registryMu.Unlock()
+
+		for _, m := range registry {
+			m.write(w)
+		}
+	})
+}
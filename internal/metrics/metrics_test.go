@@ -0,0 +1,101 @@
+package metrics
+
+import (
+	"bufio"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// metricName matches a valid Prometheus metric name
+var metricName = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+
+// TestHandlerExpositionFormat checks that /metrics is valid Prometheus text
+// exposition: every sample is preceded by its HELP and TYPE lines, names
+// are valid and values parse as numbers.
+func TestHandlerExpositionFormat(t *testing.T) {
+	MessagesTotal.Inc()
+	Rooms.Inc()
+	defer Rooms.Dec()
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q, want the text exposition format", ct)
+	}
+
+	help := map[string]bool{}
+	types := map[string]string{}
+	samples := map[string]float64{}
+	scanner := bufio.NewScanner(rec.Body)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		switch {
+		case strings.HasPrefix(text, "# HELP "):
+			name, doc, ok := strings.Cut(strings.TrimPrefix(text, "# HELP "), " ")
+			if !ok || doc == "" {
+				t.Errorf("line %d: HELP without text: %q", line, text)
+			}
+			if help[name] {
+				t.Errorf("line %d: second HELP for %s", line, name)
+			}
+			help[name] = true
+		case strings.HasPrefix(text, "# TYPE "):
+			fields := strings.Fields(strings.TrimPrefix(text, "# TYPE "))
+			if len(fields) != 2 || (fields[1] != "counter" && fields[1] != "gauge") {
+				t.Errorf("line %d: malformed TYPE: %q", line, text)
+				continue
+			}
+			if _, ok := types[fields[0]]; ok {
+				t.Errorf("line %d: second TYPE for %s", line, fields[0])
+			}
+			if !help[fields[0]] {
+				t.Errorf("line %d: TYPE for %s before its HELP", line, fields[0])
+			}
+			types[fields[0]] = fields[1]
+		case strings.HasPrefix(text, "#"):
+			t.Errorf("line %d: unexpected comment: %q", line, text)
+		default:
+			fields := strings.Fields(text)
+			if len(fields) != 2 {
+				t.Errorf("line %d: malformed sample: %q", line, text)
+				continue
+			}
+			name := fields[0]
+			if !metricName.MatchString(name) {
+				t.Errorf("line %d: invalid metric name %q", line, name)
+			}
+			if _, ok := types[name]; !ok {
+				t.Errorf("line %d: sample for %s before its TYPE", line, name)
+			}
+			value, err := strconv.ParseFloat(fields[1], 64)
+			if err != nil {
+				t.Errorf("line %d: invalid value: %q", line, text)
+			}
+			if types[name] == "counter" && !strings.HasSuffix(name, "_total") {
+				t.Errorf("line %d: counter %s should end in _total", line, name)
+			}
+			samples[name] = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	for name := range types {
+		if _, ok := samples[name]; !ok {
+			t.Errorf("%s has no sample", name)
+		}
+	}
+	if len(samples) != len(registry) {
+		t.Errorf("got %d samples, want one for each of the %d metrics", len(samples), len(registry))
+	}
+	if samples["ts_chat_messages_total"] < 1 {
+		t.Errorf("ts_chat_messages_total = %v after an increment", samples["ts_chat_messages_total"])
+	}
+	if samples["ts_chat_rooms"] < 1 {
+		t.Errorf("ts_chat_rooms = %v after an increment", samples["ts_chat_rooms"])
+	}
+}
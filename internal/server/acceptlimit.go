@@ -0,0 +1,57 @@
+package server
+
+import (
+	"sync"
+	"time"
+)
+
+// busyWriteTimeout bounds how long the accept loop spends telling a
+// rejected connection that the server is busy
+const busyWriteTimeout = 100 * time.Millisecond
+
+// acceptLimiter is a token bucket bounding how fast new connections are
+// taken on. Tokens refill at rate per second up to burst, and each accepted
+// connection spends one.
+type acceptLimiter struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	mu     sync.Mutex
+}
+
+// newAcceptLimiter creates a limiter allowing rate connections per second
+// on average and bursts of up to burst connections. It starts full.
+func newAcceptLimiter(rate float64, burst int) *acceptLimiter {
+	return &acceptLimiter{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// allow reports whether a connection may be accepted now, spending a token
+// if so
+func (l *acceptLimiter) allow() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// full reports whether the bucket will have refilled completely by now
+func (l *acceptLimiter) full(now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.tokens+now.Sub(l.last).Seconds()*l.rate >= l.burst
+}
@@ -0,0 +1,41 @@
+package server
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAcceptLimiterBurst(t *testing.T) {
+	limiter := newAcceptLimiter(1, 5)
+
+	accepted := 0
+	for i := 0; i < 100; i++ {
+		if limiter.allow() {
+			accepted++
+		}
+	}
+	if accepted != 5 {
+		t.Errorf("accepted %d of a burst of 100, want 5", accepted)
+	}
+
+	// Two seconds later two more tokens have refilled
+	limiter.mu.Lock()
+	limiter.last = limiter.last.Add(-2 * time.Second)
+	limiter.mu.Unlock()
+	accepted = 0
+	for i := 0; i < 100; i++ {
+		if limiter.allow() {
+			accepted++
+		}
+	}
+	if accepted != 2 {
+		t.Errorf("accepted %d after two seconds at 1/s, want 2", accepted)
+	}
+
+	if limiter.full(time.Now()) {
+		t.Error("limiter reports full right after a burst")
+	}
+	if !limiter.full(time.Now().Add(10 * time.Second)) {
+		t.Error("limiter not full after refilling for longer than the burst")
+	}
+}
@@ -0,0 +1,155 @@
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bscott/ts-chat/internal/chat"
+)
+
+// BanList is a concurrency-safe set of banned connection sources, optionally
+// persisted to a file so bans survive restarts
+type BanList struct {
+	path    string            // File the list is saved to (empty disables persistence)
+	entries map[string]string // Source -> reason
+	mu      sync.RWMutex
+}
+
+// NewBanList creates a ban list, loading existing entries from path if set.
+// A missing file is not an error.
+func NewBanList(path string) (*BanList, error) {
+	b := &BanList{
+		path:    path,
+		entries: make(map[string]string),
+	}
+
+	if _, err := b.Reload(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Reload re-reads the ban file, picking up entries edited by hand, and
+// returns how many bans are in effect. A missing file means no bans; the
+// current list is kept if the file can't be read.
+func (b *BanList) Reload() (int, error) {
+	if b.path == "" {
+		return len(b.List()), nil
+	}
+
+	entries := make(map[string]string)
+	f, err := os.Open(b.path)
+	if err != nil && !os.IsNotExist(err) {
+		return 0, fmt.Errorf("failed to open ban file: %w", err)
+	}
+	if err == nil {
+		defer f.Close()
+
+		// Each line is "<source> [reason]"; blank lines and # comments are skipped
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			fields := strings.SplitN(line, " ", 2)
+			reason := ""
+			if len(fields) > 1 {
+				reason = strings.TrimSpace(fields[1])
+			}
+			entries[fields[0]] = reason
+		}
+		if err := scanner.Err(); err != nil {
+			return 0, fmt.Errorf("failed to read ban file: %w", err)
+		}
+	}
+
+	b.mu.Lock()
+	b.entries = entries
+	b.mu.Unlock()
+
+	return len(entries), nil
+}
+
+// IsBanned reports whether a source is banned
+func (b *BanList) IsBanned(source string) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	_, banned := b.entries[source]
+	return banned
+}
+
+// Ban adds a source to the list
+func (b *BanList) Ban(source, reason string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.entries[source] = reason
+	return b.saveLocked()
+}
+
+// Unban removes a source from the list, reporting whether it was present
+func (b *BanList) Unban(source string) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, exists := b.entries[source]; !exists {
+		return false, nil
+	}
+
+	delete(b.entries, source)
+	return true, b.saveLocked()
+}
+
+// List returns all bans sorted by source
+func (b *BanList) List() []chat.BanEntry {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	list := make([]chat.BanEntry, 0, len(b.entries))
+	for source, reason := range b.entries {
+		list = append(list, chat.BanEntry{Source: source, Reason: reason})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Source < list[j].Source
+	})
+
+	return list
+}
+
+// saveLocked writes the list to disk, replacing the file atomically.
+// b.mu must be held.
+func (b *BanList) saveLocked() error {
+	if b.path == "" {
+		return nil
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(b.path), ".bans-*")
+	if err != nil {
+		return fmt.Errorf("failed to save ban file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	for source, reason := range b.entries {
+		fmt.Fprintf(w, "%s %s\n", source, reason)
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save ban file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save ban file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), b.path); err != nil {
+		return fmt.Errorf("failed to save ban file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package server
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// loadBanner reads a custom banner from file, or returns an empty string
+// for the default banner if file isn't set. The banner is sent to terminals
+// as is, so control characters other than tabs and line breaks are refused.
+func loadBanner(file string) (string, error) {
+	if file == "" {
+		return "", nil
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read banner file: %w", err)
+	}
+	banner := strings.TrimRight(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	for i, line := range strings.Split(banner, "\n") {
+		for _, r := range line {
+			if unicode.IsControl(r) && r != '\t' {
+				return "", fmt.Errorf("banner file %s contains control character %U on line %d", file, r, i+1)
+			}
+		}
+	}
+	if strings.TrimSpace(banner) == "" {
+		return "", fmt.Errorf("banner file %s is empty; use no_banner to show none", file)
+	}
+	// Start on a fresh line like the default banner does
+	return "\n" + banner, nil
+}
@@ -1,10 +1,201 @@
 package server
 
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/bscott/ts-chat/internal/chat"
+	"github.com/bscott/ts-chat/internal/ui"
+	"gopkg.in/yaml.v3"
+)
+
 // Config holds the server configuration
 type Config struct {
-	Port           int    // TCP port to listen on
-	RoomName       string // Chat room name
-	MaxUsers       int    // Maximum allowed users
-	EnableTailscale bool   // Whether to enable Tailscale mode
-	HostName       string // Tailscale hostname (only used if EnableTailscale is true)
-}
\ No newline at end of file
+	Port             int           `yaml:"port"`              // TCP port to listen on
+	RoomName         string        `yaml:"room_name"`         // Chat room name
+	MaxUsers         int           `yaml:"max_users"`         // Maximum allowed users
+	ShowOccupancy    bool          `yaml:"show_occupancy"`    // Include the user count in join and leave notices
+	JoinTemplate     string        `yaml:"join_template"`     // text/template for join notices with .Nickname and .RoomName (empty uses the default)
+	LeaveTemplate    string        `yaml:"leave_template"`    // text/template for leave notices with .Nickname and .RoomName (empty uses the default)
+	MaxQueue         int           `yaml:"max_queue"`         // Users who may wait for a place when the room is full (0 turns them away)
+	MaxPerIP         int           `yaml:"max_per_ip"`        // Connections allowed from one address, or one Tailscale user in Tailscale mode (0 is unlimited)
+	MaxConnections   int           `yaml:"max_connections"`   // Connections allowed across the whole server, whatever room they are in (0 is unlimited)
+	AcceptRate       float64       `yaml:"accept_rate"`       // New connections accepted per second on average (0 is unlimited)
+	AcceptBurst      int           `yaml:"accept_burst"`      // New connections accepted at once before AcceptRate applies
+	GreetRate        float64       `yaml:"greet_rate"`        // Connections from one source greeted per second on average; others are closed silently (0 is unlimited)
+	GreetBurst       int           `yaml:"greet_burst"`       // Connections from one source greeted at once before GreetRate applies
+	EnableTailscale  bool          `yaml:"tailscale"`         // Whether to enable Tailscale mode
+	Listen           []string      `yaml:"listen"`            // Listeners as network:port, e.g. tcp:2323 or tailscale:2323 (empty uses Port and EnableTailscale)
+	BindAddr         string        `yaml:"bind_addr"`         // IP address TCP listeners bind to, e.g. 127.0.0.1 or ::1 (empty binds every interface)
+	HostName         string        `yaml:"hostname"`          // Tailscale hostname (only used if EnableTailscale is true)
+	EnableTLS        bool          `yaml:"tls"`               // Whether to encrypt the chat listener with TLS
+	CertFile         string        `yaml:"tls_cert"`          // TLS certificate file (PEM)
+	KeyFile          string        `yaml:"tls_key"`           // TLS private key file (PEM)
+	HistorySize      int           `yaml:"history_size"`      // Number of recent messages replayed to users joining a room
+	IdleTimeout      time.Duration `yaml:"idle_timeout"`      // Disconnect users idle for this long (0 disables)
+	KeepAlive        time.Duration `yaml:"keepalive"`         // Interval between keepalive probes for dead connections (0 disables)
+	WriteTimeout     time.Duration `yaml:"write_timeout"`     // Disconnect users whose connection accepts no data for this long (0 disables)
+	OutboundQueue    int           `yaml:"outbound_queue"`    // Messages that may wait to be written to each user
+	DisconnectSlow   bool          `yaml:"disconnect_slow"`   // Disconnect users whose outbound queue fills up instead of dropping messages
+	OperatorPassword string        `yaml:"operator_password"` // Password for /op; when empty and Operators is unset the first user to join becomes operator
+	Operators        []string      `yaml:"operators"`         // Tailscale logins granted operator status on connect (Tailscale mode only)
+	BanFile          string        `yaml:"ban_file"`          // File bans are persisted to (empty keeps bans in memory only)
+	ExportDir        string        `yaml:"export_dir"`        // Directory /export writes transcripts to (empty disables /export)
+	FilterFile       string        `yaml:"filter_file"`       // File of banned words and patterns (empty disables filtering)
+	FilterAction     string        `yaml:"filter_action"`     // What to do with messages containing banned words: mask or reject
+	MessageLog       string        `yaml:"message_log"`       // File room messages are logged to (empty disables logging)
+	MessageLogFormat string        `yaml:"message_log_format"` // How MessageLog is written: json lines or a sqlite database
+	MessageLogSystem bool          `yaml:"message_log_system"` // Also log system messages such as join and leave notices
+	SequenceNumbers  bool          `yaml:"sequence_numbers"`  // Number each room's messages, for spotting dropped or reordered ones
+	MetricsPort      int           `yaml:"metrics_port"`      // Port for the Prometheus metrics endpoint (0 disables)
+	HealthPort       int           `yaml:"health_port"`       // Port for the /healthz and /readyz endpoints (0 disables)
+	ControlSocket    string        `yaml:"control_socket"`    // Unix socket path or localhost host:port for the admin control protocol (empty disables)
+	WebSocketPort    int           `yaml:"websocket_port"`    // Port for the JSON WebSocket gateway (0 disables)
+	WebSocketOrigins []string      `yaml:"websocket_origins"` // Extra origins allowed to open WebSocket connections, e.g. chat.example.com
+	ShutdownGrace    time.Duration `yaml:"shutdown_grace"`    // How long to warn users before disconnecting them on shutdown (0 disables)
+	LogLevel         string        `yaml:"log_level"`         // Minimum log level: debug, info, warn or error
+	LogFormat        string        `yaml:"log_format"`        // Log output format: text or json
+	Theme            string        `yaml:"theme"`             // Color theme name, see ui.ThemeNames
+	NoNickColors     bool          `yaml:"no_nick_colors"`    // Show every nickname in the theme's user color instead of one derived from the name
+	Lang             string        `yaml:"lang"`              // Language of the text users see, see i18n.Languages (empty is English)
+	Motd             string        `yaml:"motd"`              // Message of the day shown to users when they connect (empty shows none)
+	MotdFile         string        `yaml:"motd_file"`         // File the message of the day is read from, reloaded on SIGHUP (instead of Motd)
+	BannerFile       string        `yaml:"banner_file"`       // File with ASCII art shown instead of the default banner
+	NoBanner         bool          `yaml:"no_banner"`         // Skip the banner and start with the welcome message
+	Timezone         string        `yaml:"timezone"`          // IANA time zone for message timestamps, e.g. UTC (empty uses the server's local time)
+	NoColor          bool          `yaml:"no_color"`          // Send plain text to clients by default
+	MaxMessageLength int           `yaml:"max_message_length"` // Maximum message length in characters
+	RateLimit        int           `yaml:"rate_limit"`        // Maximum messages per user per rate limit window
+	RateLimitWindow  time.Duration `yaml:"rate_limit_window"` // Time window for rate limiting
+	OperatorRateLimit int          `yaml:"operator_rate_limit"` // Maximum messages per rate limit window for operators (0 exempts them)
+	FloodViolations  int           `yaml:"flood_violations"`  // Rate limit violations within FloodWindow that mute a user (0 disables)
+	FloodWindow      time.Duration `yaml:"flood_window"`      // Time window for counting rate limit violations
+	FloodMute        time.Duration `yaml:"flood_mute"`        // How long a flooding user is muted
+	Bots             []string      `yaml:"bots"`              // Bots to run in every room, see bots.Names
+	TopicLogOperators bool         `yaml:"topic_log_operators"` // Only let operators see who changed a room's topic with /topiclog
+	Macros           map[string]string `yaml:"macros"`        // Text macros such as /tableflip by name, added to or replacing the built-in ones
+	NicknameMinLength int          `yaml:"nickname_min_length"` // Minimum nickname length in characters
+	NicknameMaxLength int          `yaml:"nickname_max_length"` // Maximum nickname length in characters
+	NicknameSymbols   string       `yaml:"nickname_symbols"`    // Characters allowed in nicknames besides letters and digits
+	ReservedNicknames []string     `yaml:"reserved_nicknames"`  // Nicknames nobody may use ("System" is always reserved)
+	AutoRenameOnCollision bool     `yaml:"nickname_auto_rename"` // Assign "name2", "name3", ... when a nickname is taken instead of asking again
+	ResumeWindow      time.Duration `yaml:"resume_window"`      // How long users who lose their connection can resume their session with a token (0 disables)
+	Build             chat.BuildInfo `yaml:"-"`                 // Build details reported by /version, set by main
+}
+
+// Validate checks that the configuration values are usable
+func (c Config) Validate() error {
+	if c.MaxUsers <= 0 {
+		return fmt.Errorf("max users must be greater than 0, got %d", c.MaxUsers)
+	}
+	if c.WriteTimeout < 0 {
+		return fmt.Errorf("write timeout must not be negative, got %s", c.WriteTimeout)
+	}
+	if c.OutboundQueue <= 0 {
+		return fmt.Errorf("outbound queue must be greater than 0, got %d", c.OutboundQueue)
+	}
+	if c.MaxQueue < 0 {
+		return fmt.Errorf("max queue must not be negative, got %d", c.MaxQueue)
+	}
+	if c.Motd != "" && c.MotdFile != "" {
+		return fmt.Errorf("motd and motd file cannot both be set")
+	}
+	if c.NoBanner && c.BannerFile != "" {
+		return fmt.Errorf("banner file cannot be set when the banner is disabled")
+	}
+	if c.ResumeWindow < 0 {
+		return fmt.Errorf("resume window must not be negative, got %s", c.ResumeWindow)
+	}
+	if c.MaxPerIP < 0 {
+		return fmt.Errorf("max connections per IP must not be negative, got %d", c.MaxPerIP)
+	}
+	if c.MaxConnections < 0 {
+		return fmt.Errorf("max connections must not be negative, got %d", c.MaxConnections)
+	}
+	if c.AcceptRate < 0 {
+		return fmt.Errorf("accept rate must not be negative, got %g", c.AcceptRate)
+	}
+	if c.AcceptRate > 0 && c.AcceptBurst <= 0 {
+		return fmt.Errorf("accept burst must be greater than 0 when an accept rate is set, got %d", c.AcceptBurst)
+	}
+	if c.GreetRate < 0 {
+		return fmt.Errorf("greet rate must not be negative, got %g", c.GreetRate)
+	}
+	if c.GreetRate > 0 && c.GreetBurst <= 0 {
+		return fmt.Errorf("greet burst must be greater than 0 when a greet rate is set, got %d", c.GreetBurst)
+	}
+	if c.MaxMessageLength <= 0 {
+		return fmt.Errorf("max message length must be greater than 0, got %d", c.MaxMessageLength)
+	}
+	if c.RateLimit <= 0 {
+		return fmt.Errorf("rate limit must be greater than 0, got %d", c.RateLimit)
+	}
+	if c.RateLimitWindow <= 0 {
+		return fmt.Errorf("rate limit window must be greater than 0, got %s", c.RateLimitWindow)
+	}
+	if c.OperatorRateLimit < 0 {
+		return fmt.Errorf("operator rate limit cannot be negative, got %d", c.OperatorRateLimit)
+	}
+	if c.FloodViolations < 0 {
+		return fmt.Errorf("flood violations cannot be negative, got %d", c.FloodViolations)
+	}
+	if c.FloodViolations > 0 && (c.FloodWindow <= 0 || c.FloodMute <= 0) {
+		return fmt.Errorf("flood window and flood mute must be greater than 0 when flood violations is set")
+	}
+	if c.NicknameMinLength < 1 {
+		return fmt.Errorf("nickname min length must be at least 1, got %d", c.NicknameMinLength)
+	}
+	if c.NicknameMaxLength < c.NicknameMinLength {
+		return fmt.Errorf("nickname max length (%d) must not be less than min length (%d)", c.NicknameMaxLength, c.NicknameMinLength)
+	}
+	if c.NicknameMaxLength > ui.MaxNicknameWidth {
+		return fmt.Errorf("nickname max length must be at most %d, got %d", ui.MaxNicknameWidth, c.NicknameMaxLength)
+	}
+	for name, text := range c.Macros {
+		if err := chat.ValidateMacro(name, text); err != nil {
+			return err
+		}
+	}
+	if c.FilterAction != FilterMask && c.FilterAction != FilterReject {
+		return fmt.Errorf("invalid filter action %q (expected %s or %s)", c.FilterAction, FilterMask, FilterReject)
+	}
+	if c.MessageLogFormat != MessageLogJSON && c.MessageLogFormat != MessageLogSQLite {
+		return fmt.Errorf("invalid message log format %q (expected %s or %s)", c.MessageLogFormat, MessageLogJSON, MessageLogSQLite)
+	}
+	if _, err := c.ListenSpecs(); err != nil {
+		return err
+	}
+	if _, err := c.BindHost(); err != nil {
+		return err
+	}
+	if c.ControlSocket != "" {
+		if _, _, err := c.controlAddr(); err != nil {
+			return err
+		}
+	}
+	if c.EnableTLS && (c.CertFile == "" || c.KeyFile == "") {
+		return fmt.Errorf("TLS requires both a certificate file and a key file")
+	}
+	return nil
+}
+
+// LoadConfig reads a YAML config file into cfg. Fields missing from the file
+// keep their existing values, so cfg should be populated with defaults first.
+func LoadConfig(path string, cfg *Config) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open config file: %w", err)
+	}
+	defer f.Close()
+
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,257 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bscott/ts-chat/internal/chat"
+	"github.com/bscott/ts-chat/internal/ui"
+)
+
+// controlHelp lists the commands the control socket understands
+const controlHelp = `help                       Show this list
+list                       List connected users
+kick <nickname> [reason]   Disconnect a user
+broadcast <message>        Send an announcement to every room
+stats                      Show server statistics
+shutdown                   Stop the server
+quit                       Close this connection`
+
+// controlAddr returns where the control socket listens: a TCP address if
+// ControlSocket is a host:port, otherwise a Unix socket path
+func (c Config) controlAddr() (network, address string, err error) {
+	host, port, err := net.SplitHostPort(c.ControlSocket)
+	if err != nil || strings.Contains(c.ControlSocket, "/") {
+		return "unix", c.ControlSocket, nil
+	}
+
+	// Anyone who can reach the socket can administer the server, so TCP is
+	// limited to this machine
+	if host != "localhost" {
+		ip := net.ParseIP(host)
+		if ip == nil || !ip.IsLoopback() {
+			return "", "", fmt.Errorf("control socket %q must listen on localhost or a loopback address", c.ControlSocket)
+		}
+	}
+	return "tcp", net.JoinHostPort(host, port), nil
+}
+
+// startControl opens the control socket. A Unix socket is only accessible
+// to the user running the server.
+func (s *Server) startControl() error {
+	network, address, err := s.config.controlAddr()
+	if err != nil {
+		return err
+	}
+
+	var ln net.Listener
+	if network == "unix" {
+		ln, err = listenPrivateUnix(address)
+	} else {
+		ln, err = net.Listen(network, address)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open control socket %s: %w", address, err)
+	}
+	s.controlListener = ln
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				if s.ctx.Err() == nil && !errors.Is(err, net.ErrClosed) {
+					s.logger.Error("Control socket error", "error", err)
+				}
+				return
+			}
+			s.wg.Add(1)
+			go s.serveControl(conn)
+		}
+	}()
+
+	s.logger.Info("Serving control socket", "address", address)
+	return nil
+}
+
+// privateUnixListener is a Unix socket listener that removes its socket,
+// which was moved into place after it was created, when it's closed
+type privateUnixListener struct {
+	*net.UnixListener
+	path string
+}
+
+func (l *privateUnixListener) Close() error {
+	err := l.UnixListener.Close()
+	os.Remove(l.path)
+	return err
+}
+
+// listenPrivateUnix listens on a Unix socket at path that only the user
+// running the server can connect to. The socket is created in a private
+// directory and only moved to path once its mode is set, so there is never
+// a moment when anyone else could connect.
+func listenPrivateUnix(path string) (net.Listener, error) {
+	if err := removeStaleSocket(path); err != nil {
+		return nil, err
+	}
+
+	dir, err := os.MkdirTemp(filepath.Dir(path), ".ts-chat-control-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	tmp := filepath.Join(dir, "control.sock")
+	ln, err := net.ListenUnix("unix", &net.UnixAddr{Name: tmp, Net: "unix"})
+	if err != nil {
+		return nil, err
+	}
+	// The socket won't be at tmp by the time the listener is closed
+	ln.SetUnlinkOnClose(false)
+	if err := os.Chmod(tmp, 0o600); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	return &privateUnixListener{UnixListener: ln, path: path}, nil
+}
+
+// removeStaleSocket removes a socket at path left behind by a server that
+// didn't shut down cleanly. It refuses to remove anything else, including a
+// socket another server is still listening on.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%s exists and is not a socket", path)
+	}
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		conn.Close()
+		return fmt.Errorf("%s is in use by another server", path)
+	}
+	return os.Remove(path)
+}
+
+// serveControl runs control commands from conn, one per line. Each reply
+// ends with a line of "OK" or "ERR <reason>".
+func (s *Server) serveControl(conn net.Conn) {
+	defer s.wg.Done()
+	defer conn.Close()
+
+	// Don't keep shutdown waiting on an idle admin connection
+	stop := context.AfterFunc(s.ctx, func() { conn.Close() })
+	defer stop()
+
+	// Unix socket peers have no address of their own
+	peer := conn.RemoteAddr().String()
+	if conn.RemoteAddr().Network() == "unix" {
+		peer = conn.LocalAddr().String()
+	}
+	logger := s.logger.With("control", peer)
+	logger.Info("Control connection opened")
+	defer logger.Info("Control connection closed")
+
+	w := bufio.NewWriter(conn)
+	fmt.Fprintf(w, "ts-chat %s control socket; type help for commands\n", s.config.Build.Version)
+	w.Flush()
+
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		name, args, _ := strings.Cut(line, " ")
+		name = strings.ToLower(name)
+		if name == "quit" {
+			return
+		}
+
+		logger.Info("Control command", "command", name)
+		if err := s.runControl(w, name, strings.TrimSpace(args)); err != nil {
+			fmt.Fprintf(w, "ERR %v\n", err)
+		} else {
+			fmt.Fprintln(w, "OK")
+		}
+		if err := w.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// runControl runs one control command, writing its output to w
+func (s *Server) runControl(w io.Writer, name, args string) error {
+	switch name {
+	case "help":
+		fmt.Fprintln(w, controlHelp)
+	case "list":
+		now := time.Now()
+		for _, room := range s.rooms.Rooms() {
+			for _, user := range room.GetUserList(true) {
+				fmt.Fprintf(w, "%s room=%q source=%s idle=%s", user.Nickname, room.Name, user.Source, ui.FormatDuration(now.Sub(user.LastActive)))
+				if user.Away {
+					fmt.Fprint(w, " away")
+				}
+				if user.Invisible {
+					fmt.Fprint(w, " invisible")
+				}
+				fmt.Fprintln(w)
+			}
+		}
+	case "kick":
+		nickname, reason, _ := strings.Cut(args, " ")
+		if nickname == "" {
+			return fmt.Errorf("usage: kick <nickname> [reason]")
+		}
+		if err := s.rooms.Kick(nickname, "an administrator", strings.TrimSpace(reason)); err != nil {
+			return err
+		}
+		s.logger.Info("User kicked over the control socket", "target", nickname, "reason", reason)
+	case "broadcast":
+		if args == "" {
+			return fmt.Errorf("usage: broadcast <message>")
+		}
+		s.rooms.Broadcast(chat.Message{
+			From:           "System",
+			Content:        args,
+			Timestamp:      time.Now(),
+			IsSystem:       true,
+			IsAnnouncement: true,
+		})
+	case "stats":
+		stats := s.Stats()
+		fmt.Fprintf(w, "uptime=%s users=%d peak_users=%d rooms=%d messages=%d\n",
+			ui.FormatDuration(stats.Uptime), stats.Users, stats.PeakUsers, stats.Rooms, stats.Messages)
+	case "shutdown":
+		s.logger.Info("Shutdown requested over the control socket")
+		s.shutdownOnce.Do(func() { close(s.shutdown) })
+	default:
+		return fmt.Errorf("unknown command %q; type help for commands", name)
+	}
+	return nil
+}
+
+// ShutdownRequested is closed when an administrator asks for the server to
+// stop over the control socket. The caller is expected to call Stop.
+func (s *Server) ShutdownRequested() <-chan struct{} {
+	return s.shutdown
+}
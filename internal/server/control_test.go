@@ -0,0 +1,75 @@
+package server
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestListenPrivateUnix(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "control.sock")
+
+	ln, err := listenPrivateUnix(path)
+	if err != nil {
+		t.Fatalf("listening: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat: %v", err)
+	}
+	if mode := info.Mode().Perm(); mode != 0o600 {
+		t.Errorf("socket mode = %v, want 0600", mode)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("directory holds %d entries, want just the socket", len(entries))
+	}
+
+	// A socket that is still being listened on is left alone
+	if _, err := listenPrivateUnix(path); err == nil {
+		t.Error("took over a socket in use")
+	}
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatalf("dialing the original listener: %v", err)
+	}
+	conn.Close()
+
+	if err := ln.Close(); err != nil {
+		t.Fatalf("closing: %v", err)
+	}
+	if _, err := os.Lstat(path); !os.IsNotExist(err) {
+		t.Errorf("socket still exists after close: %v", err)
+	}
+}
+
+func TestListenPrivateUnixStalePath(t *testing.T) {
+	dir := t.TempDir()
+
+	// A socket nobody listens on any more is replaced
+	stale := filepath.Join(dir, "stale.sock")
+	old, err := net.ListenUnix("unix", &net.UnixAddr{Name: stale, Net: "unix"})
+	if err != nil {
+		t.Fatalf("listening: %v", err)
+	}
+	old.SetUnlinkOnClose(false)
+	old.Close()
+	ln, err := listenPrivateUnix(stale)
+	if err != nil {
+		t.Fatalf("replacing a stale socket: %v", err)
+	}
+	ln.Close()
+
+	// Anything else at the path is never removed
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, []byte("keep"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := listenPrivateUnix(file); err == nil {
+		t.Error("listened in place of a regular file")
+	}
+	if data, err := os.ReadFile(file); err != nil || string(data) != "keep" {
+		t.Errorf("regular file was changed: %q, %v", data, err)
+	}
+}
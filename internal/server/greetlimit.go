@@ -0,0 +1,57 @@
+package server
+
+import (
+	"sync"
+	"time"
+)
+
+// greetPruneSize is how many sources the greeting limiter tracks before it
+// starts forgetting ones whose buckets have refilled
+const greetPruneSize = 1024
+
+// greetLimiter bounds how fast each source is sent the greeting and
+// nickname prompt, so port scanners that connect over and over get nothing
+// for their trouble. Each source has its own token bucket.
+type greetLimiter struct {
+	rate    float64
+	burst   int
+	sources map[string]*acceptLimiter
+	mu      sync.Mutex
+}
+
+// newGreetLimiter creates a limiter allowing rate greetings per second per
+// source on average and bursts of up to burst
+func newGreetLimiter(rate float64, burst int) *greetLimiter {
+	return &greetLimiter{
+		rate:    rate,
+		burst:   burst,
+		sources: make(map[string]*acceptLimiter),
+	}
+}
+
+// allow reports whether source may be greeted now
+func (l *greetLimiter) allow(source string) bool {
+	l.mu.Lock()
+	bucket, ok := l.sources[source]
+	if !ok {
+		if len(l.sources) >= greetPruneSize {
+			l.pruneLocked()
+		}
+		bucket = newAcceptLimiter(l.rate, l.burst)
+		l.sources[source] = bucket
+	}
+	l.mu.Unlock()
+
+	return bucket.allow()
+}
+
+// pruneLocked forgets sources whose buckets are full again, since a new
+// bucket would behave the same. l.mu must be held.
+func (l *greetLimiter) pruneLocked() {
+	now := time.Now()
+	for source, bucket := range l.sources {
+		if bucket.full(now) {
+			delete(l.sources, source)
+		}
+	}
+}
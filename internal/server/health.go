@@ -0,0 +1,57 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// startHealth serves liveness and readiness probes over HTTP on the
+// configured port
+func (s *Server) startHealth() error {
+	ln, err := net.Listen("tcp", fmt.Sprintf(":%d", s.config.HealthPort))
+	if err != nil {
+		return fmt.Errorf("failed to listen for health checks on port %d: %w", s.config.HealthPort, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", s.handleHealthz)
+	mux.HandleFunc("/readyz", s.handleReadyz)
+	s.healthServer = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		if err := s.healthServer.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Error("Health server error", "error", err)
+		}
+	}()
+
+	s.logger.Info("Serving health checks", "port", s.config.HealthPort)
+	return nil
+}
+
+// handleHealthz reports whether the chat listeners are up and the lobby's
+// room loop is running
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	if len(s.listeners) == 0 || s.ctx.Err() != nil || !s.rooms.Lobby().Running() {
+		http.Error(w, "unhealthy", http.StatusServiceUnavailable)
+		return
+	}
+	fmt.Fprintln(w, "ok")
+}
+
+// handleReadyz reports whether the server is accepting new users. It fails
+// as soon as shutdown begins, while users are still being warned.
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	if !s.ready.Load() {
+		http.Error(w, "not ready", http.StatusServiceUnavailable)
+		return
+	}
+	fmt.Fprintln(w, "ready")
+}
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	"tailscale.com/client/tailscale/apitype"
+)
+
+// whoIsTimeout bounds how long a new connection waits for its Tailscale
+// identity to be resolved
+const whoIsTimeout = 5 * time.Second
+
+// peerIdentity is the Tailscale user and machine behind a connection
+type peerIdentity struct {
+	LoginName string // e.g. alice@example.com
+	Node      string // Machine name, e.g. alices-laptop
+}
+
+// String formats the identity for display, e.g. "alice@example.com (alices-laptop)"
+func (p peerIdentity) String() string {
+	if p.Node == "" {
+		return p.LoginName
+	}
+	return p.LoginName + " (" + p.Node + ")"
+}
+
+// isOperator reports whether the identity is in the operator allowlist.
+// Login names are compared case-insensitively.
+func (p peerIdentity) isOperator(operators []string) bool {
+	for _, login := range operators {
+		if strings.EqualFold(login, p.LoginName) {
+			return true
+		}
+	}
+	return false
+}
+
+// whoIs asks Tailscale who is connecting from addr. It reports false without
+// a Tailscale node or when the lookup fails, in which case callers fall back
+// to the remote address.
+func (s *Server) whoIs(addr net.Addr) (peerIdentity, bool) {
+	if s.tsServer == nil {
+		return peerIdentity{}, false
+	}
+
+	lc, err := s.tsServer.LocalClient()
+	if err != nil {
+		s.logger.Warn("Tailscale identity lookup unavailable", "error", err)
+		return peerIdentity{}, false
+	}
+
+	ctx, cancel := context.WithTimeout(s.ctx, whoIsTimeout)
+	defer cancel()
+
+	id, ok, err := lookupIdentity(ctx, lc, addr.String())
+	if err != nil {
+		s.logger.Warn("Failed to look up Tailscale identity", "remote", addr.String(), "error", err)
+	}
+	return id, ok
+}
+
+// whoIsProvider resolves the Tailscale identity behind a remote address.
+// It is satisfied by Tailscale's local client.
+type whoIsProvider interface {
+	WhoIs(ctx context.Context, remoteAddr string) (*apitype.WhoIsResponse, error)
+}
+
+// lookupIdentity asks provider who is connecting from remoteAddr. It
+// reports false if the lookup fails or the peer has no login name.
+func lookupIdentity(ctx context.Context, provider whoIsProvider, remoteAddr string) (peerIdentity, bool, error) {
+	who, err := provider.WhoIs(ctx, remoteAddr)
+	if err != nil {
+		return peerIdentity{}, false, err
+	}
+	if who == nil || who.UserProfile == nil || who.UserProfile.LoginName == "" {
+		return peerIdentity{}, false, nil
+	}
+
+	id := peerIdentity{LoginName: who.UserProfile.LoginName}
+	if who.Node != nil {
+		id.Node = who.Node.ComputedName
+	}
+	return id, true, nil
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"tailscale.com/client/tailscale/apitype"
+	"tailscale.com/tailcfg"
+)
+
+// fakeWhoIs resolves addresses from a fixed table of login names
+type fakeWhoIs map[string]string
+
+func (f fakeWhoIs) WhoIs(ctx context.Context, remoteAddr string) (*apitype.WhoIsResponse, error) {
+	login, ok := f[remoteAddr]
+	if !ok {
+		return nil, errors.New("no such peer")
+	}
+	return &apitype.WhoIsResponse{
+		UserProfile: &tailcfg.UserProfile{LoginName: login},
+		Node:        &tailcfg.Node{ComputedName: "laptop"},
+	}, nil
+}
+
+func TestOperatorAllowlist(t *testing.T) {
+	provider := fakeWhoIs{
+		"100.64.0.1:1000": "alice@example.com",
+		"100.64.0.2:1000": "Bob@Example.com",
+		"100.64.0.3:1000": "mallory@example.com",
+	}
+	operators := []string{"alice@example.com", "bob@example.com"}
+
+	tests := []struct {
+		addr     string
+		found    bool
+		operator bool
+	}{
+		{"100.64.0.1:1000", true, true},
+		{"100.64.0.2:1000", true, true}, // Logins match case-insensitively
+		{"100.64.0.3:1000", true, false},
+		{"100.64.0.4:1000", false, false}, // Unknown peers are never operators
+	}
+	for _, tt := range tests {
+		id, ok, err := lookupIdentity(context.Background(), provider, tt.addr)
+		if ok != tt.found {
+			t.Errorf("%s: found = %v (error %v), want %v", tt.addr, ok, err, tt.found)
+			continue
+		}
+		if got := ok && id.isOperator(operators); got != tt.operator {
+			t.Errorf("%s (%s): operator = %v, want %v", tt.addr, id, got, tt.operator)
+		}
+	}
+
+	id, _, _ := lookupIdentity(context.Background(), provider, "100.64.0.1:1000")
+	if got, want := id.String(), "alice@example.com (laptop)"; got != want {
+		t.Errorf("identity = %q, want %q", got, want)
+	}
+}
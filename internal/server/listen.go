@@ -0,0 +1,128 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// Networks a chat listener can accept connections on
+const (
+	NetworkTCP       = "tcp"       // The host's own network interfaces
+	NetworkTailscale = "tailscale" // The tailnet, through an embedded Tailscale node
+)
+
+// ListenSpec is a port chat connections are accepted on
+type ListenSpec struct {
+	Network string // NetworkTCP or NetworkTailscale
+	Port    int
+}
+
+// String formats the spec the way ParseListenSpec reads it, e.g. "tcp:2323"
+func (l ListenSpec) String() string {
+	return l.Network + ":" + strconv.Itoa(l.Port)
+}
+
+// ParseListenSpec parses a listener written as network:port, e.g.
+// "tailscale:2323". A bare port listens on TCP.
+func ParseListenSpec(spec string) (ListenSpec, error) {
+	network, port, found := strings.Cut(spec, ":")
+	if !found {
+		network, port = NetworkTCP, spec
+	}
+	if network != NetworkTCP && network != NetworkTailscale {
+		return ListenSpec{}, fmt.Errorf("invalid listener %q: network must be %s or %s", spec, NetworkTCP, NetworkTailscale)
+	}
+	n, err := strconv.Atoi(port)
+	if err != nil || n < 1 || n > 65535 {
+		return ListenSpec{}, fmt.Errorf("invalid listener %q: port must be between 1 and 65535", spec)
+	}
+	return ListenSpec{Network: network, Port: n}, nil
+}
+
+// ListenSpecs returns the listeners to open: those in Listen, or else the
+// single one given by Port and EnableTailscale
+func (c Config) ListenSpecs() ([]ListenSpec, error) {
+	if len(c.Listen) == 0 {
+		network := NetworkTCP
+		if c.EnableTailscale {
+			network = NetworkTailscale
+		}
+		return []ListenSpec{{Network: network, Port: c.Port}}, nil
+	}
+
+	specs := make([]ListenSpec, 0, len(c.Listen))
+	seen := make(map[ListenSpec]bool)
+	for _, s := range c.Listen {
+		spec, err := ParseListenSpec(s)
+		if err != nil {
+			return nil, err
+		}
+		if seen[spec] {
+			return nil, fmt.Errorf("listener %s is given more than once", spec)
+		}
+		seen[spec] = true
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+// BindHost returns the address TCP listeners bind to, without brackets, or
+// an empty string for every interface. BindAddr may be written with or
+// without brackets, e.g. "::1" or "[::1]".
+func (c Config) BindHost() (string, error) {
+	if c.BindAddr == "" {
+		return "", nil
+	}
+	addr, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(c.BindAddr, "["), "]"))
+	if err != nil {
+		return "", fmt.Errorf("invalid bind address %q: must be an IP address such as 127.0.0.1 or ::1", c.BindAddr)
+	}
+	return addr.String(), nil
+}
+
+// usesTailscale reports whether any listener is on the tailnet
+func usesTailscale(specs []ListenSpec) bool {
+	for _, spec := range specs {
+		if spec.Network == NetworkTailscale {
+			return true
+		}
+	}
+	return false
+}
+
+// chatListener accepts chat connections for one ListenSpec
+type chatListener struct {
+	net.Listener
+	spec ListenSpec
+}
+
+// openListener starts listening for spec. The Tailscale node must already be
+// set up if spec is on the tailnet.
+func (s *Server) openListener(spec ListenSpec) (*chatListener, error) {
+	var ln net.Listener
+	var err error
+	if spec.Network == NetworkTailscale {
+		ln, err = s.tsServer.Listen("tcp", fmt.Sprintf(":%d", spec.Port))
+		if err != nil {
+			return nil, fmt.Errorf("failed to start Tailscale server on port %d: %w", spec.Port, err)
+		}
+	} else {
+		ln, err = s.listenTCP(spec.Port)
+		if err != nil {
+			return nil, fmt.Errorf("failed to listen on port %d: %w", spec.Port, err)
+		}
+	}
+	return &chatListener{Listener: ln, spec: spec}, nil
+}
+
+// listenTCP opens a TCP listener on port, on the bind address if one is set
+func (s *Server) listenTCP(port int) (net.Listener, error) {
+	host, err := s.config.BindHost()
+	if err != nil {
+		return nil, err
+	}
+	return net.Listen("tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+}
@@ -0,0 +1,278 @@
+package server
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/bscott/ts-chat/internal/chat"
+	_ "modernc.org/sqlite" // Registers the "sqlite" database/sql driver
+)
+
+// Formats a message log can be written in
+const (
+	MessageLogJSON   = "json"   // Newline-delimited JSON appended to a file
+	MessageLogSQLite = "sqlite" // Rows in a SQLite database's messages table
+)
+
+// messageLogQueue is how many messages may wait to be written before new
+// ones are dropped
+const messageLogQueue = 1024
+
+// messageLogEntry is one line of the message log
+type messageLogEntry struct {
+	Timestamp time.Time `json:"timestamp"`
+	Room      string    `json:"room"`
+	From      string    `json:"from"`
+	Content   string    `json:"content"`
+	System    bool      `json:"system,omitempty"`
+	Action    bool      `json:"action,omitempty"`
+	Bot       bool      `json:"bot,omitempty"`
+	Seq       uint64    `json:"seq,omitempty"`
+}
+
+// messageLogCloser is a message logger the server closes on shutdown
+type messageLogCloser interface {
+	chat.MessageLogger
+	Close() error
+}
+
+// NewMessageLogger opens a message log at path in the given format, one of
+// MessageLogJSON or MessageLogSQLite
+func NewMessageLogger(format, path string, logger *slog.Logger) (messageLogCloser, error) {
+	switch format {
+	case MessageLogJSON:
+		return NewFileMessageLogger(path, logger)
+	case MessageLogSQLite:
+		return NewSQLiteMessageLogger(path, logger)
+	}
+	return nil, fmt.Errorf("invalid message log format %q (expected %s or %s)", format, MessageLogJSON, MessageLogSQLite)
+}
+
+// messageQueue hands messages to a background goroutine so rooms never wait
+// on the disk; if the queue fills up, messages are dropped and counted. It
+// is shared by the message loggers, which supply the writing.
+type messageQueue struct {
+	entries chan messageLogEntry
+	logger  *slog.Logger
+	dropped int // Protected by mu
+	closed  bool
+	done    chan struct{}
+	mu      sync.Mutex
+}
+
+// newMessageQueue creates an empty queue. Its goroutine is started by run.
+func newMessageQueue(logger *slog.Logger) *messageQueue {
+	return &messageQueue{
+		entries: make(chan messageLogEntry, messageLogQueue),
+		logger:  logger,
+		done:    make(chan struct{}),
+	}
+}
+
+// LogMessage queues a message to be written. It never blocks.
+func (q *messageQueue) LogMessage(room string, msg chat.Message) {
+	entry := messageLogEntry{
+		Timestamp: msg.Timestamp,
+		Room:      room,
+		From:      msg.From,
+		Content:   msg.Content,
+		System:    msg.IsSystem,
+		Action:    msg.IsAction,
+		Bot:       msg.IsBot,
+		Seq:       msg.Seq,
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return
+	}
+	select {
+	case q.entries <- entry:
+	default:
+		q.dropped++
+	}
+}
+
+// run writes queued messages with write until the queue is closed, calling
+// flush whenever it runs empty. After the first failure errors are no
+// longer logged, so a full disk doesn't flood the log.
+func (q *messageQueue) run(write func(messageLogEntry) error, flush func() error) {
+	defer close(q.done)
+
+	failed := false
+	for entry := range q.entries {
+		if err := write(entry); err != nil && !failed {
+			q.logger.Error("Failed to write message log", "error", err)
+			failed = true
+		}
+		if len(q.entries) > 0 {
+			continue
+		}
+
+		if err := flush(); err != nil && !failed {
+			q.logger.Error("Failed to write message log", "error", err)
+			failed = true
+		}
+		q.reportDropped()
+	}
+
+	if err := flush(); err != nil && !failed {
+		q.logger.Error("Failed to write message log", "error", err)
+	}
+}
+
+// reportDropped logs how many messages were dropped since it last ran
+func (q *messageQueue) reportDropped() {
+	q.mu.Lock()
+	dropped := q.dropped
+	q.dropped = 0
+	q.mu.Unlock()
+
+	if dropped > 0 {
+		q.logger.Warn("Message log queue full, dropped messages", "dropped", dropped)
+	}
+}
+
+// stop waits for the queued messages to be written. It reports whether the
+// queue was still open, so only the first Close releases the storage.
+func (q *messageQueue) stop() bool {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return false
+	}
+	q.closed = true
+	close(q.entries)
+	q.mu.Unlock()
+
+	<-q.done
+	q.reportDropped()
+	return true
+}
+
+// FileMessageLogger appends messages to a file as newline-delimited JSON.
+// Messages are queued and written by a background goroutine so rooms never
+// wait on the disk; if the queue fills up, messages are dropped and counted.
+type FileMessageLogger struct {
+	*messageQueue
+	file *os.File
+}
+
+// NewFileMessageLogger opens path for appending, creating it if needed, and
+// starts writing messages to it
+func NewFileMessageLogger(path string, logger *slog.Logger) (*FileMessageLogger, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o640)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open message log: %w", err)
+	}
+
+	l := &FileMessageLogger{
+		messageQueue: newMessageQueue(logger.With("message_log", path)),
+		file:         f,
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	go l.run(func(entry messageLogEntry) error { return enc.Encode(entry) }, w.Flush)
+	return l, nil
+}
+
+// Close writes out any queued messages and closes the file
+func (l *FileMessageLogger) Close() error {
+	if !l.stop() {
+		return nil
+	}
+	return l.file.Close()
+}
+
+// sqliteSchema creates the table SQLiteMessageLogger writes to. Timestamps
+// are RFC 3339 text in UTC, so they sort and compare as strings.
+const sqliteSchema = `CREATE TABLE IF NOT EXISTS messages (
+	id        INTEGER PRIMARY KEY,
+	timestamp TEXT NOT NULL,
+	room      TEXT NOT NULL,
+	sender    TEXT NOT NULL,
+	content   TEXT NOT NULL,
+	system    INTEGER NOT NULL DEFAULT 0,
+	action    INTEGER NOT NULL DEFAULT 0,
+	bot       INTEGER NOT NULL DEFAULT 0,
+	seq       INTEGER
+);
+CREATE INDEX IF NOT EXISTS messages_room_timestamp ON messages (room, timestamp);`
+
+// sqliteInsert adds one message to the messages table
+const sqliteInsert = `INSERT INTO messages (timestamp, room, sender, content, system, action, bot, seq) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+
+// SQLiteMessageLogger writes messages to the messages table of a SQLite
+// database, creating it if needed. Like FileMessageLogger it queues messages
+// for a background goroutine, which inserts each burst in one transaction.
+type SQLiteMessageLogger struct {
+	*messageQueue
+	db *sql.DB
+	tx *sql.Tx // Open transaction of the burst being written; only used by the queue's goroutine
+}
+
+// NewSQLiteMessageLogger opens or creates the database at path and starts
+// writing messages to it
+func NewSQLiteMessageLogger(path string, logger *slog.Logger) (*SQLiteMessageLogger, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open message log: %w", err)
+	}
+	// SQLite allows one writer at a time, and only the queue writes
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open message log: %w", err)
+	}
+
+	l := &SQLiteMessageLogger{
+		messageQueue: newMessageQueue(logger.With("message_log", path)),
+		db:           db,
+	}
+	go l.run(l.insert, l.commit)
+	return l, nil
+}
+
+// insert adds a message to the current transaction, starting one if needed
+func (l *SQLiteMessageLogger) insert(entry messageLogEntry) error {
+	if l.tx == nil {
+		tx, err := l.db.Begin()
+		if err != nil {
+			return err
+		}
+		l.tx = tx
+	}
+
+	var seq any
+	if entry.Seq > 0 {
+		seq = int64(entry.Seq)
+	}
+	_, err := l.tx.Exec(sqliteInsert, entry.Timestamp.UTC().Format(time.RFC3339Nano), entry.Room, entry.From, entry.Content, entry.System, entry.Action, entry.Bot, seq)
+	return err
+}
+
+// commit commits the current transaction, if there is one
+func (l *SQLiteMessageLogger) commit() error {
+	if l.tx == nil {
+		return nil
+	}
+	err := l.tx.Commit()
+	l.tx = nil
+	return err
+}
+
+// Close writes out any queued messages and closes the database
+func (l *SQLiteMessageLogger) Close() error {
+	if !l.stop() {
+		return nil
+	}
+	return l.db.Close()
+}
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bscott/ts-chat/internal/chat"
+)
+
+// testLogMessages are logged by each message logger test, in order
+var testLogMessages = []chat.Message{
+	{From: "alice", Content: "hello", Timestamp: time.Date(2025, 3, 1, 12, 34, 56, 0, time.UTC), Seq: 1},
+	{From: "bob", Content: "waves", Timestamp: time.Date(2025, 3, 1, 12, 35, 0, 0, time.UTC), IsAction: true, Seq: 2},
+	{From: "pingbot", Content: "pong", Timestamp: time.Date(2025, 3, 1, 12, 35, 1, 0, time.UTC), IsBot: true},
+}
+
+func TestFileMessageLogger(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "messages.jsonl")
+	l, err := NewMessageLogger(MessageLogJSON, path, slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatalf("opening: %v", err)
+	}
+	for _, msg := range testLogMessages {
+		l.LogMessage("lobby", msg)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatalf("closing: %v", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var got []messageLogEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry messageLogEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("line %q: %v", scanner.Text(), err)
+		}
+		got = append(got, entry)
+	}
+	checkLoggedMessages(t, got)
+}
+
+func TestSQLiteMessageLogger(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "messages.db")
+	l, err := NewMessageLogger(MessageLogSQLite, path, slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatalf("opening: %v", err)
+	}
+	for _, msg := range testLogMessages {
+		l.LogMessage("lobby", msg)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatalf("closing: %v", err)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatalf("closing twice: %v", err)
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	rows, err := db.Query("SELECT timestamp, room, sender, content, system, action, bot, seq FROM messages ORDER BY id")
+	if err != nil {
+		t.Fatalf("querying: %v", err)
+	}
+	defer rows.Close()
+	var got []messageLogEntry
+	for rows.Next() {
+		var entry messageLogEntry
+		var timestamp string
+		var seq sql.NullInt64
+		if err := rows.Scan(&timestamp, &entry.Room, &entry.From, &entry.Content, &entry.System, &entry.Action, &entry.Bot, &seq); err != nil {
+			t.Fatalf("scanning: %v", err)
+		}
+		if entry.Timestamp, err = time.Parse(time.RFC3339Nano, timestamp); err != nil {
+			t.Fatalf("timestamp %q: %v", timestamp, err)
+		}
+		entry.Seq = uint64(seq.Int64)
+		got = append(got, entry)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	checkLoggedMessages(t, got)
+}
+
+func TestNewMessageLoggerRejectsUnknownFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "messages.csv")
+	if _, err := NewMessageLogger("csv", path, slog.New(slog.DiscardHandler)); err == nil {
+		t.Fatal("opened a message log in an unknown format")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("created %s for an unknown format", path)
+	}
+}
+
+// checkLoggedMessages compares what a logger wrote with testLogMessages
+func checkLoggedMessages(t *testing.T, got []messageLogEntry) {
+	t.Helper()
+	if len(got) != len(testLogMessages) {
+		t.Fatalf("logged %d messages, want %d: %+v", len(got), len(testLogMessages), got)
+	}
+	for i, msg := range testLogMessages {
+		want := messageLogEntry{
+			Timestamp: msg.Timestamp,
+			Room:      "lobby",
+			From:      msg.From,
+			Content:   msg.Content,
+			Action:    msg.IsAction,
+			Bot:       msg.IsBot,
+			Seq:       msg.Seq,
+		}
+		if !got[i].Timestamp.Equal(want.Timestamp) {
+			t.Errorf("message %d timestamp = %v, want %v", i, got[i].Timestamp, want.Timestamp)
+		}
+		got[i].Timestamp = want.Timestamp
+		if got[i] != want {
+			t.Errorf("message %d = %+v, want %+v", i, got[i], want)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package server
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// loadMOTD returns the message of the day: the contents of file if it is
+// set, otherwise text
+func loadMOTD(text, file string) (string, error) {
+	if file == "" {
+		return strings.TrimSpace(text), nil
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read MOTD file: %w", err)
+	}
+	return strings.TrimSpace(strings.ReplaceAll(string(data), "\r\n", "\n")), nil
+}
+
+// MOTD returns the current message of the day, empty if there is none
+func (s *Server) MOTD() string {
+	if motd := s.motd.Load(); motd != nil {
+		return *motd
+	}
+	return ""
+}
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// reloadable lists the config fields, by YAML key, that Reload applies to a
+// running server. The log level is applied by main, which owns the logger.
+var reloadable = map[string]bool{
+	"motd":      true,
+	"motd_file": true,
+	"log_level": true,
+}
+
+// Reload applies the parts of cfg that are safe to change without dropping
+// connections: the message of the day, the TLS certificate, the ban list and
+// the filter word list are re-read. Any other setting that differs from the
+// one the server started with is logged and ignored until a restart. Room
+// topics aren't configured, so they are left as /topic last set them. Every
+// step is attempted; the errors of those that failed are returned together.
+func (s *Server) Reload(cfg Config) error {
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	if ignored := changedFields(s.config, cfg); len(ignored) > 0 {
+		s.logger.Warn("Ignoring settings that need a restart to change", "settings", strings.Join(ignored, ", "))
+	}
+
+	var errs []error
+	if motd, err := loadMOTD(cfg.Motd, cfg.MotdFile); err != nil {
+		errs = append(errs, err)
+	} else {
+		s.motd.Store(&motd)
+	}
+	if err := s.ReloadCertificate(); err != nil {
+		errs = append(errs, err)
+	}
+	if count, err := s.bans.Reload(); err != nil {
+		errs = append(errs, err)
+	} else if s.config.BanFile != "" {
+		s.logger.Info("Reloaded ban list", "bans", count)
+	}
+	if s.filter != nil {
+		if count, err := s.filter.Reload(); err != nil {
+			errs = append(errs, err)
+		} else {
+			s.logger.Info("Reloaded filter list", "entries", count)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// changedFields returns the YAML keys of the settings that differ between
+// old and new and can't be reloaded
+func changedFields(old, new Config) []string {
+	var changed []string
+	oldValue, newValue := reflect.ValueOf(old), reflect.ValueOf(new)
+	for i := 0; i < oldValue.NumField(); i++ {
+		key, _, _ := strings.Cut(oldValue.Type().Field(i).Tag.Get("yaml"), ",")
+		if key == "" || key == "-" || reloadable[key] {
+			continue
+		}
+		if !reflect.DeepEqual(oldValue.Field(i).Interface(), newValue.Field(i).Interface()) {
+			changed = append(changed, key)
+		}
+	}
+	return changed
+}
@@ -2,100 +2,359 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net"
+	"net/http"
+	"net/netip"
 	"os"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/bscott/ts-chat/internal/bots"
 	"github.com/bscott/ts-chat/internal/chat"
+	"github.com/bscott/ts-chat/internal/i18n"
+	"github.com/bscott/ts-chat/internal/metrics"
+	"github.com/bscott/ts-chat/internal/ui"
 	"tailscale.com/tsnet"
 )
 
 // Server represents the chat server
 type Server struct {
 	config      Config
-	listener    net.Listener
+	logger      *slog.Logger
+	listeners   []*chatListener // Chat listeners, see Config.ListenSpecs
 	tsServer    *tsnet.Server
-	chatRoom    *chat.Room
+	metricsServer *http.Server
+	healthServer  *http.Server
+	wsServer    *http.Server
+	controlListener net.Listener // nil when the control socket is disabled
+	shutdown    chan struct{} // Closed by the control socket's shutdown command
+	shutdownOnce sync.Once
+	rooms       *chat.RoomManager
+	bans        *BanList
+	filter      chat.WordFilter // nil when filtering is disabled
+	messageLog  messageLogCloser // nil when message logging is disabled
+	sessions    *sessionRegistry // nil when sessions can't be resumed
+	theme       *ui.Theme
+	catalog     *i18n.Catalog
+	motd        atomic.Pointer[string] // Message of the day shown after the banner; swapped by Reload
+	banner      string // Custom banner (empty uses the default)
+	location    *time.Location // Default time zone for message timestamps
+	certs       *certReloader // TLS certificate (nil when TLS is disabled)
+	reloadMu    sync.Mutex   // Serializes Reload
 	ctx         context.Context
 	cancel      context.CancelFunc
 	wg          sync.WaitGroup
-	connections map[string]net.Conn
+	connections map[string]chat.Transport
+	perSource   map[string]int // Open connections by source, see serveClient; protected by mu
+	acceptLimit *acceptLimiter // nil when the accept rate is unlimited
+	greetLimit  *greetLimiter  // nil when greetings aren't rate limited
+	startTime   time.Time    // When Start was called
+	ready       atomic.Bool  // Accepting connections; cleared when shutdown begins
+	peakUsers   atomic.Int64 // Most users connected at once
+	openConns   atomic.Int64 // Client connections being served, see MaxConnections
+	stopping    bool         // Set by Stop, after which no client is added to wg; protected by mu
 	mu          sync.Mutex
 }
 
-// NewServer creates a new chat server
-func NewServer(cfg Config) (*Server, error) {
+// NewServer creates a new chat server that logs to logger
+func NewServer(cfg Config, logger *slog.Logger) (*Server, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	
+	themeName := cfg.Theme
+	if themeName == "" {
+		themeName = ui.DefaultThemeName
+	}
+	theme, err := ui.NewTheme(themeName)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.NoNickColors {
+		theme.NickColors = false
+	}
+	
+	catalog, err := i18n.New(cfg.Lang)
+	if err != nil {
+		return nil, err
+	}
+	
+	motd, err := loadMOTD(cfg.Motd, cfg.MotdFile)
+	if err != nil {
+		return nil, err
+	}
+	
+	banner, err := loadBanner(cfg.BannerFile)
+	if err != nil {
+		return nil, err
+	}
+	
+	location := time.Local
+	if cfg.Timezone != "" {
+		location, err = time.LoadLocation(cfg.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time zone %q: %w", cfg.Timezone, err)
+		}
+	}
+	
+	// Load the certificate up front so a bad one stops startup
+	var certs *certReloader
+	if cfg.EnableTLS {
+		certs, err = newCertReloader(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+	
 	ctx, cancel := context.WithCancel(context.Background())
 	
-	// Create a new chat room
-	room := chat.NewRoom(cfg.RoomName, cfg.MaxUsers)
+	// A broken template shouldn't keep the server from starting
+	presence, err := chat.ParsePresenceTemplates(cfg.JoinTemplate, cfg.LeaveTemplate)
+	if err != nil {
+		logger.Warn("Using the default join and leave notices", "error", err)
+		presence = chat.PresenceTemplates{}
+	}
+	
+	// Create the room manager with the configured room as the lobby
+	rooms := chat.NewRoomManager(cfg.RoomName, cfg.MaxUsers, cfg.HistorySize, cfg.ShowOccupancy, presence, logger)
+	
+	bans, err := NewBanList(cfg.BanFile)
+	if err != nil {
+		cancel()
+		rooms.Stop()
+		return nil, err
+	}
+	
+	var filter chat.WordFilter
+	if cfg.FilterFile != "" {
+		wordFilter, err := NewWordFilter(cfg.FilterFile, cfg.FilterAction)
+		if err != nil {
+			cancel()
+			rooms.Stop()
+			return nil, err
+		}
+		filter = wordFilter
+	}
+	
+	for _, name := range cfg.Bots {
+		bot, err := bots.New(name)
+		if err != nil {
+			cancel()
+			rooms.Stop()
+			return nil, err
+		}
+		rooms.RegisterHandler(bot)
+	}
+	
+	var messageLog messageLogCloser
+	if cfg.MessageLog != "" {
+		messageLog, err = NewMessageLogger(cfg.MessageLogFormat, cfg.MessageLog, logger)
+		if err != nil {
+			cancel()
+			rooms.Stop()
+			return nil, err
+		}
+		rooms.SetMessageLogger(messageLog, cfg.MessageLogSystem)
+	}
+	rooms.SetSequenceNumbers(cfg.SequenceNumbers)
+	rooms.SetCatalog(catalog)
+
+	var sessions *sessionRegistry
+	if cfg.ResumeWindow > 0 {
+		sessions = newSessionRegistry(cfg.ResumeWindow)
+	}
+	
+	var acceptLimit *acceptLimiter
+	if cfg.AcceptRate > 0 {
+		acceptLimit = newAcceptLimiter(cfg.AcceptRate, cfg.AcceptBurst)
+	}
+	var greetLimit *greetLimiter
+	if cfg.GreetRate > 0 {
+		greetLimit = newGreetLimiter(cfg.GreetRate, cfg.GreetBurst)
+	}
 	
-	return &Server{
+	s := &Server{
+		acceptLimit: acceptLimit,
+		greetLimit:  greetLimit,
+		bans:        bans,
+		filter:      filter,
+		messageLog:  messageLog,
+		sessions:    sessions,
+		theme:       theme,
+		catalog:     catalog,
+		banner:      banner,
+		location:    location,
+		certs:       certs,
 		config:      cfg,
+		logger:      logger,
 		ctx:         ctx,
 		cancel:      cancel,
-		chatRoom:    room,
-		connections: make(map[string]net.Conn),
-	}, nil
+		rooms:       rooms,
+		connections: make(map[string]chat.Transport),
+		perSource:   make(map[string]int),
+		shutdown:    make(chan struct{}),
+	}
+	s.motd.Store(&motd)
+	return s, nil
 }
 
 // Start starts the chat server
 func (s *Server) Start() error {
-	var listener net.Listener
-	var err error
+	s.startTime = time.Now()
 	
-	if s.config.EnableTailscale {
-		// Start the tsnet Tailscale server
+	specs, err := s.config.ListenSpecs()
+	if err != nil {
+		return err
+	}
+	
+	// Start the tsnet Tailscale server if any listener is on the tailnet
+	if usesTailscale(specs) {
 		s.tsServer = &tsnet.Server{
 			Hostname: s.config.HostName,
 			AuthKey:  os.Getenv("TS_AUTHKEY"),
 		}
-		
-		// Listen on the specified port
-		listener, err = s.tsServer.Listen("tcp", fmt.Sprintf(":%d", s.config.Port))
+	}
+	
+	for _, spec := range specs {
+		listener, err := s.openListener(spec)
 		if err != nil {
-			return fmt.Errorf("failed to start Tailscale server on port %d: %w", s.config.Port, err)
+			s.closeListeners()
+			return err
 		}
 		
+		// Encrypt the chat listeners when TLS is enabled
+		if s.certs != nil {
+			listener.Listener = tls.NewListener(listener.Listener, s.certs.tlsConfig())
+		}
+		s.listeners = append(s.listeners, listener)
+	}
+	
+	if s.tsServer != nil {
 		// Try to get Tailscale status
 		ln, err := s.tsServer.LocalClient()
 		if err != nil {
-			log.Printf("Warning: unable to get Tailscale local client: %v", err)
+			s.logger.Warn("Unable to get Tailscale local client", "error", err)
 		} else {
 			status, err := ln.Status(s.ctx)
 			if err != nil {
-				log.Printf("Warning: unable to get Tailscale status: %v", err)
+				s.logger.Warn("Unable to get Tailscale status", "error", err)
 			} else if status != nil && status.Self != nil && status.Self.DNSName != "" {
-				log.Printf("Tailscale node running as: %s", status.Self.DNSName)
+				s.logger.Info("Tailscale node running", "dns_name", status.Self.DNSName)
 			} else {
-				log.Printf("Tailscale node running but DNS name not available yet")
+				s.logger.Info("Tailscale node running but DNS name not available yet")
 			}
 		}
-	} else {
-		// Start a regular TCP server
-		listener, err = net.Listen("tcp", fmt.Sprintf(":%d", s.config.Port))
-		if err != nil {
-			return fmt.Errorf("failed to listen on port %d: %w", s.config.Port, err)
+	}
+	
+	if s.config.MetricsPort > 0 {
+		if err := s.startMetrics(); err != nil {
+			s.closeListeners()
+			return err
 		}
 	}
 	
-	s.listener = listener
+	if s.config.WebSocketPort > 0 {
+		if err := s.startWebSocket(); err != nil {
+			s.closeListeners()
+			return err
+		}
+	}
+	
+	if s.config.HealthPort > 0 {
+		if err := s.startHealth(); err != nil {
+			s.closeListeners()
+			return err
+		}
+	}
+	
+	if s.config.ControlSocket != "" {
+		if err := s.startControl(); err != nil {
+			s.closeListeners()
+			return err
+		}
+	}
+	
+	for _, listener := range s.listeners {
+		s.logger.Info("Listening for chat connections", "listener", listener.spec.String())
+	}
+	s.logger.Info("Server started", "room", s.config.RoomName, "max_users", s.config.MaxUsers)
 	
-	log.Printf("Server started on port %d", s.config.Port)
-	log.Printf("Room name: %s", s.config.RoomName)
-	log.Printf("Maximum users: %d", s.config.MaxUsers)
+	if s.sessions != nil {
+		s.wg.Add(1)
+		go s.expireSessions()
+	}
+	
+	// Accept connections on every listener
+	for _, listener := range s.listeners {
+		s.wg.Add(1)
+		go s.acceptConnections(listener)
+	}
+	s.ready.Store(true)
+	
+	return nil
+}
+
+// listen opens a TCP listener on port, on the tailnet if any chat listener
+// is there
+func (s *Server) listen(port int) (net.Listener, error) {
+	if s.tsServer != nil {
+		return s.tsServer.Listen("tcp", fmt.Sprintf(":%d", port))
+	}
+	return s.listenTCP(port)
+}
+
+// startMetrics serves Prometheus metrics over HTTP on the configured port
+func (s *Server) startMetrics() error {
+	ln, err := net.Listen("tcp", fmt.Sprintf(":%d", s.config.MetricsPort))
+	if err != nil {
+		return fmt.Errorf("failed to listen for metrics on port %d: %w", s.config.MetricsPort, err)
+	}
+	
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	s.metricsServer = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
 	
-	// Accept connections
 	s.wg.Add(1)
-	go s.acceptConnections()
+	go func() {
+		defer s.wg.Done()
+		if err := s.metricsServer.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Error("Metrics server error", "error", err)
+		}
+	}()
 	
+	s.logger.Info("Serving metrics", "port", s.config.MetricsPort)
 	return nil
 }
 
-// acceptConnections accepts incoming connections
-func (s *Server) acceptConnections() {
+// expireSessions periodically forgets sessions nobody came back for
+func (s *Server) expireSessions() {
+	defer s.wg.Done()
+	
+	ticker := time.NewTicker(min(s.sessions.Window(), maxSessionCleanupInterval))
+	defer ticker.Stop()
+	
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			if removed := s.sessions.expire(); removed > 0 {
+				s.logger.Debug("Expired suspended sessions", "count", removed)
+			}
+		}
+	}
+}
+
+// acceptConnections accepts incoming connections on listener
+func (s *Server) acceptConnections(listener *chatListener) {
 	defer s.wg.Done()
 	
 	for {
@@ -103,99 +362,420 @@ func (s *Server) acceptConnections() {
 		case <-s.ctx.Done():
 			return
 		default:
-			conn, err := s.listener.Accept()
+			conn, err := listener.Accept()
 			if err != nil {
 				// Check if server is shutting down
 				select {
 				case <-s.ctx.Done():
 					return
 				default:
-					log.Printf("Error accepting connection: %v", err)
+					s.logger.Warn("Error accepting connection", "error", err)
 					continue
 				}
 			}
 			
+			// Turn away bursts of connections before they cost a goroutine each
+			if s.acceptLimit != nil && !s.acceptLimit.allow() {
+				s.logger.Debug("Rejected connection over the accept rate", "remote", conn.RemoteAddr())
+				metrics.ConnectionsRejected.Inc()
+				s.rejectBusy(conn)
+				continue
+			}
+			
 			// Handle the connection in a new goroutine
 			s.wg.Add(1)
-			go s.handleConnection(conn)
+			go s.handleConnection(conn, listener.spec.Network == NetworkTailscale)
 		}
 	}
 }
 
-// handleConnection handles a client connection
-func (s *Server) handleConnection(conn net.Conn) {
+// rejectBusy turns away a connection accepted over the accept rate. The
+// notice is best effort and TLS clients get none, since greeting them would
+// mean waiting for a handshake.
+func (s *Server) rejectBusy(conn net.Conn) {
+	if _, isTLS := conn.(*tls.Conn); !isTLS {
+		conn.SetWriteDeadline(time.Now().Add(busyWriteTimeout))
+		fmt.Fprint(conn, s.catalog.T("reject.busy")+chat.TelnetLineEnding)
+	}
+	conn.Close()
+}
+
+// handleConnection handles a telnet client connection, accepted on the
+// tailnet if tailnet is set
+func (s *Server) handleConnection(conn net.Conn, tailnet bool) {
+	s.serveClient(conn, false, tailnet)
+}
+
+// serveClient runs a chat client over conn until it disconnects. JSON
+// clients receive JSON-encoded messages instead of styled text. Clients
+// that came in over the tailnet are identified by their Tailscale login.
+// The caller must have added the client to s.wg.
+func (s *Server) serveClient(conn chat.Transport, useJSON, tailnet bool) {
 	defer s.wg.Done()
 	defer conn.Close()
 	
 	remoteAddr := conn.RemoteAddr().String()
-	log.Printf("New connection from %s", remoteAddr)
+	logger := s.logger.With("remote", remoteAddr)
+	eol := chat.TelnetLineEnding
+	if useJSON {
+		eol = chat.StreamLineEnding
+	}
+	
+	// Cap the connections open at once, whichever rooms they end up in
+	open := s.openConns.Add(1)
+	defer s.openConns.Add(-1)
+	if s.config.MaxConnections > 0 && open > int64(s.config.MaxConnections) {
+		logger.Warn("Rejected connection over the server-wide limit", "max_connections", s.config.MaxConnections)
+		metrics.ConnectionsRejected.Inc()
+		fmt.Fprint(conn, s.catalog.T("reject.full")+eol)
+		return
+	}
+	
+	// On a tailnet, moderation follows the Tailscale user rather than
+	// their address, which changes from device to device
+	host := remoteHost(conn.RemoteAddr())
+	source := host
+	identity := ""
+	operator := false
+	if tailnet {
+		if id, ok := s.whoIs(conn.RemoteAddr()); ok {
+			source = id.LoginName
+			identity = id.String()
+			operator = id.isOperator(s.config.Operators)
+			logger = logger.With("identity", identity)
+		}
+	}
+	
+	// A source reconnecting faster than any person would is most likely a
+	// scanner, so it is dropped quietly before being sent anything
+	if s.greetLimit != nil && !s.greetLimit.allow(source) {
+		logger.Debug("Dropped connection over the greeting rate", "source", source)
+		metrics.ConnectionsRejected.Inc()
+		return
+	}
+	logger.Info("New connection")
 	
 	// Register connection
 	s.mu.Lock()
 	s.connections[remoteAddr] = conn
 	s.mu.Unlock()
+	metrics.ConnectionsActive.Inc()
 	
 	// Deregister connection when done
 	defer func() {
 		s.mu.Lock()
 		delete(s.connections, remoteAddr)
 		s.mu.Unlock()
-		log.Printf("Connection from %s closed", remoteAddr)
+		metrics.ConnectionsActive.Dec()
+		logger.Info("Connection closed")
 	}()
 	
+	// Refuse banned sources before doing any work for them. Bans made
+	// before identities were known may name the address instead.
+	if s.bans.IsBanned(source) || s.bans.IsBanned(host) {
+		logger.Warn("Rejected connection from banned source", "source", source)
+		fmt.Fprint(conn, s.catalog.T("reject.banned")+eol)
+		return
+	}
+	
+	// Stop one host from using up every place on the server
+	if !s.acquireSourceSlot(source) {
+		logger.Warn("Rejected connection over the per-source limit", "source", source, "max_per_ip", s.config.MaxPerIP)
+		fmt.Fprint(conn, s.catalog.T("reject.per_source")+eol)
+		return
+	}
+	defer s.releaseSourceSlot(source)
+	
+	// Detect peers that vanish without closing the connection. TCP
+	// connections use kernel keepalives; anything else, such as connections
+	// over tsnet, gets application-level probes from the client instead.
+	keepAlive := s.config.KeepAlive
+	if useJSON {
+		// Telnet NOPs mean nothing to JSON clients
+		keepAlive = 0
+	}
+	tcp, ok := conn.(*net.TCPConn)
+	if tlsConn, isTLS := conn.(*tls.Conn); isTLS {
+		tcp, ok = tlsConn.NetConn().(*net.TCPConn)
+	}
+	if ok && keepAlive > 0 {
+		err := tcp.SetKeepAliveConfig(net.KeepAliveConfig{
+			Enable:   true,
+			Idle:     keepAlive,
+			Interval: keepAlive,
+			Count:    3,
+		})
+		if err != nil {
+			logger.Warn("Error enabling TCP keepalive", "error", err)
+		} else {
+			keepAlive = 0
+		}
+	}
+	
+	// Leave the interface nil rather than holding a nil pointer
+	var sessions chat.SessionStore
+	if s.sessions != nil {
+		sessions = s.sessions
+	}
+	
 	// Create a new client
-	client, err := chat.NewClient(conn, s.chatRoom)
+	client, err := chat.NewClient(s.ctx, conn, s.rooms, chat.ClientConfig{
+		IdleTimeout:      s.config.IdleTimeout,
+		KeepAlive:        keepAlive,
+		WriteTimeout:     s.config.WriteTimeout,
+		OutboundQueueSize: s.config.OutboundQueue,
+		DisconnectSlow:   s.config.DisconnectSlow,
+		OperatorPassword: s.config.OperatorPassword,
+		Operator:         operator,
+		AutoOperator:     s.config.OperatorPassword == "" && len(s.config.Operators) == 0,
+		Source:           source,
+		Identity:         identity,
+		Bans:             s.bans,
+		Sessions:         sessions,
+		MaxQueue:         s.config.MaxQueue,
+		Filter:           s.filter,
+		Stats:            s,
+		Build:            s.config.Build,
+		MOTD:             s.MOTD(),
+		Banner:           s.banner,
+		NoBanner:         s.config.NoBanner,
+		ExportDir:        s.config.ExportDir,
+		Logger:           logger,
+		Theme:            s.theme,
+		Catalog:          s.catalog,
+		Location:         s.location,
+		NoColor:          s.config.NoColor,
+		MaxMessageLength: s.config.MaxMessageLength,
+		MessageRateLimit: s.config.RateLimit,
+		RateLimitWindow:  s.config.RateLimitWindow,
+		OperatorRateLimit: s.config.OperatorRateLimit,
+		FloodViolations:  s.config.FloodViolations,
+		FloodWindow:      s.config.FloodWindow,
+		FloodMute:        s.config.FloodMute,
+		Nicknames: chat.NicknamePolicy{
+			MinLength:       s.config.NicknameMinLength,
+			MaxLength:       s.config.NicknameMaxLength,
+			Symbols:         s.config.NicknameSymbols,
+			Reserved:        s.config.ReservedNicknames,
+		},
+		AutoRenameOnCollision: s.config.AutoRenameOnCollision,
+		JSON:             useJSON,
+		LineEnding:       eol,
+		Macros:           s.config.Macros,
+		TopicLogOperatorsOnly: s.config.TopicLogOperators,
+	})
 	if err != nil {
-		log.Printf("Error creating client: %v", err)
+		logger.Info("Client setup ended", "error", err)
 		return
 	}
 	
+	s.recordPeakUsers()
+	
 	// Handle the client
 	client.Handle(s.ctx)
 }
 
+// closeListeners closes every chat listener that has been opened
+func (s *Server) closeListeners() {
+	for _, listener := range s.listeners {
+		s.logger.Info("Closing listener", "listener", listener.spec.String())
+		if err := listener.Close(); err != nil {
+			s.logger.Error("Error closing listener", "listener", listener.spec.String(), "error", err)
+		}
+	}
+}
+
+// ReloadCertificate reloads the TLS certificate and key from disk. It does
+// nothing when TLS is disabled.
+func (s *Server) ReloadCertificate() error {
+	if s.certs == nil {
+		return nil
+	}
+	return s.certs.Reload()
+}
+
+// acquireSourceSlot counts a new connection from source, reporting false
+// if the source already has MaxPerIP connections open
+func (s *Server) acquireSourceSlot(source string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	if s.config.MaxPerIP > 0 && s.perSource[source] >= s.config.MaxPerIP {
+		return false
+	}
+	s.perSource[source]++
+	return true
+}
+
+// addClient adds a client that isn't started by a goroutine in s.wg to
+// s.wg, reporting false if the server is stopping
+func (s *Server) addClient() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	if s.stopping {
+		return false
+	}
+	s.wg.Add(1)
+	return true
+}
+
+// releaseSourceSlot forgets a closed connection from source
+func (s *Server) releaseSourceSlot(source string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	s.perSource[source]--
+	if s.perSource[source] <= 0 {
+		delete(s.perSource, source)
+	}
+}
+
+// recordPeakUsers updates the peak user count if it has been exceeded
+func (s *Server) recordPeakUsers() {
+	users := int64(s.rooms.UserCount())
+	for {
+		peak := s.peakUsers.Load()
+		if users <= peak || s.peakUsers.CompareAndSwap(peak, users) {
+			return
+		}
+	}
+}
+
+// Stats reports server statistics for /stats
+func (s *Server) Stats() chat.Stats {
+	return chat.Stats{
+		Uptime:    time.Since(s.startTime),
+		Messages:  metrics.MessagesTotal.Value(),
+		Users:     s.rooms.UserCount(),
+		PeakUsers: int(s.peakUsers.Load()),
+		Rooms:     len(s.rooms.Rooms()),
+	}
+}
+
+// remoteHost returns the host portion of a connection's remote address
+func remoteHost(addr net.Addr) string {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	
+	// Dual-stack listeners see IPv4 clients as ::ffff:1.2.3.4, and IPv6
+	// link-local addresses carry a zone; either way it is the same host
+	if ip, err := netip.ParseAddr(host); err == nil {
+		return ip.Unmap().WithZone("").String()
+	}
+	return host
+}
+
+// drain warns every room that the server is shutting down and waits out the
+// grace period. The wait is bounded even if a room is too busy to accept
+// the announcement, so a stuck client can't hold up shutdown.
+func (s *Server) drain() {
+	grace := s.config.ShutdownGrace
+	s.logger.Info("Draining connections", "grace", grace)
+	
+	go s.rooms.Broadcast(chat.Message{
+		From:      "System",
+		Content:   s.catalog.T("server.shutting_down", grace),
+		Timestamp: time.Now(),
+		IsSystem:  true,
+	})
+	
+	time.Sleep(grace)
+}
+
 // Stop stops the chat server
 func (s *Server) Stop() error {
-	log.Print("Stopping chat server...")
+	s.logger.Info("Stopping chat server")
+	s.ready.Store(false)
+	
+	// Give connected users a chance to see that the server is going away
+	if s.config.ShutdownGrace > 0 && s.rooms != nil {
+		s.drain()
+	}
 	
 	// Cancel the context to signal shutdown
 	s.cancel()
 	
-	// Stop the chat room
-	if s.chatRoom != nil {
-		log.Print("Stopping chat room...")
-		if err := s.chatRoom.Stop(); err != nil {
-			log.Printf("Error stopping chat room: %v", err)
+	// Turn away clients that haven't been added to s.wg yet, since adding
+	// them while Wait runs below would race with it
+	s.mu.Lock()
+	s.stopping = true
+	s.mu.Unlock()
+	
+	// Stop the chat rooms
+	if s.rooms != nil {
+		s.logger.Info("Stopping chat rooms")
+		if err := s.rooms.Stop(); err != nil {
+			s.logger.Error("Error stopping chat rooms", "error", err)
+		}
+	}
+
+	// Write out messages still waiting to be logged
+	if s.messageLog != nil {
+		s.logger.Info("Closing message log")
+		if err := s.messageLog.Close(); err != nil {
+			s.logger.Error("Error closing message log", "error", err)
 		}
 	}
 	
 	// Close all active connections
 	s.mu.Lock()
 	for addr, conn := range s.connections {
-		log.Printf("Closing connection from %s", addr)
+		s.logger.Debug("Closing connection", "remote", addr)
 		conn.Close()
 	}
 	s.mu.Unlock()
 	
-	// Close the listener
-	if s.listener != nil {
-		log.Print("Closing listener")
-		if err := s.listener.Close(); err != nil {
-			log.Printf("Error closing listener: %v", err)
+	// Stop the WebSocket gateway; its clients were closed above
+	if s.wsServer != nil {
+		s.logger.Info("Stopping WebSocket server")
+		if err := s.wsServer.Close(); err != nil {
+			s.logger.Error("Error stopping WebSocket server", "error", err)
+		}
+	}
+	
+	// Stop serving metrics
+	if s.metricsServer != nil {
+		s.logger.Info("Stopping metrics server")
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := s.metricsServer.Shutdown(ctx); err != nil {
+			s.logger.Error("Error stopping metrics server", "error", err)
 		}
+		cancel()
 	}
 	
-	// Close the tsnet server if in Tailscale mode
-	if s.config.EnableTailscale && s.tsServer != nil {
-		log.Print("Closing Tailscale node")
+	// Close the listeners
+	s.closeListeners()
+	if s.controlListener != nil {
+		s.logger.Info("Closing control socket")
+		if err := s.controlListener.Close(); err != nil {
+			s.logger.Error("Error closing control socket", "error", err)
+		}
+	}
+	
+	// Close the tsnet server if any listener was on the tailnet
+	if s.tsServer != nil {
+		s.logger.Info("Closing Tailscale node")
 		if err := s.tsServer.Close(); err != nil {
-			log.Printf("Error closing Tailscale node: %v", err)
+			s.logger.Error("Error closing Tailscale node", "error", err)
+		}
+	}
+	
+	// Stop answering health checks last, so probes see the shutdown
+	if s.healthServer != nil {
+		s.logger.Info("Stopping health server")
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := s.healthServer.Shutdown(ctx); err != nil {
+			s.logger.Error("Error stopping health server", "error", err)
 		}
+		cancel()
 	}
 	
 	// Wait for all goroutines to finish
 	s.wg.Wait()
 	
-	log.Print("Chat server stopped")
+	s.logger.Info("Chat server stopped")
 	return nil
 }
\ No newline at end of file
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bscott/ts-chat/internal/chat"
+)
+
+// resumeTokenBytes is the length of resume tokens before hex encoding
+const resumeTokenBytes = 8
+
+// maxSessionCleanupInterval bounds how long expired sessions linger before
+// they are removed
+const maxSessionCleanupInterval = time.Minute
+
+// suspendedSession is a session waiting for its owner to reconnect
+type suspendedSession struct {
+	session chat.Session
+	expires time.Time
+}
+
+// sessionRegistry keeps the sessions of users whose connection dropped for
+// window, keyed by resume token
+type sessionRegistry struct {
+	window   time.Duration
+	sessions map[string]suspendedSession
+	mu       sync.Mutex
+}
+
+// newSessionRegistry creates a registry keeping sessions for window
+func newSessionRegistry(window time.Duration) *sessionRegistry {
+	return &sessionRegistry{
+		window:   window,
+		sessions: make(map[string]suspendedSession),
+	}
+}
+
+// Issue returns a new random resume token
+func (r *sessionRegistry) Issue() string {
+	b := make([]byte, resumeTokenBytes)
+	rand.Read(b) // Never fails, see crypto/rand.Read
+	return hex.EncodeToString(b)
+}
+
+// Suspend keeps a session under token until the grace window passes
+func (r *sessionRegistry) Suspend(token string, s chat.Session) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.sessions[token] = suspendedSession{session: s, expires: time.Now().Add(r.window)}
+}
+
+// Resume returns and forgets the session kept under token
+func (r *sessionRegistry) Resume(token string) (chat.Session, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	suspended, ok := r.sessions[token]
+	if !ok {
+		return chat.Session{}, false
+	}
+	delete(r.sessions, token)
+	if time.Now().After(suspended.expires) {
+		return chat.Session{}, false
+	}
+	return suspended.session, true
+}
+
+// Held reports whether nickname belongs to a session that hasn't expired.
+// Nicknames that differ only in case count as the same.
+func (r *sessionRegistry) Held(nickname string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for _, suspended := range r.sessions {
+		if strings.EqualFold(suspended.session.Nickname, nickname) && now.Before(suspended.expires) {
+			return true
+		}
+	}
+	return false
+}
+
+// Window returns how long suspended sessions are kept
+func (r *sessionRegistry) Window() time.Duration {
+	return r.window
+}
+
+// expire removes sessions whose grace window has passed, returning how many
+// were removed
+func (r *sessionRegistry) expire() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	removed := 0
+	for token, suspended := range r.sessions {
+		if now.After(suspended.expires) {
+			delete(r.sessions, token)
+			removed++
+		}
+	}
+	return removed
+}
@@ -0,0 +1,185 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/coder/websocket"
+)
+
+// startWebSocket serves the WebSocket gateway on the configured port. Web
+// clients connect to /ws and exchange JSON messages with the same rooms as
+// telnet users.
+func (s *Server) startWebSocket() error {
+	ln, err := s.listen(s.config.WebSocketPort)
+	if err != nil {
+		return fmt.Errorf("failed to listen for WebSocket connections on port %d: %w", s.config.WebSocketPort, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", s.handleWebSocket)
+	s.wsServer = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		if err := s.wsServer.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Error("WebSocket server error", "error", err)
+		}
+	}()
+
+	s.logger.Info("Serving WebSocket gateway", "port", s.config.WebSocketPort)
+	return nil
+}
+
+// handleWebSocket upgrades a request to a WebSocket and runs a JSON chat
+// client over it
+func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	remote, err := netip.ParseAddrPort(r.RemoteAddr)
+	if err != nil {
+		http.Error(w, "invalid remote address", http.StatusBadRequest)
+		return
+	}
+	if s.bans.IsBanned(remote.Addr().String()) {
+		s.logger.Warn("Rejected WebSocket connection from banned source", "source", remote.Addr().String())
+		http.Error(w, s.catalog.T("reject.banned"), http.StatusForbidden)
+		return
+	}
+
+	// Requests are served outside s.wg, so the client is added to it before
+	// the upgrade, while a shutdown can still be answered with an error
+	if !s.addClient() {
+		http.Error(w, s.catalog.T("reject.stopping"), http.StatusServiceUnavailable)
+		return
+	}
+
+	ws, err := websocket.Accept(w, r, &websocket.AcceptOptions{
+		OriginPatterns: s.config.WebSocketOrigins,
+	})
+	if err != nil {
+		// Accept has already written an error response
+		s.logger.Debug("WebSocket upgrade failed", "remote", r.RemoteAddr, "error", err)
+		s.wg.Done()
+		return
+	}
+
+	conn := &wsConn{
+		ws:     ws,
+		remote: net.TCPAddrFromAddrPort(remote),
+	}
+
+	s.serveClient(conn, true, s.tsServer != nil)
+}
+
+// wsConn adapts a WebSocket to a chat.Transport. Each text message received
+// is one line of input, and each Write is sent as one text message.
+type wsConn struct {
+	ws        *websocket.Conn
+	remote    net.Addr
+	buf       []byte // Unread input from the current message
+	mu        sync.Mutex
+	readDL    time.Time // Read deadline, protected by mu
+	writeDL   time.Time // Write deadline, protected by mu
+	closeOnce sync.Once
+}
+
+// Read reads input, ending each received message with a newline
+func (c *wsConn) Read(p []byte) (int, error) {
+	if len(c.buf) == 0 {
+		c.mu.Lock()
+		ctx, cancel := deadlineContext(c.readDL)
+		c.mu.Unlock()
+		defer cancel()
+
+		_, data, err := c.ws.Read(ctx)
+		if err != nil {
+			return 0, translateWSError(err)
+		}
+		c.buf = append(data, '\n')
+	}
+
+	n := copy(p, c.buf)
+	c.buf = c.buf[n:]
+	return n, nil
+}
+
+// Write sends p as a single text message
+func (c *wsConn) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	ctx, cancel := deadlineContext(c.writeDL)
+	c.mu.Unlock()
+	defer cancel()
+
+	if err := c.ws.Write(ctx, websocket.MessageText, p); err != nil {
+		return 0, translateWSError(err)
+	}
+	return len(p), nil
+}
+
+// Close starts the closing handshake without waiting for it to complete
+func (c *wsConn) Close() error {
+	c.closeOnce.Do(func() {
+		go c.ws.Close(websocket.StatusNormalClosure, "")
+	})
+	return nil
+}
+
+// RemoteAddr returns the remote network address
+func (c *wsConn) RemoteAddr() net.Addr { return c.remote }
+
+// Ping sends a WebSocket ping and waits for the pong. The client's reader
+// goroutine, which is always reading, receives it.
+func (c *wsConn) Ping(ctx context.Context) error {
+	return c.ws.Ping(ctx)
+}
+
+// SetReadDeadline sets the deadline for future Read calls
+func (c *wsConn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.readDL = t
+	return nil
+}
+
+// SetWriteDeadline sets the deadline for future Write calls
+func (c *wsConn) SetWriteDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.writeDL = t
+	return nil
+}
+
+// deadlineContext returns a context that expires at t, or never if t is zero
+func deadlineContext(t time.Time) (context.Context, context.CancelFunc) {
+	if t.IsZero() {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithDeadline(context.Background(), t)
+}
+
+// translateWSError maps WebSocket errors onto the errors chat clients
+// expect from a transport
+func translateWSError(err error) error {
+	switch {
+	case websocket.CloseStatus(err) == websocket.StatusNormalClosure,
+		websocket.CloseStatus(err) == websocket.StatusGoingAway:
+		return io.EOF
+	case errors.Is(err, context.DeadlineExceeded):
+		return os.ErrDeadlineExceeded
+	default:
+		return err
+	}
+}
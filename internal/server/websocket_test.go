@@ -0,0 +1,28 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWebSocketRejectedWhileStopping(t *testing.T) {
+	bans, err := NewBanList("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{logger: slog.New(slog.DiscardHandler), bans: bans}
+	s.stopping = true
+
+	req := httptest.NewRequest(http.MethodGet, "/ws", nil)
+	req.RemoteAddr = "127.0.0.1:40000"
+	rec := httptest.NewRecorder()
+	s.handleWebSocket(rec, req)
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+	// Nothing was added to the WaitGroup Stop is waiting on
+	s.wg.Wait()
+}
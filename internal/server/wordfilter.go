@@ -0,0 +1,106 @@
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Actions a WordFilter can take on messages containing banned words
+const (
+	FilterMask   = "mask"   // Replace banned words with asterisks
+	FilterReject = "reject" // Refuse to send the message
+)
+
+// WordFilter censors banned words and patterns loaded from a file. Matching
+// is case-insensitive, and plain words only match whole words so that
+// banning "ass" doesn't also catch "class".
+type WordFilter struct {
+	path    string
+	reject  bool
+	pattern *regexp.Regexp // nil when the list is empty; protected by mu
+	mu      sync.RWMutex
+}
+
+// NewWordFilter loads the word list from path. action is FilterMask or
+// FilterReject.
+func NewWordFilter(path, action string) (*WordFilter, error) {
+	f := &WordFilter{
+		path:   path,
+		reject: action == FilterReject,
+	}
+	if _, err := f.Reload(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Reload re-reads the word list. Each line is either a word or a regular
+// expression wrapped in slashes, e.g. /fr[e3]+d/; blank lines and # comments
+// are skipped. The current list is kept if the file can't be loaded.
+func (f *WordFilter) Reload() (int, error) {
+	file, err := os.Open(f.path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open filter file: %w", err)
+	}
+	defer file.Close()
+
+	var alternatives []string
+	scanner := bufio.NewScanner(file)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if len(line) > 2 && strings.HasPrefix(line, "/") && strings.HasSuffix(line, "/") {
+			expr := line[1 : len(line)-1]
+			if _, err := regexp.Compile(expr); err != nil {
+				return 0, fmt.Errorf("invalid pattern on line %d of filter file: %w", lineNum, err)
+			}
+			alternatives = append(alternatives, "(?:"+expr+")")
+		} else {
+			alternatives = append(alternatives, `\b`+regexp.QuoteMeta(line)+`\b`)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read filter file: %w", err)
+	}
+
+	var pattern *regexp.Regexp
+	if len(alternatives) > 0 {
+		pattern, err = regexp.Compile("(?i)" + strings.Join(alternatives, "|"))
+		if err != nil {
+			return 0, fmt.Errorf("invalid filter file: %w", err)
+		}
+	}
+
+	f.mu.Lock()
+	f.pattern = pattern
+	f.mu.Unlock()
+
+	return len(alternatives), nil
+}
+
+// Filter masks banned words in content, or reports false if the message
+// contains any and the filter rejects messages
+func (f *WordFilter) Filter(content string) (string, bool) {
+	f.mu.RLock()
+	pattern := f.pattern
+	f.mu.RUnlock()
+
+	if pattern == nil || !pattern.MatchString(content) {
+		return content, true
+	}
+	if f.reject {
+		return "", false
+	}
+
+	return pattern.ReplaceAllStringFunc(content, func(match string) string {
+		return strings.Repeat("*", utf8.RuneCountInString(match))
+	}), true
+}
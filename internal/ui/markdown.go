@@ -0,0 +1,114 @@
+package ui
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Styles for markdown-lite emphasis in messages
+var (
+	boldStyle   = lipgloss.NewStyle().Bold(true)
+	italicStyle = lipgloss.NewStyle().Italic(true)
+)
+
+// WithMarkdown returns a copy of the renderer that shows *bold* and
+// _italic_ in user messages when enabled. Plain renderers ignore it.
+func (r *Renderer) WithMarkdown(enabled bool) *Renderer {
+	copied := *r
+	copied.markdown = enabled
+	return &copied
+}
+
+// Markdown reports whether the renderer styles markdown-lite emphasis
+func (r *Renderer) Markdown() bool {
+	return r.markdown
+}
+
+// emphasize styles a message's markdown-lite emphasis if enabled
+func (r *Renderer) emphasize(message string) string {
+	if !r.markdown || !r.Colored() {
+		return message
+	}
+	return renderMarkdown(message)
+}
+
+// renderMarkdown styles *bold* and _italic_ spans. A span must start after
+// a non-word character and end before one, so snake_case_names and 2*3*4
+// are left alone, and spans don't nest. \* and \_ are literal markers; the
+// backslash is kept after a symbol so ¯\_(ツ)_/¯ shows as typed.
+func renderMarkdown(s string) string {
+	if !strings.ContainsAny(s, "*_") {
+		return s
+	}
+
+	runes := []rune(s)
+	var sb strings.Builder
+	for i := 0; i < len(runes); i++ {
+		ch := runes[i]
+		if ch == '\\' && i+1 < len(runes) && isMarkdownMarker(runes[i+1]) {
+			if i > 0 && !unicode.IsSpace(runes[i-1]) && !isWordRune(runes[i-1]) {
+				sb.WriteRune(ch)
+			}
+			sb.WriteRune(runes[i+1])
+			i++
+			continue
+		}
+
+		if isMarkdownMarker(ch) && opensSpan(runes, i) {
+			if end := closeSpan(runes, i); end > 0 {
+				style := boldStyle
+				if ch == '_' {
+					style = italicStyle
+				}
+				sb.WriteString(style.Render(unescapeMarkdown(string(runes[i+1 : end]))))
+				i = end
+				continue
+			}
+		}
+		sb.WriteRune(ch)
+	}
+	return sb.String()
+}
+
+// isMarkdownMarker reports whether ch starts or ends a span
+func isMarkdownMarker(ch rune) bool {
+	return ch == '*' || ch == '_'
+}
+
+// isWordRune reports whether ch is part of a word for span boundaries
+func isWordRune(ch rune) bool {
+	return unicode.IsLetter(ch) || unicode.IsDigit(ch)
+}
+
+// opensSpan reports whether the marker at i can open a span
+func opensSpan(runes []rune, i int) bool {
+	if i > 0 && isWordRune(runes[i-1]) {
+		return false
+	}
+	return i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) && runes[i+1] != runes[i]
+}
+
+// closeSpan returns the index of the marker closing the span opened at i,
+// or -1 if there is none
+func closeSpan(runes []rune, i int) int {
+	for j := i + 2; j < len(runes); j++ {
+		if runes[j] == '\\' {
+			j++
+			continue
+		}
+		if runes[j] != runes[i] || unicode.IsSpace(runes[j-1]) {
+			continue
+		}
+		if j+1 == len(runes) || !isWordRune(runes[j+1]) {
+			return j
+		}
+	}
+	return -1
+}
+
+// unescapeMarkdown turns \* and \_ into literal markers
+func unescapeMarkdown(s string) string {
+	return strings.NewReplacer(`\*`, "*", `\_`, "_").Replace(s)
+}
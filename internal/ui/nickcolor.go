@@ -0,0 +1,92 @@
+package ui
+
+import (
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// nickColors is the palette nicknames are hashed onto. Each color has a
+// light and dark variant so names stay readable on either background.
+var nickColors = []lipgloss.TerminalColor{
+	lipgloss.AdaptiveColor{Light: "#C92A2A", Dark: "#FF6B6B"},
+	lipgloss.AdaptiveColor{Light: "#C05700", Dark: "#FFA94D"},
+	lipgloss.AdaptiveColor{Light: "#8A6D00", Dark: "#FFD43B"},
+	lipgloss.AdaptiveColor{Light: "#2B8A3E", Dark: "#69DB7C"},
+	lipgloss.AdaptiveColor{Light: "#0B7285", Dark: "#3BC9DB"},
+	lipgloss.AdaptiveColor{Light: "#1864AB", Dark: "#4DABF7"},
+	lipgloss.AdaptiveColor{Light: "#5F3DC4", Dark: "#9775FA"},
+	lipgloss.AdaptiveColor{Light: "#A61E4D", Dark: "#F783AC"},
+}
+
+// namedNickColors are the palette's colors by the names users pick them with
+var namedNickColors = map[string]lipgloss.TerminalColor{
+	"red":    nickColors[0],
+	"orange": nickColors[1],
+	"yellow": nickColors[2],
+	"green":  nickColors[3],
+	"cyan":   nickColors[4],
+	"blue":   nickColors[5],
+	"purple": nickColors[6],
+	"pink":   nickColors[7],
+}
+
+// hexColorPattern matches #RGB and #RRGGBB colors
+var hexColorPattern = regexp.MustCompile(`^#([0-9a-f]{3}|[0-9a-f]{6})$`)
+
+// NickColorNames returns the names ParseNickColor accepts, sorted
+func NickColorNames() []string {
+	names := make([]string, 0, len(namedNickColors))
+	for name := range namedNickColors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseNickColor checks a nickname color a user asked for: one of
+// NickColorNames, a hex color such as #FF8800, or an ANSI color number
+// from 0 to 255. It returns the color in the form FormatUserMessage takes.
+func ParseNickColor(s string) (string, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if _, ok := namedNickColors[s]; ok {
+		return s, nil
+	}
+	if hexColorPattern.MatchString(s) {
+		return s, nil
+	}
+	if n, err := strconv.Atoi(s); err == nil && n >= 0 && n <= 255 {
+		return strconv.Itoa(n), nil
+	}
+	return "", fmt.Errorf("unknown color %q: use %s, a hex color such as #FF8800, or a number from 0 to 255", s, strings.Join(NickColorNames(), ", "))
+}
+
+// ColorForNick returns the color for a nickname. It depends only on the
+// name, ignoring case like nickname matching does, so every client shows a
+// user in the same color.
+func ColorForNick(name string) lipgloss.TerminalColor {
+	h := fnv.New32a()
+	h.Write([]byte(strings.ToLower(name)))
+	return nickColors[h.Sum32()%uint32(len(nickColors))]
+}
+
+// userStyle returns the style for another user's message prefix, colored
+// by nickname if the theme enables it. color, from ParseNickColor,
+// overrides the nickname's own color when set.
+func (r *Renderer) userStyle(username, color string) lipgloss.Style {
+	if !r.theme.NickColors {
+		return r.theme.User
+	}
+	if color == "" {
+		return r.theme.User.Copy().Foreground(ColorForNick(username))
+	}
+	if named, ok := namedNickColors[color]; ok {
+		return r.theme.User.Copy().Foreground(named)
+	}
+	return r.theme.User.Copy().Foreground(lipgloss.Color(color))
+}
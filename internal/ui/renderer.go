@@ -0,0 +1,39 @@
+package ui
+
+import "github.com/bscott/ts-chat/internal/i18n"
+
+// Renderer formats chat output for a single client, either styled with a
+// theme or as plain text for terminals that don't understand ANSI escapes
+type Renderer struct {
+	theme    *Theme
+	catalog  *i18n.Catalog // Language of headings and help text (nil is English)
+	markdown bool          // Style *bold* and _italic_ in user messages, see WithMarkdown
+}
+
+// NewRenderer creates a renderer that styles output with the given theme,
+// falling back to the default theme when theme is nil
+func NewRenderer(theme *Theme, catalog *i18n.Catalog) *Renderer {
+	if theme == nil {
+		theme, _ = NewTheme(DefaultThemeName)
+	}
+	return &Renderer{theme: theme, catalog: catalog}
+}
+
+// NewPlainRenderer creates a renderer that emits plain text only
+func NewPlainRenderer(catalog *i18n.Catalog) *Renderer {
+	return &Renderer{theme: plainTheme, catalog: catalog}
+}
+
+// ClearScreen returns the sequence that clears the terminal and moves the
+// cursor home, or an empty string for plain renderers
+func (r *Renderer) ClearScreen() string {
+	if !r.Colored() {
+		return ""
+	}
+	return "\x1b[2J\x1b[H"
+}
+
+// Colored reports whether the renderer emits styled output
+func (r *Renderer) Colored() bool {
+	return r.theme != plainTheme
+}
@@ -2,119 +2,487 @@ package ui
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/charmbracelet/lipgloss"
 )
 
-// Color definitions
-var (
-	subtle    = lipgloss.AdaptiveColor{Light: "#D9DCCF", Dark: "#383838"}
-	highlight = lipgloss.AdaptiveColor{Light: "#874BFD", Dark: "#7D56F4"}
-	special   = lipgloss.AdaptiveColor{Light: "#43BF6D", Dark: "#2B5F3A"}
-	accent    = lipgloss.AdaptiveColor{Light: "#1D9BF0", Dark: "#1D9BF0"}
-	warning   = lipgloss.AdaptiveColor{Light: "#F25D94", Dark: "#F25D94"}
-)
+// FormatSystemMessage formats a system message
+func (r *Renderer) FormatSystemMessage(message string) string {
+	return r.theme.System.Render("[System] " + message)
+}
 
-// Style definitions
-var (
-	// Base styles
-	BaseStyle = lipgloss.NewStyle().
-		BorderStyle(lipgloss.RoundedBorder()).
-		BorderForeground(subtle)
-
-	// Headers
-	HeaderStyle = lipgloss.NewStyle().
-		Bold(true).
-		Foreground(highlight).
-		Padding(0, 1)
-
-	// Text styles
-	SystemStyle = lipgloss.NewStyle().
-		Foreground(special).
-		Bold(true)
-
-	UserStyle = lipgloss.NewStyle().
-		Foreground(accent).
-		Bold(true)
-
-	SelfStyle = lipgloss.NewStyle().
-		Foreground(highlight).
-		Bold(true)
-
-	ActionStyle = lipgloss.NewStyle().
-		Foreground(warning).
-		Italic(true)
-
-	// UI components
-	BoxStyle = lipgloss.NewStyle().
-		Border(lipgloss.RoundedBorder()).
-		BorderForeground(subtle).
-		Padding(0, 1)
-
-	InputStyle = lipgloss.NewStyle().
-		Border(lipgloss.RoundedBorder()).
-		BorderForeground(highlight).
-		Padding(0, 1)
-)
+// MaxNicknameWidth is the longest nickname shown in full. Validation keeps
+// nicknames shorter; anything longer that slips through is cut short so it
+// can't break the layout of messages and lists.
+const MaxNicknameWidth = 32
 
-// FormatSystemMessage formats a system message
-func FormatSystemMessage(message string) string {
-	return SystemStyle.Render("[System] " + message)
+// displayNickname shortens a nickname longer than MaxNicknameWidth
+// characters, marking the cut with an ellipsis
+func displayNickname(nickname string) string {
+	runes := []rune(nickname)
+	if len(runes) <= MaxNicknameWidth {
+		return nickname
+	}
+	return string(runes[:MaxNicknameWidth-1]) + "…"
+}
+
+// FormatAnnouncement formats a server-wide announcement from an operator
+func (r *Renderer) FormatAnnouncement(from, message, timestamp string) string {
+	return r.theme.Announcement.Render("[" + timestamp + "] ANNOUNCEMENT from " + displayNickname(from) + ": " + message)
 }
 
-// FormatUserMessage formats a user message
-func FormatUserMessage(username, message, timestamp string) string {
-	return UserStyle.Render("["+timestamp+"] "+username+": ") + message
+// FormatUserMessage formats a user message. color is the sender's chosen
+// nickname color, see ParseNickColor, or empty for the usual one.
+func (r *Renderer) FormatUserMessage(username, color, message, timestamp string) string {
+	return r.userStyle(username, color).Render("["+timestamp+"] "+displayNickname(username)+": ") + r.emphasize(message)
 }
 
 // FormatSelfMessage formats the user's own message
-func FormatSelfMessage(message, timestamp string) string {
-	return SelfStyle.Render("["+timestamp+"] You: ") + message
+func (r *Renderer) FormatSelfMessage(message, timestamp string) string {
+	return r.theme.Self.Render("["+timestamp+"] "+r.catalog.T("you")+": ") + r.emphasize(message)
+}
+
+// FormatPrivateMessage formats a private message. When outgoing is true the
+// line is rendered from the sender's point of view.
+func (r *Renderer) FormatPrivateMessage(from, to, message, timestamp string, outgoing bool) string {
+	if outgoing {
+		return r.theme.Private.Render("["+timestamp+"] -> "+displayNickname(to)+": ") + message
+	}
+	return r.theme.Private.Render("["+timestamp+"] <- "+displayNickname(from)+" (private): ") + message
 }
 
 // FormatActionMessage formats an action message
-func FormatActionMessage(username, action string) string {
-	return ActionStyle.Render("* " + username + " " + action)
+func (r *Renderer) FormatActionMessage(username, action, timestamp string) string {
+	return r.theme.Action.Render("[" + timestamp + "] * " + displayNickname(username) + " " + action)
+}
+
+// FormatSelfActionMessage formats the user's own action message
+func (r *Renderer) FormatSelfActionMessage(username, action, timestamp string) string {
+	return r.theme.Self.Copy().Italic(true).Render("[" + timestamp + "] * " + displayNickname(username) + " " + action)
+}
+
+// FormatBanner formats the ASCII art banner
+func (r *Renderer) FormatBanner(banner string) string {
+	return r.theme.System.Render(banner)
+}
+
+// FormatPrompt formats an input prompt
+func (r *Renderer) FormatPrompt(prompt string) string {
+	return r.theme.Input.Render(prompt)
 }
 
 // FormatTitle formats a title
-func FormatTitle(title string) string {
-	return HeaderStyle.Render("=== " + title + " ===")
+func (r *Renderer) FormatTitle(title string) string {
+	return r.theme.Header.Render("=== " + title + " ===")
 }
 
 // CreateColoredBox creates a colored box with a title and content
-func CreateColoredBox(title, content string, width int) string {
-	box := BoxStyle.Copy().Width(width)
+func (r *Renderer) CreateColoredBox(title, content string, width int) string {
+	box := r.theme.Box.Copy().Width(width)
 	return box.Render(
-		HeaderStyle.Render(title) + "\n\n" +
+		r.theme.Header.Render(title) + "\n\n" +
 		content,
 	)
 }
 
-// FormatHelp formats the help message
-func FormatHelp() string {
-	return BoxStyle.Render(
-		HeaderStyle.Render("Available Commands:") + "\n" +
-			"/who - Show all users in the room\n" +
-			"/me <action> - Perform an action\n" +
-			"/help - Show this help message\n" +
-			"/quit - Leave the chat",
-	)
+// box renders content in a bordered box, wrapping it so the box is no
+// wider than width columns
+func (r *Renderer) box(content string, width int) string {
+	style := r.theme.Box
+	frame := style.GetHorizontalFrameSize()
+	if width > frame && lipgloss.Width(content)+frame > width {
+		style = style.Copy().Width(width - style.GetHorizontalBorderSize())
+	}
+	return style.Render(content)
+}
+
+// FormatHelp formats the help message to fit a terminal width. Each of
+// commands is shown with its catalog message "help.<command>", macros are
+// listed together on one line, and each of operatorCommands with "help.op.<command>". operatorCommands is empty for
+// users who aren't operators, who are told about /op instead.
+func (r *Renderer) FormatHelp(commands, macros, operatorCommands []string, width int) string {
+	content := r.theme.Header.Render(r.catalog.T("help.title")) + "\n"
+	for _, command := range commands {
+		content += r.catalog.T("help."+command) + "\n"
+	}
+	if len(macros) > 0 {
+		content += r.catalog.T("help.macros", strings.Join(macros, " ")) + "\n"
+	}
+	
+	if len(operatorCommands) > 0 {
+		content += "\n" + r.theme.Header.Render(r.catalog.T("help.op.title")) + "\n"
+		for _, command := range operatorCommands {
+			content += r.catalog.T("help.op."+command) + "\n"
+		}
+	} else {
+		content += r.catalog.T("help.op") + "\n"
+	}
+	
+	return r.box(strings.TrimSuffix(content, "\n"), width)
+}
+
+// FormatEmojiList formats the emoji shortcodes, packing as many entries on
+// each line as fit a terminal width
+func (r *Renderer) FormatEmojiList(entries []string, enabled bool, width int) string {
+	state := r.catalog.T("emoji.off")
+	if enabled {
+		state = r.catalog.T("emoji.on")
+	}
+	content := r.theme.Header.Render(r.catalog.T("emoji.title")) + " " + state + "\n"
+	
+	// Leave room for the box's border and padding
+	room := width - r.theme.Box.GetHorizontalFrameSize()
+	line := ""
+	for _, entry := range entries {
+		if line != "" && lipgloss.Width(line)+2+lipgloss.Width(entry) > room {
+			content += line + "\n"
+			line = ""
+		}
+		if line != "" {
+			line += "  "
+		}
+		line += entry
+	}
+	content += line
+	
+	return r.box(content, width)
+}
+
+// UserListEntry is a single row of the user list
+type UserListEntry struct {
+	Nickname  string
+	Connected time.Duration // Time since the user connected
+	Idle      time.Duration // Time since the user last sent a message
+	Away      bool          // Shown with an [away] marker
+	DND       bool          // Shown with a [dnd] marker
+	Invisible bool          // Shown with an [invisible] marker; only operators are sent invisible users
+	Source    string        // Where the user connected from; the column is only shown if set for some user
+}
+
+// FormatUserList formats the user list with connected and idle columns to
+// fit a terminal width
+func (r *Renderer) FormatUserList(roomName string, users []UserListEntry, maxUsers, width int) string {
+	content := r.theme.Header.Render(r.catalog.T("users.title", roomName, r.theme.Accent.Render(fmt.Sprintf("%d/%d", len(users), maxUsers)))) + "\n"
+	
+	// Pad nicknames and durations so the columns line up
+	nickname, connected, idle := r.catalog.T("users.nickname"), r.catalog.T("users.connected"), r.catalog.T("users.idle")
+	names := make([]string, len(users))
+	nickWidth := lipgloss.Width(nickname)
+	connectedWidth := max(10, lipgloss.Width(connected))
+	idleWidth := max(6, lipgloss.Width(idle))
+	showSource := false
+	for i, user := range users {
+		showSource = showSource || user.Source != ""
+		names[i] = displayNickname(user.Nickname)
+		if user.Away {
+			names[i] += " " + r.catalog.T("users.away")
+		}
+		if user.DND {
+			names[i] += " " + r.catalog.T("users.dnd")
+		}
+		if user.Invisible {
+			names[i] += " " + r.catalog.T("users.invisible")
+		}
+		if w := lipgloss.Width(names[i]); w > nickWidth {
+			nickWidth = w
+		}
+	}
+	
+	if showSource {
+		content += "  " + padRight(nickname, nickWidth) + "  " + padRight(connected, connectedWidth) + " " + padRight(idle, idleWidth) + " " + r.catalog.T("users.source") + "\n"
+	} else {
+		content += "  " + padRight(nickname, nickWidth) + "  " + padRight(connected, connectedWidth) + " " + idle + "\n"
+	}
+	for i, user := range users {
+		content += "- " + r.theme.User.Render(padRight(names[i], nickWidth))
+		content += "  " + padRight(FormatDuration(user.Connected), connectedWidth)
+		if showSource {
+			content += " " + padRight(FormatDuration(user.Idle), idleWidth) + " " + user.Source + "\n"
+		} else {
+			content += " " + FormatDuration(user.Idle) + "\n"
+		}
+	}
+	
+	return r.box(content, width)
 }
 
-// FormatUserList formats the user list
-func FormatUserList(roomName string, users []string, maxUsers int) string {
-	content := HeaderStyle.Render("Users in "+roomName+" ("+lipgloss.NewStyle().Foreground(accent).Render(fmt.Sprintf("%d/%d", len(users), maxUsers))+"):") + "\n"
+// field is a labelled line in output such as /stats. The label is a catalog
+// ID.
+type field struct {
+	label string
+	value string
+}
+
+// formatFields renders fields one per line, padding the translated labels
+// so the values line up
+func (r *Renderer) formatFields(fields []field) string {
+	labels := make([]string, len(fields))
+	width := 11 // Keeps the English values in the same column everywhere
+	for i, f := range fields {
+		labels[i] = r.catalog.T(f.label) + ":"
+		width = max(width, lipgloss.Width(labels[i]))
+	}
+	
+	lines := make([]string, len(fields))
+	for i, f := range fields {
+		lines[i] = padRight(labels[i], width+1) + f.value
+	}
+	return strings.Join(lines, "\n")
+}
+
+// padRight pads s with spaces to the given display width
+func padRight(s string, width int) string {
+	if w := lipgloss.Width(s); w < width {
+		return s + strings.Repeat(" ", width-w)
+	}
+	return s
+}
+
+// FormatDuration formats a duration compactly using its two largest units,
+// e.g. "2d3h", "1h5m", "4m12s" or "9s"
+func FormatDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	days := int(d / (24 * time.Hour))
+	hours := int(d % (24 * time.Hour) / time.Hour)
+	minutes := int(d % time.Hour / time.Minute)
+	seconds := int(d % time.Minute / time.Second)
 	
-	for _, user := range users {
-		content += "- " + UserStyle.Render(user) + "\n"
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd%dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh%dm", hours, minutes)
+	case minutes > 0:
+		return fmt.Sprintf("%dm%ds", minutes, seconds)
+	default:
+		return fmt.Sprintf("%ds", seconds)
 	}
+}
+
+// FormatUptime formats an uptime for people to read, e.g. "3d 4h 12m",
+// "4h 0m" or "12m 5s"
+func FormatUptime(d time.Duration) string {
+	d = d.Round(time.Second)
+	days := int(d / (24 * time.Hour))
+	hours := int(d % (24 * time.Hour) / time.Hour)
+	minutes := int(d % time.Hour / time.Minute)
+	seconds := int(d % time.Minute / time.Second)
 	
-	return BoxStyle.Render(content)
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh %dm", days, hours, minutes)
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	case minutes > 0:
+		return fmt.Sprintf("%dm %ds", minutes, seconds)
+	default:
+		return fmt.Sprintf("%ds", seconds)
+	}
+}
+
+// FormatRoomList formats the list of open rooms, marking the current one
+func (r *Renderer) FormatRoomList(names []string, counts []int, current string) string {
+	content := r.theme.Header.Render(r.catalog.T("rooms.title")) + "\n"
+	
+	for i, name := range names {
+		line := fmt.Sprintf("- %s (%d)", name, counts[i])
+		if name == current {
+			content += r.theme.Self.Render(line+" *") + "\n"
+		} else {
+			content += line + "\n"
+		}
+	}
+	
+	return r.theme.Box.Render(content)
+}
+
+// FormatStats formats server statistics
+func (r *Renderer) FormatStats(uptime time.Duration, messages int64, users, peakUsers, rooms int) string {
+	content := r.theme.Header.Render(r.catalog.T("stats.title")) + "\n" +
+		r.formatFields([]field{
+			{"field.uptime", FormatDuration(uptime)},
+			{"field.messages", strconv.FormatInt(messages, 10)},
+			{"field.users", r.catalog.T("stats.users", users, peakUsers)},
+			{"field.rooms", strconv.Itoa(rooms)},
+		})
+	
+	return r.theme.Box.Render(content)
+}
+
+// WhoisEntry describes a user for /whois. The fields after Operator are
+// only filled in for operators asking.
+type WhoisEntry struct {
+	Nickname    string
+	Room        string
+	Connected   time.Duration // Time since the user connected
+	Idle        time.Duration // Time since the user last sent a message
+	Away        bool
+	AwayMessage string
+	Operator    bool
+	Invisible   bool
+	Muted       time.Duration // Time left on a mute, 0 if not muted
+	SendLatency  time.Duration // Average time for messages to reach the user's connection
+	WorstLatency time.Duration // Longest time a message took to reach it
+	Source      string        // Address the user connected from
+	Identity    string        // Tailscale login, if known
+}
+
+// FormatWhois formats the details /whois shows about a user
+func (r *Renderer) FormatWhois(entry WhoisEntry) string {
+	fields := []field{
+		{"field.room", entry.Room},
+		{"field.connected", FormatDuration(entry.Connected)},
+		{"field.idle", FormatDuration(entry.Idle)},
+	}
+	if entry.Away {
+		away := r.catalog.T("whois.yes")
+		if entry.AwayMessage != "" {
+			away += " (" + entry.AwayMessage + ")"
+		}
+		fields = append(fields, field{"field.away", away})
+	}
+	if entry.Operator {
+		fields = append(fields, field{"field.operator", r.catalog.T("whois.yes")})
+	}
+	if entry.Invisible {
+		fields = append(fields, field{"field.invisible", r.catalog.T("whois.yes")})
+	}
+	if entry.Muted > 0 {
+		fields = append(fields, field{"field.muted", r.catalog.T("whois.muted", FormatUptime(entry.Muted))})
+	}
+	if entry.SendLatency > 0 {
+		fields = append(fields, field{"field.latency", r.catalog.T("whois.latency", formatLatency(entry.SendLatency), formatLatency(entry.WorstLatency))})
+	}
+	if entry.Source != "" {
+		fields = append(fields, field{"field.source", entry.Source})
+	}
+	if entry.Identity != "" {
+		fields = append(fields, field{"field.identity", entry.Identity})
+	}
+	
+	content := r.theme.Header.Render(r.catalog.T("whois.title", displayNickname(entry.Nickname))) + "\n" +
+		r.formatFields(fields)
+	return r.theme.Box.Render(content)
+}
+
+// ReportEntry describes a /report for the operators who receive it
+type ReportEntry struct {
+	Reporter string
+	Target   string
+	Reason   string
+	Context  []ReportLine // The reported user's recent messages, oldest first
+}
+
+// ReportLine is one of the reported user's messages included in a report
+type ReportLine struct {
+	Timestamp string
+	Content   string
+	Action    bool // Sent with /me
+}
+
+// FormatReport formats a user's report about another user
+func (r *Renderer) FormatReport(entry ReportEntry) string {
+	reason := entry.Reason
+	if reason == "" {
+		reason = r.catalog.T("report.no_reason")
+	}
+	content := r.theme.Announcement.Render(r.catalog.T("report.title", displayNickname(entry.Reporter), displayNickname(entry.Target))) + "\n" +
+		r.catalog.T("report.reason", reason)
+	if len(entry.Context) == 0 {
+		content += "\n" + r.catalog.T("report.no_messages")
+	}
+	for _, line := range entry.Context {
+		if line.Action {
+			content += "\n[" + line.Timestamp + "] * " + displayNickname(entry.Target) + " " + line.Content
+		} else {
+			content += "\n[" + line.Timestamp + "] " + displayNickname(entry.Target) + ": " + line.Content
+		}
+	}
+	
+	return r.theme.Box.Render(content)
+}
+
+// FormatVersion formats the server's build details
+func (r *Renderer) FormatVersion(version, commit, buildDate, goVersion string) string {
+	content := r.theme.Header.Render(r.catalog.T("version.title")) + "\n" +
+		r.formatFields([]field{
+			{"field.version", version},
+			{"field.commit", commit},
+			{"field.built", buildDate},
+			{"field.go", goVersion},
+		})
+	
+	return r.theme.Box.Render(content)
+}
+
+// FormatTopic formats a room topic. An empty topic renders as nothing.
+func (r *Renderer) FormatTopic(topic string) string {
+	if topic == "" {
+		return ""
+	}
+	return r.theme.Box.Render(r.theme.Header.Render(r.catalog.T("topic.title")) + " " + topic)
+}
+
+// TopicLogEntry is a single change in the topic log
+type TopicLogEntry struct {
+	Topic string        // The new topic, empty if it was cleared
+	SetBy string        // Who changed it
+	Age   time.Duration // How long ago it was changed
+}
+
+// FormatTopicLog formats a room's topic changes, oldest first, to fit a
+// terminal width
+func (r *Renderer) FormatTopicLog(roomName string, entries []TopicLogEntry, width int) string {
+	content := r.theme.Header.Render(r.catalog.T("topiclog.title", roomName)) + "\n"
+	if len(entries) == 0 {
+		content += r.catalog.T("topiclog.none") + "\n"
+	}
+	for _, entry := range entries {
+		topic := entry.Topic
+		if topic == "" {
+			topic = r.catalog.T("topiclog.cleared")
+		}
+		content += fmt.Sprintf("%s  %s: %s\n", r.theme.Accent.Render(r.catalog.T("topiclog.ago", FormatDuration(entry.Age))), displayNickname(entry.SetBy), topic)
+	}
+	return r.box(strings.TrimSuffix(content, "\n"), width)
+}
+
+// FormatMOTD formats the message of the day. An empty message renders as
+// nothing.
+func (r *Renderer) FormatMOTD(motd string) string {
+	if motd == "" {
+		return ""
+	}
+	return r.theme.Box.Render(r.theme.Header.Render(r.catalog.T("motd.title")) + "\n" + motd)
+}
+
+// FormatBanList formats the list of bans
+func (r *Renderer) FormatBanList(bans []string) string {
+	content := r.theme.Header.Render(r.catalog.T("bans.title", len(bans))) + "\n"
+	
+	if len(bans) == 0 {
+		content += r.catalog.T("bans.none") + "\n"
+	}
+	for _, ban := range bans {
+		content += "- " + ban + "\n"
+	}
+	
+	return r.theme.Box.Render(content)
 }
 
 // FormatWelcomeMessage formats the welcome message
-func FormatWelcomeMessage(roomName, nickname string) string {
-	return HeaderStyle.Render("Welcome to "+roomName+", "+nickname+"!") + "\n\n" +
-		"Type a message and press Enter to send. Use /help to see available commands."
-}
\ No newline at end of file
+func (r *Renderer) FormatWelcomeMessage(roomName, nickname string) string {
+	return r.theme.Header.Render(r.catalog.T("welcome.room", roomName, displayNickname(nickname))) + "\n\n" +
+		r.catalog.T("welcome.hint")
+}
+
+// formatLatency rounds a latency to a readable precision
+func formatLatency(d time.Duration) string {
+	if d < time.Millisecond {
+		return d.Round(time.Microsecond).String()
+	}
+	return d.Round(time.Millisecond).String()
+}
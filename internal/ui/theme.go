@@ -0,0 +1,172 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Theme holds the styles used to render chat output
+type Theme struct {
+	Name string
+
+	Header       lipgloss.Style // Titles and box headings
+	System       lipgloss.Style // System messages
+	User         lipgloss.Style // Other users' message prefixes
+	Self         lipgloss.Style // The user's own message prefixes
+	Action       lipgloss.Style // /me actions
+	Private      lipgloss.Style // Private messages
+	Announcement lipgloss.Style // Server-wide operator announcements
+	Accent       lipgloss.Style // Highlighted values such as counts
+	Box          lipgloss.Style // Bordered boxes
+	Input        lipgloss.Style // Input prompts
+
+	// NickColors colors each user's message prefix by nickname, see
+	// ColorForNick, instead of using User for everyone
+	NickColors bool
+}
+
+// palette is the set of colors a theme is built from
+type palette struct {
+	subtle    lipgloss.TerminalColor
+	highlight lipgloss.TerminalColor
+	special   lipgloss.TerminalColor
+	accent    lipgloss.TerminalColor
+	warning   lipgloss.TerminalColor
+}
+
+// palettes holds the colored themes by name
+var palettes = map[string]palette{
+	"default": {
+		subtle:    lipgloss.AdaptiveColor{Light: "#D9DCCF", Dark: "#383838"},
+		highlight: lipgloss.AdaptiveColor{Light: "#874BFD", Dark: "#7D56F4"},
+		special:   lipgloss.AdaptiveColor{Light: "#43BF6D", Dark: "#2B5F3A"},
+		accent:    lipgloss.AdaptiveColor{Light: "#1D9BF0", Dark: "#1D9BF0"},
+		warning:   lipgloss.AdaptiveColor{Light: "#F25D94", Dark: "#F25D94"},
+	},
+	"solarized": {
+		subtle:    lipgloss.Color("#586E75"),
+		highlight: lipgloss.Color("#6C71C4"),
+		special:   lipgloss.Color("#859900"),
+		accent:    lipgloss.Color("#268BD2"),
+		warning:   lipgloss.Color("#D33682"),
+	},
+	"highcontrast": {
+		subtle:    lipgloss.Color("15"),
+		highlight: lipgloss.Color("11"),
+		special:   lipgloss.Color("10"),
+		accent:    lipgloss.Color("14"),
+		warning:   lipgloss.Color("9"),
+	},
+}
+
+// DefaultThemeName is the theme used when none is configured
+const DefaultThemeName = "default"
+
+// monochromeTheme is the name of the theme that uses no colors at all
+const monochromeTheme = "monochrome"
+
+// ThemeNames returns the names of all available themes
+func ThemeNames() []string {
+	names := []string{monochromeTheme}
+	for name := range palettes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewTheme returns the named theme
+func NewTheme(name string) (*Theme, error) {
+	if name == monochromeTheme {
+		return newMonochromeTheme(), nil
+	}
+
+	p, ok := palettes[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown theme %q (available: %v)", name, ThemeNames())
+	}
+
+	return &Theme{
+		Name:       name,
+		NickColors: true,
+		Header: lipgloss.NewStyle().
+			Bold(true).
+			Foreground(p.highlight).
+			Padding(0, 1),
+		System: lipgloss.NewStyle().
+			Foreground(p.special).
+			Bold(true),
+		User: lipgloss.NewStyle().
+			Foreground(p.accent).
+			Bold(true),
+		Self: lipgloss.NewStyle().
+			Foreground(p.highlight).
+			Bold(true),
+		Action: lipgloss.NewStyle().
+			Foreground(p.warning).
+			Italic(true),
+		Private: lipgloss.NewStyle().
+			Foreground(p.highlight).
+			Italic(true),
+		Announcement: lipgloss.NewStyle().
+			Foreground(p.warning).
+			Bold(true).
+			Border(lipgloss.DoubleBorder()).
+			BorderForeground(p.warning).
+			Padding(0, 1),
+		Accent: lipgloss.NewStyle().
+			Foreground(p.accent),
+		Box: lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(p.subtle).
+			Padding(0, 1),
+		Input: lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(p.highlight).
+			Padding(0, 1),
+	}, nil
+}
+
+// plainTheme renders everything as unstyled text, without any escape codes
+var plainTheme = &Theme{
+	Name:         "plain",
+	Header:       lipgloss.NewStyle(),
+	System:       lipgloss.NewStyle(),
+	User:         lipgloss.NewStyle(),
+	Self:         lipgloss.NewStyle(),
+	Action:       lipgloss.NewStyle(),
+	Private:      lipgloss.NewStyle(),
+	Announcement: lipgloss.NewStyle(),
+	Accent:       lipgloss.NewStyle(),
+	Box:          lipgloss.NewStyle(),
+	Input:        lipgloss.NewStyle(),
+}
+
+// newMonochromeTheme builds a theme that relies on text attributes instead
+// of color, for terminals with limited color support
+func newMonochromeTheme() *Theme {
+	return &Theme{
+		Name: monochromeTheme,
+		Header: lipgloss.NewStyle().
+			Bold(true).
+			Padding(0, 1),
+		System:  lipgloss.NewStyle().Bold(true),
+		User:    lipgloss.NewStyle().Bold(true),
+		Self:    lipgloss.NewStyle().Bold(true).Underline(true),
+		Action:  lipgloss.NewStyle().Italic(true),
+		Private: lipgloss.NewStyle().Italic(true),
+		Announcement: lipgloss.NewStyle().
+			Bold(true).
+			Border(lipgloss.DoubleBorder()).
+			Padding(0, 1),
+		Accent: lipgloss.NewStyle(),
+		Box: lipgloss.NewStyle().
+			Border(lipgloss.NormalBorder()).
+			Padding(0, 1),
+		Input: lipgloss.NewStyle().
+			Border(lipgloss.NormalBorder()).
+			Padding(0, 1),
+	}
+}